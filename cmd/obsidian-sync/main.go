@@ -1,114 +1,135 @@
 package main
 
 import (
-	"bytes"
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"io"
-	"net/http"
 	"os"
+	"os/signal"
 	"path/filepath"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
+	"syscall"
 	"time"
 
 	"github.com/fsnotify/fsnotify"
 	"github.com/joho/godotenv"
+
+	"todo-tracker/internal/agenda"
+	"todo-tracker/internal/completion"
+	"todo-tracker/internal/config"
+	"todo-tracker/internal/labels"
+	"todo-tracker/internal/notes"
+	"todo-tracker/internal/recurrence"
+	"todo-tracker/internal/secrets"
+	"todo-tracker/internal/storage"
+	"todo-tracker/internal/supabase"
 )
 
 var (
-	supabaseURL  string
-	supabaseKey  string
-	userID       string
 	todoFile     string
 	debounce     = 2 * time.Second
 	pollInterval = 120 * time.Second // Poll Supabase for changes every 120 seconds
+	store        storage.Storage     // scoped to the token's user
 )
 
-type Task struct {
-	ID        int    `json:"id"`
-	Title     string `json:"title"`
-	DueDate   string `json:"due_date"`
-	Priority  string `json:"priority"`
-	Status    string `json:"status"`
-	ParentID  *int   `json:"parent_id"`
-	UserID    string `json:"user_id"`
-	CreatedAt string `json:"created_at"`
-}
-
 func main() {
 	// Load .env
+	envFiles := []string{".env"}
 	if exe, err := os.Executable(); err == nil {
-		godotenv.Load(filepath.Join(filepath.Dir(exe), ".env"))
+		exeEnv := filepath.Join(filepath.Dir(exe), ".env")
+		godotenv.Load(exeEnv)
+		envFiles = append(envFiles, exeEnv)
 	}
 	godotenv.Load()
 
-	supabaseURL = os.Getenv("TODO_CLI_SUPABASE_URL")
-	if supabaseURL == "" {
-		supabaseURL = os.Getenv("SUPABASE_URL")
-	}
-	supabaseKey = os.Getenv("TODO_CLI_SUPABASE_SERVICE_ROLE_KEY")
-	if supabaseKey == "" {
-		supabaseKey = os.Getenv("SUPABASE_SERVICE_ROLE_KEY")
+	// Settings resolve exactly as for the todo CLI, profiles included.
+	profile, args, err := config.TakeProfileFlag(os.Args[1:])
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(1)
 	}
-	userID = os.Getenv("TODO_CLI_TELEGRAM_CHAT_ID")
-	if userID == "" {
-		userID = os.Getenv("TELEGRAM_CHAT_ID")
+	settings, err := loadSettings(profile, envFiles)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(1)
 	}
-	todoFile = os.Getenv("TODO_CLI_FILE")
+	token := settings.Get("token")
+	todoFile = settings.Get("todo_file")
 
-	if supabaseURL == "" || supabaseKey == "" {
-		fmt.Println("❌ Missing TODO_CLI_SUPABASE_URL or TODO_CLI_SUPABASE_SERVICE_ROLE_KEY")
+	if token == "" {
+		fmt.Println("❌ Not logged in: run todo login, or set TODO_CLI_TOKEN")
 		os.Exit(1)
 	}
 
 	if todoFile == "" {
-		fmt.Println("❌ Missing TODO_CLI_FILE environment variable")
+		fmt.Println("❌ Missing todo_file setting")
 		fmt.Println("   Set it to your todo.md path, e.g.:")
-		fmt.Println("   export TODO_CLI_FILE=~/Documents/todo/todo.md")
+		fmt.Println("   todo config set todo_file ~/Documents/todo/todo.md")
 		os.Exit(1)
 	}
 
-	// Expand ~ to home directory
-	if strings.HasPrefix(todoFile, "~") {
-		home, _ := os.UserHomeDir()
-		todoFile = filepath.Join(home, todoFile[1:])
+	// Ctrl+C cancels in-flight requests and stops the watch loop.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Every request acts as the token's user, so the database's row-level
+	// security keeps the sync to their tasks. Access tokens are short-lived:
+	// a rejected request exchanges the token for a new one.
+	client := supabase.NewClient(settings.Get("supabase_url"), settings.Get("anon_key"))
+	var info *supabase.TokenInfo
+	signIn := func(ctx context.Context) error {
+		var err error
+		if info, err = client.VerifyToken(ctx, token); err == nil {
+			client.SetAccessToken(info.AccessToken)
+		}
+		return err
 	}
-
-	if userID == "" {
-		userID = "cli"
+	if err := signIn(ctx); err != nil {
+		fmt.Printf("❌ Invalid API token: %v\n", err)
+		os.Exit(1)
 	}
+	store = storage.NewReauth(supabase.NewTaskStore(client).ForUser(info.UserID), signIn)
 
 	// Check for subcommands
-	if len(os.Args) > 1 {
-		switch os.Args[1] {
+	if len(args) > 0 {
+		switch args[0] {
 		case "export":
-			exportToMarkdown()
+			exportToMarkdown(ctx)
 			return
 		case "watch":
 			// Export first, then watch
-			exportToMarkdown()
+			exportToMarkdown(ctx)
 			fmt.Println()
 		case "help", "--help", "-h":
 			fmt.Println(`Obsidian Sync - Two-way sync between Supabase and Obsidian
 
-Usage: obsidian-sync [command]
+Usage: obsidian-sync [--profile NAME] [command]
 
 Commands:
   export    Export tasks from Supabase to markdown file
   watch     Export tasks and watch for changes (default)
   help      Show this help message
 
-Environment:
-  TODO_CLI_FILE       Path to your todo.md file (required)`)
+Settings are read like the todo CLI's: the environment first, then the
+profile in ~/.config/todo/config.toml (see todo config list), and the
+token from the profile's secret_store when it has one. The sync acts as
+the token's user and only sees their tasks.
+
+  todo_file           Path to your todo.md file (required;
+                      env TODO_CLI_FILE)
+  token               API token from todo login (required;
+                      env TODO_CLI_TOKEN)
+  supabase_url, anon_key`)
 			return
 		}
 	}
 
 	// Always export on startup to ensure file is up-to-date
 	os.MkdirAll(filepath.Dir(todoFile), 0755)
-	exportToMarkdown()
+	exportToMarkdown(ctx)
 	fmt.Println()
 
 	fmt.Printf("👀 Watching %s for changes...\n", todoFile)
@@ -117,10 +138,39 @@ Environment:
 	fmt.Println("   Run 'obsidian-sync export' to refresh from Supabase")
 
 	// Watch for changes
-	watchFile()
+	watchFile(ctx)
+}
+
+// loadSettings resolves the settings of profile, or of the selected or
+// default profile when it is "", with its credentials read from its
+// secret store. It warns about credential files others can read.
+func loadSettings(profile string, envFiles []string) (config.Settings, error) {
+	path, err := config.DefaultPath()
+	if err != nil {
+		return config.Settings{}, err
+	}
+	f, err := config.Load(path)
+	if err != nil {
+		return config.Settings{}, err
+	}
+	for _, err := range f.InsecureFiles(path, envFiles...) {
+		fmt.Printf("⚠️  %v\n", err)
+	}
+	for _, msg := range f.Dropped {
+		fmt.Printf("⚠️  %s\n", msg)
+	}
+	s, err := config.Resolve(f, config.SelectProfile(f, profile))
+	if err != nil {
+		return s, err
+	}
+	store, err := secrets.Open(s.Get("secret_store"), secrets.Options{})
+	if err != nil || store == nil {
+		return s, err
+	}
+	return s, s.LoadSecrets(store)
 }
 
-func watchFile() {
+func watchFile(ctx context.Context) {
 	watcher, err := fsnotify.NewWatcher()
 	if err != nil {
 		fmt.Printf("❌ Failed to create watcher: %v\n", err)
@@ -148,6 +198,9 @@ func watchFile() {
 
 	for {
 		select {
+		case <-ctx.Done():
+			fmt.Println("\n👋 Stopped watching")
+			return
 		case event, ok := <-watcher.Events:
 			if !ok {
 				return
@@ -165,7 +218,7 @@ func watchFile() {
 
 				fmt.Printf("📝 File changed, syncing...\n")
 				time.Sleep(500 * time.Millisecond) // Wait for file to be fully written
-				syncFromMarkdown()
+				syncFromMarkdown(ctx)
 			}
 		case <-pollTicker.C:
 			// Skip if we just processed a local file change
@@ -173,7 +226,7 @@ func watchFile() {
 				continue
 			}
 			// Poll for remote changes
-			if checkAndUpdateFromRemote() {
+			if checkAndUpdateFromRemote(ctx) {
 				lastPoll = time.Now()
 				_ = lastPoll // Suppress unused warning
 			}
@@ -187,9 +240,10 @@ func watchFile() {
 }
 
 // checkAndUpdateFromRemote fetches tasks from Supabase and updates the local file if there are changes
-func checkAndUpdateFromRemote() bool {
-	tasks, err := fetchTasks()
+func checkAndUpdateFromRemote(ctx context.Context) bool {
+	tasks, err := fetchTasks(ctx)
 	if err != nil {
+		fmt.Printf("⚠️ Failed to poll Supabase: %v\n", err)
 		return false
 	}
 
@@ -216,8 +270,8 @@ func checkAndUpdateFromRemote() bool {
 	return false
 }
 
-func exportToMarkdown() {
-	tasks, err := fetchTasks()
+func exportToMarkdown(ctx context.Context) {
+	tasks, err := fetchTasks(ctx)
 	if err != nil {
 		fmt.Printf("❌ Failed to fetch tasks: %v\n", err)
 		return
@@ -232,42 +286,16 @@ func exportToMarkdown() {
 	fmt.Printf("✅ Exported %d tasks to %s\n", len(tasks), todoFile)
 }
 
-func buildMarkdownContent(tasks []Task) string {
-	today := time.Now().Format("2006-01-02")
-	var overdue, todayTasks, upcoming []Task
-
-	for _, t := range tasks {
-		if t.DueDate < today {
-			overdue = append(overdue, t)
-		} else if t.DueDate == today {
-			todayTasks = append(todayTasks, t)
-		} else {
-			upcoming = append(upcoming, t)
-		}
-	}
-
+func buildMarkdownContent(tasks []supabase.Task) string {
 	var sb strings.Builder
 	sb.WriteString("# TODO List\n\n")
 
-	if len(overdue) > 0 {
-		sb.WriteString("## Overdue\n")
-		for _, t := range overdue {
-			sb.WriteString(formatTaskMD(t))
-		}
-		sb.WriteString("\n")
-	}
-
-	if len(todayTasks) > 0 {
-		sb.WriteString("## Today\n")
-		for _, t := range todayTasks {
-			sb.WriteString(formatTaskMD(t))
+	for _, section := range agenda.Group(tasks, time.Now().Format("2006-01-02")) {
+		if len(section.Tasks) == 0 {
+			continue
 		}
-		sb.WriteString("\n")
-	}
-
-	if len(upcoming) > 0 {
-		sb.WriteString("## Upcoming\n")
-		for _, t := range upcoming {
+		sb.WriteString("## " + section.Name + "\n")
+		for _, t := range section.Tasks {
 			sb.WriteString(formatTaskMD(t))
 		}
 		sb.WriteString("\n")
@@ -280,16 +308,25 @@ func buildMarkdownContent(tasks []Task) string {
 	return sb.String()
 }
 
-func formatTaskMD(t Task) string {
+func formatTaskMD(t supabase.Task) string {
 	checkbox := "- [ ]"
 	if t.Status == "Done" {
 		checkbox = "- [x]"
 	}
-	// Format: - [ ] Task title — P1 — id:5 — due:2026-02-02
-	return fmt.Sprintf("%s %s — %s — id:%d — due:%s\n", checkbox, t.Title, t.Priority, t.ID, t.DueDate)
+	// Format: - [ ] Task title — P1 — id:5 — due:2026-02-02 [— +infra #work] [— 🔁 every Mon]
+	line := fmt.Sprintf("%s %s — %s — id:%d — due:%s", checkbox, t.Title, t.Priority, t.ID, t.DueDate)
+	if l := labels.Format(t.Tags, t.Project); l != "" {
+		line += " — " + l
+	}
+	if t.Recurrence != "" {
+		line += " — 🔁 " + recurrence.Describe(t.Recurrence)
+	}
+	// Notes and links follow as an indented block, which Obsidian shows
+	// as part of the list item.
+	return line + "\n" + notes.Block(t.Notes, t.Links)
 }
 
-func syncFromMarkdown() {
+func syncFromMarkdown(ctx context.Context) {
 	content, err := os.ReadFile(todoFile)
 	if err != nil {
 		fmt.Printf("❌ Failed to read file: %v\n", err)
@@ -300,9 +337,21 @@ func syncFromMarkdown() {
 	// Format: - [x] Task title — P1 — id:5 — due:2026-02-02
 	// Also supports partial format (missing priority/date will be filled on next export)
 	re := regexp.MustCompile(`- \[([  x])\] (.+?) — (P[0-4]) — id:(\d+) — due:(\d{4}-\d{2}-\d{2})`)
-	matches := re.FindAllStringSubmatch(string(content), -1)
+	lines := strings.Split(string(content), "\n")
+
+	for i := 0; i < len(lines); i++ {
+		match := re.FindStringSubmatch(lines[i])
+		if match == nil {
+			continue
+		}
+		// Indented lines below the checkbox hold the notes and links.
+		var block []string
+		for i+1 < len(lines) && isBlockLine(lines[i+1]) && !re.MatchString(lines[i+1]) {
+			i++
+			block = append(block, lines[i])
+		}
+		noteText, links := notes.ParseBlock(block)
 
-	for _, match := range matches {
 		checked := match[1] == "x"
 		title := strings.TrimSpace(match[2])
 		priority := match[3] // e.g. "P1"
@@ -310,8 +359,12 @@ func syncFromMarkdown() {
 		dueDate := match[5]
 
 		// Get current task status
-		task, err := fetchTaskByID(id)
+		task, err := store.Get(ctx, id)
+		if errors.Is(err, supabase.ErrNotFound) {
+			continue
+		}
 		if err != nil {
+			fmt.Printf("❌ Failed to fetch task %d: %v\n", id, err)
 			continue
 		}
 
@@ -320,11 +373,9 @@ func syncFromMarkdown() {
 			newStatus = "Done"
 		}
 
-		// Check what needs updating
+		// Check what needs updating. Status goes through the completion
+		// rules below rather than straight into the patch.
 		updates := make(map[string]interface{})
-		if task.Status != newStatus {
-			updates["status"] = newStatus
-		}
 		if title != "" && title != task.Title {
 			updates["title"] = title
 		}
@@ -334,77 +385,77 @@ func syncFromMarkdown() {
 		if dueDate != "" && dueDate != task.DueDate {
 			updates["due_date"] = dueDate
 		}
+		if noteText != task.Notes {
+			updates["notes"] = noteText
+			if noteText == "" {
+				updates["notes"] = nil
+			}
+		}
+		if !slices.Equal(links, task.Links) {
+			updates["links"] = links
+			if links == nil {
+				updates["links"] = []string{}
+			}
+		}
 
 		if len(updates) > 0 {
-			err = updateTask(id, updates)
-			if err != nil {
+			if _, err := store.UpdateByID(ctx, id, updates); err != nil {
 				fmt.Printf("❌ Failed to update task %d: %v\n", id, err)
-			} else {
-				fmt.Printf("✅ Task %d updated\n", id)
+				continue
 			}
+			fmt.Printf("✅ Task %d updated\n", id)
 		}
-	}
-
-	// Re-export to fill in any missing priority/due date
-	exportToMarkdown()
-}
-
-// API helpers
 
-func fetchTasks() ([]Task, error) {
-	url := fmt.Sprintf("%s/rest/v1/tasks?user_id=eq.%s&status=eq.Todo&order=priority,due_date", supabaseURL, userID)
-	req, _ := http.NewRequest("GET", url, nil)
-	req.Header.Set("apikey", supabaseKey)
-	req.Header.Set("Authorization", "Bearer "+supabaseKey)
+		if task.Status == newStatus {
+			continue
+		}
+		if newStatus == "Todo" {
+			reopened, err := completion.Reopen(ctx, store, id)
+			if err != nil {
+				fmt.Printf("❌ Failed to reopen task %d: %v\n", id, err)
+			}
+			for _, t := range reopened {
+				fmt.Printf("↩️  Task %d reopened\n", t.ID)
+			}
+			continue
+		}
 
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, err
+		// A checked parent with open subtasks stays open; the re-export
+		// below unchecks it again.
+		res, err := completion.Complete(ctx, store, id, completion.Options{AutoParent: true}, time.Now())
+		if errors.Is(err, completion.ErrOpenSubtasks) {
+			fmt.Printf("⚠️  Task %d has open subtasks; finish those first\n", id)
+			continue
+		}
+		if len(res.Tasks) > 0 {
+			fmt.Printf("✅ Task %d done\n", id)
+		}
+		for _, p := range res.Parents {
+			fmt.Printf("✅ Task %d done (all subtasks done)\n", p.ID)
+		}
+		for _, next := range res.Scheduled {
+			fmt.Printf("🔁 Task %d repeats as task %d, due %s\n", id, next.ID, next.DueDate)
+		}
+		if err != nil {
+			fmt.Printf("❌ Failed to complete task %d: %v\n", id, err)
+		}
 	}
-	defer resp.Body.Close()
 
-	var tasks []Task
-	json.NewDecoder(resp.Body).Decode(&tasks)
-	return tasks, nil
+	// Re-export to fill in any missing priority/due date
+	exportToMarkdown(ctx)
 }
 
-func fetchTaskByID(id int) (*Task, error) {
-	url := fmt.Sprintf("%s/rest/v1/tasks?id=eq.%d", supabaseURL, id)
-	req, _ := http.NewRequest("GET", url, nil)
-	req.Header.Set("apikey", supabaseKey)
-	req.Header.Set("Authorization", "Bearer "+supabaseKey)
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, err
-	}
-	defer resp.Body.Close()
-
-	var tasks []Task
-	json.NewDecoder(resp.Body).Decode(&tasks)
-	if len(tasks) == 0 {
-		return nil, fmt.Errorf("not found")
-	}
-	return &tasks[0], nil
+// isBlockLine reports whether line can belong to the indented block under
+// a task: it is blank or starts with whitespace.
+func isBlockLine(line string) bool {
+	return strings.TrimSpace(line) == "" || strings.HasPrefix(line, " ") || strings.HasPrefix(line, "\t")
 }
 
-func updateTask(id int, updates map[string]interface{}) error {
-	url := fmt.Sprintf("%s/rest/v1/tasks?id=eq.%d", supabaseURL, id)
-	body, _ := json.Marshal(updates)
-	req, _ := http.NewRequest("PATCH", url, bytes.NewBuffer(body))
-	req.Header.Set("apikey", supabaseKey)
-	req.Header.Set("Authorization", "Bearer "+supabaseKey)
-	req.Header.Set("Content-Type", "application/json")
-
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return err
-	}
-	defer resp.Body.Close()
+// API helpers
 
-	if resp.StatusCode >= 400 {
-		body, _ := io.ReadAll(resp.Body)
-		return fmt.Errorf("API error: %s", string(body))
-	}
-	return nil
+func fetchTasks(ctx context.Context) ([]supabase.Task, error) {
+	return store.List(ctx, supabase.Filter{
+		Status: "Todo",
+		Order:  []string{"priority", "due_date"},
+	})
 }
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"todo-tracker/internal/query"
+	"todo-tracker/internal/storage"
+	"todo-tracker/internal/supabase"
+)
+
+// selection is the set of tasks a bulk command acts on: IDs and ranges
+// given as arguments, or the open tasks matching the filter flags.
+type selection struct {
+	ids      []int
+	filter   supabase.Filter
+	byFilter bool
+	yes      bool
+}
+
+// newBulkFlags returns a flag set with the filter flags and --yes, for a
+// command that also takes task IDs.
+func newBulkFlags(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	addFilterFlags(fs)
+	fs.Bool("yes", false, "do not ask for confirmation")
+	return fs
+}
+
+// parseSelection parses args with fs, which must come from newBulkFlags.
+// With withText, positional words after the IDs are joined and returned
+// (e.g. the "3d" of "snooze 5 3d"); otherwise every word must be an ID.
+// It exits with a usage message when there is nothing to select.
+func parseSelection(fs *flag.FlagSet, args []string, usage string, withText bool) (selection, string) {
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(exitError)
+	}
+	sel := selection{filter: supabase.Filter{UserID: userID, Status: "Todo", Order: []string{"priority", "due_date"}}}
+	sel.yes = fs.Lookup("yes").Value.String() == "true"
+	if sel.byFilter, err = applyFilterFlags(fs, &sel.filter, time.Now()); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(exitError)
+	}
+
+	n := len(positional)
+	if withText {
+		n = 0
+		for n < len(positional) && !sel.byFilter {
+			if _, err := query.IDs(positional[n : n+1]); err != nil {
+				break
+			}
+			n++
+		}
+	}
+	if sel.ids, err = query.IDs(positional[:n]); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(exitError)
+	}
+	text := strings.Join(positional[n:], " ")
+
+	switch {
+	case sel.byFilter && len(sel.ids) > 0:
+		fmt.Println("❌ Give task IDs or filter flags, not both")
+		os.Exit(exitError)
+	case !sel.byFilter && len(sel.ids) == 0:
+		fmt.Println("❌ Missing task ID. Usage: " + usage)
+		os.Exit(1)
+	}
+	return sel, text
+}
+
+// resolve fetches the selected tasks. Every ID given must exist.
+func (sel selection) resolve(ctx context.Context) ([]supabase.Task, error) {
+	if sel.byFilter {
+		return tasks.List(ctx, sel.filter)
+	}
+	found, err := tasks.List(ctx, supabase.Filter{UserID: userID, IDs: sel.ids})
+	if err != nil {
+		return nil, err
+	}
+	// Keep the order the IDs were given in.
+	byID := map[int]supabase.Task{}
+	for _, t := range found {
+		byID[t.ID] = t
+	}
+	var out []supabase.Task
+	var missing []string
+	for _, id := range sel.ids {
+		if t, ok := byID[id]; ok {
+			out = append(out, t)
+		} else {
+			missing = append(missing, strconv.Itoa(id))
+		}
+	}
+	if len(missing) > 0 {
+		return nil, fmt.Errorf("%w: %s", supabase.ErrNotFound, strings.Join(missing, ", "))
+	}
+	return out, nil
+}
+
+// offline reports whether err means the selected tasks could not be read
+// because the server is unreachable. Only explicit IDs can still be
+// changed then, by queuing the update unchecked.
+func (sel selection) offline(err error) bool {
+	return !sel.byFilter && (supabase.IsNetworkError(err) || errors.Is(err, storage.ErrQueued))
+}
+
+// confirm asks before changing tasks picked by filter flags, unless --yes
+// was given. It exits when the answer is not yes.
+func (sel selection) confirm(ctx context.Context, verb string, targets []supabase.Task) {
+	if !sel.byFilter || sel.yes {
+		return
+	}
+	today := time.Now().Format("2006-01-02")
+	for _, t := range targets {
+		fmt.Println("   " + formatTaskLine(t, today, !structured()))
+	}
+	if fi, err := os.Stdin.Stat(); err != nil || fi.Mode()&os.ModeCharDevice == 0 {
+		fmt.Printf("❌ Not asking to %s %d task(s) without a terminal; pass --yes\n", verb, len(targets))
+		os.Exit(exitError)
+	}
+	fmt.Printf("%s these %d task(s)? [y/N] ", strings.ToUpper(verb[:1])+verb[1:], len(targets))
+	answer, _ := readLine(ctx)
+	if ctx.Err() != nil {
+		fail("Cancelled", ctx.Err())
+	}
+	if !slices.Contains([]string{"y", "yes"}, strings.ToLower(strings.TrimSpace(answer))) {
+		fmt.Println("Cancelled")
+		os.Exit(exitError)
+	}
+}
+
+// taskIDs returns the IDs of tasks.
+func taskIDs(tasks []supabase.Task) []int {
+	ids := make([]int, len(tasks))
+	for i, t := range tasks {
+		ids[i] = t.ID
+	}
+	return ids
+}
@@ -0,0 +1,503 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"todo-tracker/internal/query"
+	"todo-tracker/internal/supabase"
+)
+
+// completionTTL is how long `todo __complete` answers from its cache
+// before asking the backend again. Changes made by this CLI invalidate
+// the cache at once; changes from Telegram show up within the TTL.
+const completionTTL = 2 * time.Minute
+
+// completionCache holds the "id<TAB>title" lines of the open tasks in the
+// state directory.
+const completionCache = "completion.tsv"
+
+// cmdCompletion prints a completion script for bash, zsh or fish.
+func cmdCompletion(args []string) {
+	if len(args) != 1 {
+		fmt.Println("❌ Usage: todo completion bash|zsh|fish")
+		os.Exit(exitError)
+	}
+	cmds := shellCommands()
+	switch args[0] {
+	case "bash":
+		fmt.Print(bashCompletion(cmds))
+	case "zsh":
+		fmt.Print(zshCompletion(cmds))
+	case "fish":
+		fmt.Print(fishCompletion(cmds))
+	default:
+		fmt.Printf("❌ Unknown shell %q (expected bash, zsh or fish)\n", args[0])
+		os.Exit(exitError)
+	}
+}
+
+// completeFromCache answers `todo __complete ids` from the cache while it
+// is fresh. It reports whether it did.
+func completeFromCache(args []string) bool {
+	if len(args) != 1 || args[0] != "ids" || !cachesCompletions() {
+		return false
+	}
+	path := filepath.Join(stateDir, completionCache)
+	info, err := os.Stat(path)
+	if err != nil || time.Since(info.ModTime()) > completionTTL {
+		return false
+	}
+	// Anything journaled or queued since makes the cache stale.
+	for _, name := range []string{"journal.json", "queue.jsonl"} {
+		if other, err := os.Stat(filepath.Join(stateDir, name)); err == nil && other.ModTime().After(info.ModTime()) {
+			return false
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return false
+	}
+	stdout.Write(data)
+	return true
+}
+
+// cachesCompletions reports whether task IDs are worth caching: the
+// Supabase backend needs a round trip, the local file does not.
+func cachesCompletions() bool {
+	return stateDir != "" && settings.Get("backend") == "supabase"
+}
+
+// cmdComplete is the hidden command the completion scripts call. It
+// prints the open tasks as "id<TAB>title" lines; its messages and errors
+// are discarded so they never end up among the candidates.
+func cmdComplete(ctx context.Context, args []string) {
+	if len(args) != 1 || args[0] != "ids" {
+		os.Exit(exitError)
+	}
+	found, err := tasks.List(ctx, supabase.Filter{UserID: userID, Status: "Todo", Order: []string{"id"}})
+	if err != nil {
+		// Offline, a stale list beats none.
+		if data, readErr := os.ReadFile(filepath.Join(stateDir, completionCache)); readErr == nil && cachesCompletions() {
+			stdout.Write(data)
+		}
+		os.Exit(exitCode(err))
+	}
+	var b strings.Builder
+	for _, t := range found {
+		title := strings.Join(strings.Fields(t.Title), " ")
+		fmt.Fprintf(&b, "%d\t%s\n", t.ID, title)
+	}
+	if cachesCompletions() {
+		writeState(completionCache, strings.TrimSuffix(b.String(), "\n"))
+	}
+	fmt.Fprint(stdout, b.String())
+}
+
+// shellFlag is a command-line flag as the completion scripts see it.
+type shellFlag struct {
+	name  string
+	usage string
+	value bool // takes an argument
+}
+
+// idArgs says which arguments of a command are task IDs.
+type idArgs int
+
+const (
+	noIDs   idArgs = iota
+	firstID        // only the first argument, e.g. edit <id>
+	allIDs         // any argument, e.g. done <id>...
+)
+
+// shellCommand is a command as the completion scripts see it.
+type shellCommand struct {
+	names []string // the command, then its aliases
+	help  string
+	flags []shellFlag
+	ids   idArgs
+	words string // fixed words for the first argument
+}
+
+// flagValues are the words completed after a flag, by flag name.
+var flagValues = map[string]string{
+	"output":   "text table plain json csv tsv",
+	"status":   "Todo Done all",
+	"priority": strings.Join(query.Priorities, " "),
+}
+
+// shellCommands lists the commands to complete, in help order. The
+// filter flags are read from the flag sets the commands themselves use.
+func shellCommands() []shellCommand {
+	filter := flag.NewFlagSet("", flag.ContinueOnError)
+	addFilterFlags(filter)
+	filterFlags := shellFlags(filter)
+	bulkFlags := shellFlags(newBulkFlags(""))
+
+	with := func(base []shellFlag, extra ...shellFlag) []shellFlag {
+		return append(append([]shellFlag(nil), base...), extra...)
+	}
+	limit := shellFlag{"limit", "show at most this many tasks", true}
+
+	return []shellCommand{
+		{names: []string{"add"}, help: "Add a new task"},
+		{names: []string{"list", "ls"}, help: "Show pending tasks", flags: with(filterFlags,
+			shellFlag{"sort", "fields to sort by, e.g. due,-priority", true},
+			limit,
+			shellFlag{"flat", "one task per line, without subtask trees", false})},
+		{names: []string{"show"}, help: "Show a task with its subtasks", ids: firstID},
+		{names: []string{"note"}, help: "Edit a task's notes and links", ids: firstID},
+		{names: []string{"search"}, help: "Find tasks by title and notes", flags: with(filterFlags, limit)},
+		{names: []string{"done", "rm"}, help: "Mark tasks as complete", ids: allIDs, flags: with(bulkFlags,
+			shellFlag{"cascade", "also complete every open subtask", false},
+			shellFlag{"keep-parent", "leave the parent open when its last subtask is done", false})},
+		{names: []string{"reopen"}, help: "Mark a done task as open again"},
+		{names: []string{"snooze"}, help: "Postpone tasks", ids: allIDs, flags: with(bulkFlags,
+			shellFlag{"skip-weekends", "count working days and never land on a weekend", false})},
+		{names: []string{"subtask"}, help: "Add a subtask to a task", ids: firstID},
+		{names: []string{"edit"}, help: "Change a task's fields", ids: firstID, flags: []shellFlag{
+			{"title", "new title", true},
+			{"due", "new due date", true},
+			{"priority", "new priority", true},
+			{"parent", "new parent task ID", true},
+			{"no-parent", "detach from parent", false}}},
+		{names: []string{"ui"}, help: "Full-screen task list"},
+		{names: []string{"undo"}, help: "Revert the last changes", flags: []shellFlag{
+			{"force", "revert even if the tasks changed since", false}}},
+		{names: []string{"history"}, help: "List recent changes"},
+		{names: []string{"parse-date"}, help: "Show how a date expression is read"},
+		{names: []string{"sync"}, help: "Replay changes queued while offline", flags: []shellFlag{
+			{"force", "overwrite tasks changed on the server", false},
+			{"skip", "discard changes to tasks changed on the server", false}}},
+		{names: []string{"config"}, help: "Show or change settings", words: "list get set unset"},
+		{names: []string{"login"}, help: "Log in by pairing with the Telegram bot", flags: []shellFlag{
+			{"name", "name of the new token", true},
+			{"paste", "read a token from /token on stdin", false}}},
+		{names: []string{"logout"}, help: "Revoke and forget the token", flags: []shellFlag{
+			{"keep", "forget the token without revoking it", false}}},
+		{names: []string{"whoami"}, help: "Show who the token belongs to"},
+		{names: []string{"tokens"}, help: "List or revoke your API tokens", words: "list revoke"},
+		{names: []string{"completion"}, help: "Print a shell completion script", words: "bash zsh fish"},
+		{names: []string{"help"}, help: "Show help"},
+	}
+}
+
+func shellFlags(fs *flag.FlagSet) []shellFlag {
+	var flags []shellFlag
+	fs.VisitAll(func(f *flag.Flag) {
+		b, ok := f.Value.(interface{ IsBoolFlag() bool })
+		flags = append(flags, shellFlag{f.Name, f.Usage, !ok || !b.IsBoolFlag()})
+	})
+	return flags
+}
+
+// valueFlags returns every flag, with dashes, that takes an argument,
+// the global ones included, as a shell case pattern.
+func valueFlags(cmds []shellCommand) string {
+	seen := map[string]bool{"-o": true, "--output": true, "--profile": true}
+	for _, c := range cmds {
+		for _, f := range c.flags {
+			if f.value {
+				seen["--"+f.name] = true
+			}
+		}
+	}
+	names := make([]string, 0, len(seen))
+	for name := range seen {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return strings.Join(names, "|")
+}
+
+func flagList(c shellCommand) string {
+	names := make([]string, len(c.flags))
+	for i, f := range c.flags {
+		names[i] = "--" + f.name
+	}
+	return strings.Join(names, " ")
+}
+
+// idCommands returns the names of the commands taking IDs as ids says,
+// as a shell case pattern.
+func idCommands(cmds []shellCommand, ids idArgs) string {
+	var names []string
+	for _, c := range cmds {
+		if c.ids == ids {
+			names = append(names, c.names...)
+		}
+	}
+	return strings.Join(names, "|")
+}
+
+func bashCompletion(cmds []shellCommand) string {
+	var names, flagCases, wordCases, valueCases strings.Builder
+	for _, c := range cmds {
+		names.WriteString(" " + strings.Join(c.names, " "))
+		if len(c.flags) > 0 {
+			fmt.Fprintf(&flagCases, "    %s) flags=%q ;;\n", strings.Join(c.names, "|"), flagList(c))
+		}
+		if c.words != "" {
+			fmt.Fprintf(&wordCases, "    %s) ((args == 0)) && COMPREPLY=($(compgen -W %q -- \"$cur\")) ;;\n", strings.Join(c.names, "|"), c.words)
+		}
+	}
+	for _, name := range sortedKeys(flagValues) {
+		pattern := "--" + name
+		if name == "output" {
+			pattern = "-o|--output"
+		}
+		fmt.Fprintf(&valueCases, "    %s) COMPREPLY=($(compgen -W %q -- \"$cur\")); return ;;\n", pattern, flagValues[name])
+	}
+	return strings.NewReplacer(
+		"@COMMANDS@", strings.TrimSpace(names.String()),
+		"@VALUE_FLAGS@", valueFlags(cmds),
+		"@VALUE_CASES@", valueCases.String(),
+		"@FLAG_CASES@", flagCases.String(),
+		"@WORD_CASES@", wordCases.String(),
+		"@ALL_IDS@", idCommands(cmds, allIDs),
+		"@FIRST_ID@", idCommands(cmds, firstID),
+	).Replace(bashTemplate)
+}
+
+const bashTemplate = `# bash completion for todo
+# Load with: source <(todo completion bash)
+
+_todo_ids() {
+    local IFS=$'\n' line
+    local -a found=()
+    for line in $("$1" ${profile:+--profile="$profile"} __complete ids 2>/dev/null); do
+        [[ ${line%%$'\t'*} == "$cur"* ]] && found+=("$line")
+    done
+    if ((${#found[@]} == 1)); then
+        COMPREPLY=("${found[0]%%$'\t'*}")
+        return
+    fi
+    for line in "${found[@]}"; do
+        COMPREPLY+=("${line%%$'\t'*}  (${line#*$'\t'})")
+    done
+}
+
+_todo() {
+    local cur=${COMP_WORDS[COMP_CWORD]} prev=${COMP_WORDS[COMP_CWORD-1]}
+    local cmd= flags= profile= args=0 i
+    COMPREPLY=()
+    for ((i = 1; i < COMP_CWORD; i++)); do
+        case ${COMP_WORDS[i]} in
+        --profile) profile=${COMP_WORDS[i+1]}; ((i++)) ;;
+        @VALUE_FLAGS@) ((i++)) ;;
+        -*) ;;
+        *) if [[ -z $cmd ]]; then cmd=${COMP_WORDS[i]}; else ((args++)); fi ;;
+        esac
+    done
+
+    case $prev in
+@VALUE_CASES@    --parent) _todo_ids "$1"; return ;;
+    @VALUE_FLAGS@) return ;;
+    esac
+
+    if [[ -z $cmd ]]; then
+        COMPREPLY=($(compgen -W "@COMMANDS@ --output --profile --reverify" -- "$cur"))
+        return
+    fi
+    case $cmd in
+@FLAG_CASES@    esac
+    if [[ $cur == -* ]]; then
+        COMPREPLY=($(compgen -W "$flags --output --profile --reverify" -- "$cur"))
+        return
+    fi
+    case $cmd in
+    @ALL_IDS@) _todo_ids "$1" ;;
+    @FIRST_ID@) ((args == 0)) && _todo_ids "$1" ;;
+@WORD_CASES@    esac
+}
+
+complete -F _todo todo
+`
+
+func zshCompletion(cmds []shellCommand) string {
+	var names, flagCases, wordCases, valueCases strings.Builder
+	for _, c := range cmds {
+		for _, name := range c.names {
+			fmt.Fprintf(&names, "        %s\n", zshQuote(name+":"+c.help))
+		}
+		if len(c.flags) > 0 {
+			fmt.Fprintf(&flagCases, "    %s) flags=(\n", strings.Join(c.names, "|"))
+			for _, f := range c.flags {
+				fmt.Fprintf(&flagCases, "        %s\n", zshQuote("--"+f.name+":"+f.usage))
+			}
+			flagCases.WriteString("    ) ;;\n")
+		}
+		if c.words != "" {
+			fmt.Fprintf(&wordCases, "    %s) ((args == 0)) && compadd %s ;;\n", strings.Join(c.names, "|"), c.words)
+		}
+	}
+	for _, name := range sortedKeys(flagValues) {
+		pattern := "--" + name
+		if name == "output" {
+			pattern = "-o|--output"
+		}
+		fmt.Fprintf(&valueCases, "    %s) compadd %s; return ;;\n", pattern, flagValues[name])
+	}
+	return strings.NewReplacer(
+		"@COMMANDS@", names.String(),
+		"@VALUE_FLAGS@", valueFlags(cmds),
+		"@VALUE_CASES@", valueCases.String(),
+		"@FLAG_CASES@", flagCases.String(),
+		"@WORD_CASES@", wordCases.String(),
+		"@ALL_IDS@", idCommands(cmds, allIDs),
+		"@FIRST_ID@", idCommands(cmds, firstID),
+	).Replace(zshTemplate)
+}
+
+// zshQuote single-quotes s for zsh.
+func zshQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+const zshTemplate = `#compdef todo
+# zsh completion for todo
+# Load with: source <(todo completion zsh)
+
+_todo_ids() {
+    local -a found
+    local line
+    for line in "${(@f)$(${words[1]} ${profile:+--profile=$profile} __complete ids 2>/dev/null)}"; do
+        [[ -n $line ]] && found+=("${line%%$'\t'*}:${line#*$'\t'}")
+    done
+    _describe -t tasks task found
+}
+
+_todo() {
+    local -a commands flags
+    local cmd profile i args=0
+    commands=(
+@COMMANDS@    )
+    flags=('--output:text, table, plain, json, csv or tsv' '--profile:config profile to use' '--reverify:verify the token with the server now')
+    for ((i = 2; i < CURRENT; i++)); do
+        case ${words[i]} in
+        --profile) profile=${words[i+1]}; ((i++)) ;;
+        @VALUE_FLAGS@) ((i++)) ;;
+        -*) ;;
+        *) if [[ -z $cmd ]]; then cmd=${words[i]}; else ((args++)); fi ;;
+        esac
+    done
+
+    case ${words[CURRENT-1]} in
+@VALUE_CASES@    --parent) _todo_ids; return ;;
+    @VALUE_FLAGS@) return ;;
+    esac
+
+    if [[ -z $cmd ]]; then
+        if [[ $PREFIX == -* ]]; then
+            _describe -t options option flags
+        else
+            _describe -t commands command commands
+        fi
+        return
+    fi
+    case $cmd in
+@FLAG_CASES@    esac
+    if [[ $PREFIX == -* ]]; then
+        flags+=('--output:text, table, plain, json, csv or tsv' '--profile:config profile to use' '--reverify:verify the token with the server now')
+        _describe -t options option flags
+        return
+    fi
+    case $cmd in
+    @ALL_IDS@) _todo_ids ;;
+    @FIRST_ID@) ((args == 0)) && _todo_ids ;;
+@WORD_CASES@    esac
+}
+
+if [[ $zsh_eval_context[-1] == loadautofunc ]]; then
+    _todo "$@"
+else
+    compdef _todo todo
+fi
+`
+
+func fishCompletion(cmds []shellCommand) string {
+	var b strings.Builder
+	b.WriteString(strings.NewReplacer("@VALUE_FLAGS@", strings.ReplaceAll(valueFlags(cmds), "|", " ")).Replace(fishTemplate))
+	for _, c := range cmds {
+		for _, name := range c.names {
+			fmt.Fprintf(&b, "complete -c todo -n 'test (__todo_args) = -1' -a %s -d %s\n", name, fishQuote(c.help))
+		}
+	}
+	b.WriteString("\n")
+	for _, c := range cmds {
+		seen := "__fish_seen_subcommand_from " + strings.Join(c.names, " ")
+		for _, f := range c.flags {
+			fmt.Fprintf(&b, "complete -c todo -n '%s' -l %s", seen, f.name)
+			if f.value {
+				b.WriteString(" -x")
+				if words, ok := flagValues[f.name]; ok {
+					fmt.Fprintf(&b, " -a '%s'", words)
+				} else if f.name == "parent" {
+					b.WriteString(" -a '(__todo_ids)'")
+				}
+			}
+			fmt.Fprintf(&b, " -d %s\n", fishQuote(f.usage))
+		}
+		switch c.ids {
+		case allIDs:
+			fmt.Fprintf(&b, "complete -c todo -n '%s; and test (__todo_args) -ge 0' -a '(__todo_ids)'\n", seen)
+		case firstID:
+			fmt.Fprintf(&b, "complete -c todo -n '%s; and test (__todo_args) = 0' -a '(__todo_ids)'\n", seen)
+		}
+		if c.words != "" {
+			fmt.Fprintf(&b, "complete -c todo -n '%s; and test (__todo_args) = 0' -a '%s'\n", seen, c.words)
+		}
+	}
+	return b.String()
+}
+
+// fishQuote single-quotes s for fish.
+func fishQuote(s string) string {
+	return "'" + strings.NewReplacer(`\`, `\\`, "'", `\'`).Replace(s) + "'"
+}
+
+const fishTemplate = `# fish completion for todo
+# Load with: todo completion fish | source
+
+# __todo_args prints how many arguments follow the command, flags and
+# their values aside, or -1 before the command.
+function __todo_args
+    set -l n -1
+    set -l skip 0
+    for t in (commandline -opc)[2..-1]
+        if test $skip = 1
+            set skip 0
+            continue
+        end
+        switch $t
+            case @VALUE_FLAGS@
+                set skip 1
+            case '-*'
+            case '*'
+                set n (math $n + 1)
+        end
+    end
+    echo $n
+end
+
+function __todo_ids
+    set -l words (commandline -opc)
+    set -l profile
+    if set -l i (contains -i -- --profile $words)
+        set profile --profile=$words[(math $i + 1)]
+    end
+    command $words[1] $profile __complete ids 2>/dev/null
+end
+
+complete -c todo -f
+complete -c todo -s o -l output -x -a 'text table plain json csv tsv' -d 'Output format'
+complete -c todo -l profile -x -d 'Config profile to use'
+complete -c todo -l reverify -d 'Verify the token with the server now'
+
+`
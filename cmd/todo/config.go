@@ -0,0 +1,315 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"todo-tracker/internal/config"
+	"todo-tracker/internal/secrets"
+)
+
+// configColumns are the fields of `todo config list` in structured formats.
+var configColumns = []string{"key", "value", "source"}
+
+// cmdConfig reads and changes config.toml. loadErr is the error reading
+// the file or resolving the profile, if any.
+func cmdConfig(ctx context.Context, args []string, f *config.File, loadErr error) {
+	usage := "❌ Usage: todo [--profile NAME] config list | get <key> | set <key> <value> | set token | unset <key>"
+	if len(args) == 0 {
+		fmt.Println(usage)
+		os.Exit(exitError)
+	}
+	switch sub := args[0]; {
+	case sub == "list" && len(args) == 1:
+		if loadErr != nil {
+			fmt.Printf("❌ %v\n", loadErr)
+			os.Exit(exitError)
+		}
+		loadSecrets()
+		listConfig(f)
+	case sub == "get" && len(args) == 2:
+		if loadErr != nil {
+			fmt.Printf("❌ %v\n", loadErr)
+			os.Exit(exitError)
+		}
+		if args[1] == "profile" {
+			fmt.Println(profile)
+			return
+		}
+		if _, ok := config.LookupKey(args[1]); !ok {
+			fmt.Printf("❌ Unknown setting %q (see todo config list)\n", args[1])
+			os.Exit(exitError)
+		}
+		loadSecrets()
+		value := settings.Get(args[1])
+		if value == "" {
+			os.Exit(exitError)
+		}
+		fmt.Println(value)
+	case sub == "set" && credential(args[1]):
+		// Like login --paste, so the token never appears in argv.
+		if len(args) > 2 {
+			fmt.Printf("❌ Pass %s on stdin, not as an argument: todo config set %s\n", args[1], args[1])
+			os.Exit(exitError)
+		}
+		setConfig(args[1], readToken(ctx))
+	case sub == "set" && len(args) >= 3, sub == "unset" && len(args) == 2:
+		value := strings.Join(args[2:], " ")
+		setConfig(args[1], value)
+	default:
+		fmt.Println(usage)
+		os.Exit(exitError)
+	}
+}
+
+// credential reports whether key is kept in the secret store.
+func credential(key string) bool {
+	k, ok := config.LookupKey(key)
+	return ok && k.Credential
+}
+
+// setConfig stores key in the selected profile and reports it.
+func setConfig(key, value string) {
+	name, err := saveSetting(key, value)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(exitError)
+	}
+
+	switch {
+	case key == "profile" && value == "":
+		fmt.Println("✅ No default profile")
+	case key == "profile":
+		fmt.Printf("✅ Default profile: %s\n", value)
+	case value == "":
+		fmt.Printf("✅ Removed %s from %s\n", key, savedIn(key, name))
+	default:
+		fmt.Printf("✅ Set %s in %s\n", key, savedIn(key, name))
+	}
+	warnEnvOverride(key)
+}
+
+// saveSetting stores key in the selected profile, or in a new "default"
+// profile when there is none yet, and returns the profile's name. An
+// empty value removes the key.
+func saveSetting(key, value string) (string, error) {
+	// Reload so that a file that does not parse is never overwritten.
+	f, err := config.Load(configPath)
+	if err != nil {
+		return "", err
+	}
+	name := profile
+	if name == "" && key != "profile" {
+		name = "default"
+		f.Profile = name
+	}
+	// A broken secret_store must not stop it from being changed.
+	store, storeErr := secretStore()
+	k, _ := config.LookupKey(key)
+	switch {
+	case k.Credential && storeErr != nil:
+		return "", storeErr
+	case k.Credential && store != nil:
+		// Credentials go to the secret store, never into the file.
+		if value == "" {
+			err = store.Delete(config.SecretName(name, key))
+		} else {
+			err = store.Set(config.SecretName(name, key), value)
+		}
+		if err != nil {
+			return "", fmt.Errorf("cannot save %s in the %s: %w", key, store, err)
+		}
+		value = ""
+	case key == "secret_store" && os.Getenv("TODO_CLI_SECRET_STORE") == "":
+		to, err := secrets.Open(value, secrets.Options{Passphrase: passphrase})
+		if err != nil {
+			return "", err
+		}
+		if err := moveSecrets(f, name, store, to); err != nil {
+			return "", err
+		}
+	}
+	if err := f.Set(name, key, value); err != nil {
+		return "", err
+	}
+	if err := f.Save(configPath); err != nil {
+		return "", fmt.Errorf("cannot save config: %w", err)
+	}
+	return name, nil
+}
+
+// moveSecrets moves the credentials of profile name from one secret store
+// to another, where nil is the config file itself.
+func moveSecrets(f *config.File, name string, from, to secrets.Store) error {
+	if from == nil && to == nil || from != nil && to != nil && from.String() == to.String() {
+		return nil
+	}
+	for _, k := range config.Keys {
+		if !k.Credential {
+			continue
+		}
+		secret := config.SecretName(name, k.Name)
+		value := f.Profiles[name][k.Name]
+		if from != nil {
+			var err error
+			if value, err = from.Get(secret); errors.Is(err, secrets.ErrNotFound) {
+				continue
+			} else if err != nil {
+				return fmt.Errorf("cannot read %s from the %s: %w", k.Name, from, err)
+			}
+		}
+		if value == "" {
+			continue
+		}
+
+		var err error
+		if to == nil {
+			err = f.Set(name, k.Name, value)
+		} else {
+			err = to.Set(secret, value)
+		}
+		if err != nil {
+			return fmt.Errorf("cannot move %s: %w", k.Name, err)
+		}
+		if from == nil {
+			f.Set(name, k.Name, "")
+		} else if err := from.Delete(secret); err != nil {
+			return fmt.Errorf("cannot remove %s from the %s: %w", k.Name, from, err)
+		}
+		dest := "the config file"
+		if to != nil {
+			dest = "the " + to.String()
+		}
+		fmt.Printf("🔐 Moved %s to %s\n", k.Name, dest)
+	}
+	return nil
+}
+
+// savedIn says where saveSetting put key for profile name.
+func savedIn(key, name string) string {
+	if k, _ := config.LookupKey(key); k.Credential && openSecrets() != nil {
+		return fmt.Sprintf("the %s for profile %s", openSecrets(), name)
+	}
+	return "profile " + name
+}
+
+// secretStore opens the store named by the secret_store setting, once.
+var secretStore = sync.OnceValues(func() (secrets.Store, error) {
+	return secrets.Open(settings.Get("secret_store"), secrets.Options{Passphrase: passphrase})
+})
+
+// noPrompt is set while the shell completes a word, where asking for a
+// passphrase would get in the way.
+var noPrompt bool
+
+func passphrase(create bool) (string, error) {
+	if noPrompt && os.Getenv(secrets.PassphraseEnv) == "" {
+		return "", fmt.Errorf("%s is not set", secrets.PassphraseEnv)
+	}
+	return secrets.Prompt(create)
+}
+
+// openSecrets returns the profile's secret store, or nil when credentials
+// stay in the config file.
+func openSecrets() secrets.Store {
+	store, err := secretStore()
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(exitError)
+	}
+	return store
+}
+
+// loadSecrets reads the credentials kept in the profile's secret store.
+func loadSecrets() {
+	if store := openSecrets(); store != nil {
+		if err := settings.LoadSecrets(store); err != nil {
+			fmt.Printf("❌ %v\n", err)
+			os.Exit(exitError)
+		}
+	}
+}
+
+// warnEnvOverride says when an environment variable hides the saved key.
+func warnEnvOverride(key string) {
+	if k, ok := config.LookupKey(key); ok {
+		for _, env := range k.Env {
+			if os.Getenv(env) != "" {
+				fmt.Printf("⚠️  %s is set in the environment and takes precedence\n", env)
+				return
+			}
+		}
+	}
+}
+
+// listConfig prints every setting of the selected profile with where its
+// value came from. Secrets are shortened.
+func listConfig(f *config.File) {
+	var rows [][]string
+	for _, k := range config.Keys {
+		value := settings.Get(k.Name)
+		if k.Secret && len(value) > 8 {
+			value = value[:4] + "…"
+		}
+		rows = append(rows, []string{k.Name, value, settings.Source(k.Name)})
+	}
+
+	switch outputFormat {
+	case outputJSON:
+		type record struct {
+			Key    string `json:"key"`
+			Value  string `json:"value"`
+			Source string `json:"source"`
+		}
+		records := make([]record, len(rows))
+		for i, r := range rows {
+			records[i] = record{r[0], r[1], r[2]}
+		}
+		writeJSON(records)
+		return
+	case outputCSV, outputTSV:
+		writeDelimited(configColumns, rows)
+		return
+	case outputTable, outputPlain:
+		w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+		if outputFormat == outputTable {
+			fmt.Fprintln(w, strings.ToUpper(strings.Join(configColumns, "\t")))
+		}
+		for _, r := range rows {
+			fmt.Fprintln(w, strings.Join(r, "\t"))
+		}
+		w.Flush()
+		return
+	}
+
+	fmt.Printf("⚙️  Config file: %s\n", configPath)
+	if len(f.Profiles) == 0 {
+		fmt.Println("   No profiles yet; todo config set <key> <value> creates one")
+	}
+	for _, name := range f.Names() {
+		marker := " "
+		if name == profile {
+			marker = "*"
+		}
+		fmt.Printf("   %s %s\n", marker, name)
+	}
+	fmt.Println()
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	for _, r := range rows {
+		value := r[1]
+		if value == "" {
+			value = "-"
+		}
+		source := ""
+		if r[2] != "" {
+			source = "(" + r[2] + ")"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", r[0], value, source)
+	}
+	w.Flush()
+}
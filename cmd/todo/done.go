@@ -0,0 +1,118 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"todo-tracker/internal/completion"
+	"todo-tracker/internal/storage"
+	"todo-tracker/internal/supabase"
+)
+
+func cmdDone(ctx context.Context, args []string) {
+	fs := newBulkFlags("done")
+	cascade := fs.Bool("cascade", false, "also complete every open subtask")
+	keepParent := fs.Bool("keep-parent", false, "leave the parent open when its last subtask is done")
+	sel, _ := parseSelection(fs, args, "todo done <id>... [--cascade] [--keep-parent]", false)
+
+	targets, err := sel.resolve(ctx)
+	if sel.offline(err) {
+		// Offline, the subtasks cannot be checked: queue the tasks
+		// themselves and leave the rules to whoever replays them.
+		_, err = tasks.Update(ctx, supabase.Filter{IDs: sel.ids}, map[string]any{"status": "Done"})
+		if reportQueued(err) {
+			fmt.Println("⚠️  Subtasks were not checked while offline")
+			return
+		}
+	}
+	if err != nil {
+		fail("Cannot complete tasks", err)
+	}
+	if len(targets) == 0 {
+		fmt.Println("🔍 No matching tasks")
+		return
+	}
+	sel.confirm(ctx, "complete", targets)
+
+	opt := completion.Options{Cascade: *cascade, AutoParent: !*keepParent}
+	res, err := completion.CompleteAll(ctx, tasks, targets, opt, time.Now())
+
+	var open *completion.OpenSubtasksError
+	if errors.As(err, &open) {
+		today := time.Now().Format("2006-01-02")
+		fmt.Printf("❌ %d open subtask(s) would be left under finished tasks:\n", len(open.Open))
+		for _, t := range open.Open {
+			fmt.Println("   " + formatTaskLine(t, today, !structured()))
+		}
+		fmt.Printf("Finish them first, or complete them too with: todo %s --cascade\n", strings.Join(append([]string{"done"}, args...), " "))
+		os.Exit(exitError)
+	}
+	if err != nil && !errors.Is(err, storage.ErrQueued) {
+		if len(res.Tasks) > 0 {
+			printDone(res)
+		}
+		fail("Failed to complete tasks", err)
+	}
+
+	if emitTasks(res.Changed()...) {
+		return
+	}
+	printDone(res)
+	reportQueued(err)
+}
+
+func printDone(res completion.Result) {
+	if len(res.Tasks) == 1 {
+		fmt.Printf("✅ Marked as done: %s\n", res.Tasks[0].Title)
+	} else {
+		fmt.Printf("✅ Marked %d tasks as done:\n", len(res.Tasks))
+		for _, t := range res.Tasks {
+			fmt.Printf("   [id:%d] %s\n", t.ID, t.Title)
+		}
+	}
+	if n := len(res.Subtasks); n > 0 {
+		fmt.Printf("✅ Also completed %d subtask(s)\n", n)
+	}
+	for _, p := range res.Parents {
+		fmt.Printf("✅ All subtasks done, so [id:%d] %s is done too\n", p.ID, p.Title)
+	}
+	for _, next := range res.Scheduled {
+		fmt.Printf("🔁 Next: [id:%d] %s — due %s\n", next.ID, next.Title, next.Due())
+	}
+}
+
+func cmdReopen(ctx context.Context, args []string) {
+	if len(args) != 1 {
+		fmt.Println("❌ Missing task ID. Usage: todo reopen <id>")
+		os.Exit(1)
+	}
+
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		fmt.Println("❌ Invalid task ID")
+		os.Exit(1)
+	}
+
+	reopened, err := completion.Reopen(ctx, tasks, id)
+	if errors.Is(err, storage.ErrQueued) && len(reopened) == 0 {
+		reportQueued(err)
+		return
+	}
+	if err != nil && !errors.Is(err, storage.ErrQueued) {
+		fail("Failed to reopen task", err)
+	}
+
+	if emitTasks(reopened...) {
+		return
+	}
+	fmt.Printf("↩️  Reopened: %s\n", reopened[0].Title)
+	for _, p := range reopened[1:] {
+		fmt.Printf("↩️  Parent reopened: [id:%d] %s\n", p.ID, p.Title)
+	}
+	reportQueued(err)
+}
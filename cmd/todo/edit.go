@@ -0,0 +1,310 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"todo-tracker/internal/dateparse"
+	"todo-tracker/internal/supabase"
+)
+
+var (
+	priorityPattern = regexp.MustCompile(`^P[0-4]$`)
+	datePattern     = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}$`)
+)
+
+// taskEdit holds the editable fields of a task. A nil ParentID means the
+// task has no parent.
+type taskEdit struct {
+	Title    string
+	DueDate  string
+	DueTime  string // HH:MM or empty
+	Priority string
+	ParentID *int
+}
+
+func editFromTask(t *supabase.Task) taskEdit {
+	return taskEdit{Title: t.Title, DueDate: t.DueDate, DueTime: t.Clock(), Priority: t.Priority, ParentID: t.ParentID}
+}
+
+func cmdEdit(ctx context.Context, args []string) {
+	if len(args) == 0 {
+		fmt.Println("❌ Missing task ID. Usage: todo edit <id> [--title T] [--due D] [--priority P] [--parent ID|--no-parent]")
+		os.Exit(exitError)
+	}
+
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		fmt.Println("❌ Invalid task ID")
+		os.Exit(exitError)
+	}
+
+	fs := flag.NewFlagSet("edit", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	title := fs.String("title", "", "new title")
+	due := fs.String("due", "", "new due date")
+	priority := fs.String("priority", "", "new priority")
+	parent := fs.Int("parent", 0, "new parent task ID")
+	noParent := fs.Bool("no-parent", false, "detach from parent")
+	if err := fs.Parse(args[1:]); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(exitError)
+	}
+
+	task, err := tasks.Get(ctx, id)
+	if err != nil {
+		fail("Cannot edit task", err)
+	}
+	current := editFromTask(task)
+
+	var want taskEdit
+	if fs.NFlag() == 0 {
+		want, err = editInEditor(id, current)
+		if err != nil {
+			fmt.Printf("❌ %v\n", err)
+			os.Exit(exitError)
+		}
+	} else {
+		want = current
+		set := map[string]bool{}
+		fs.Visit(func(f *flag.Flag) { set[f.Name] = true })
+		if set["title"] {
+			want.Title = strings.TrimSpace(*title)
+		}
+		if set["due"] {
+			// Keep the existing time of day unless the new value has one.
+			d, err := dateparse.Parse(*due, time.Now())
+			if err != nil {
+				fmt.Printf("❌ Invalid due date %q\n", *due)
+				os.Exit(exitError)
+			}
+			want.DueDate = d.DateString()
+			if d.Time != "" {
+				want.DueTime = d.Time
+			}
+		}
+		if set["priority"] {
+			want.Priority = strings.ToUpper(*priority)
+		}
+		if set["parent"] && set["no-parent"] {
+			fmt.Println("❌ Use either --parent or --no-parent, not both")
+			os.Exit(exitError)
+		}
+		if set["parent"] {
+			want.ParentID = parent
+		}
+		if *noParent {
+			want.ParentID = nil
+		}
+	}
+
+	if err := validateEdit(ctx, id, want); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(exitError)
+	}
+
+	fields := diffEdit(current, want)
+	if len(fields) == 0 {
+		if emitTasks(*task) {
+			return
+		}
+		fmt.Println("✅ No changes")
+		return
+	}
+
+	updated, err := tasks.UpdateByID(ctx, id, fields)
+	if reportQueued(err) {
+		return
+	}
+	if err != nil {
+		fail("Failed to edit task", err)
+	}
+
+	if emitTasks(*updated) {
+		return
+	}
+	fmt.Printf("✅ Updated #%d: %s — due %s [%s]%s\n", updated.ID, updated.Title, updated.Due(), updated.Priority, parentSuffix(updated.ParentID))
+}
+
+func parentSuffix(parentID *int) string {
+	if parentID == nil {
+		return ""
+	}
+	return fmt.Sprintf(" (under #%d)", *parentID)
+}
+
+// diffEdit returns the PATCH body for the fields that differ between from and to.
+func diffEdit(from, to taskEdit) map[string]any {
+	fields := map[string]any{}
+	if to.Title != from.Title {
+		fields["title"] = to.Title
+	}
+	if to.DueDate != from.DueDate {
+		fields["due_date"] = to.DueDate
+	}
+	if to.DueTime != from.DueTime {
+		if to.DueTime == "" {
+			fields["due_time"] = nil
+		} else {
+			fields["due_time"] = to.DueTime
+		}
+	}
+	if to.Priority != from.Priority {
+		fields["priority"] = to.Priority
+	}
+	switch {
+	case to.ParentID == nil && from.ParentID != nil:
+		fields["parent_id"] = nil
+	case to.ParentID != nil && (from.ParentID == nil || *from.ParentID != *to.ParentID):
+		fields["parent_id"] = *to.ParentID
+	}
+	return fields
+}
+
+// validateEdit checks field formats and that a new parent exists and would
+// not make the task its own ancestor.
+func validateEdit(ctx context.Context, id int, e taskEdit) error {
+	if e.Title == "" {
+		return errors.New("title cannot be empty")
+	}
+	if !datePattern.MatchString(e.DueDate) {
+		return fmt.Errorf("invalid due date %q (expected YYYY-MM-DD)", e.DueDate)
+	}
+	if !priorityPattern.MatchString(e.Priority) {
+		return fmt.Errorf("invalid priority %q (expected P0-P4)", e.Priority)
+	}
+	if e.ParentID == nil {
+		return nil
+	}
+	for ancestor, seen := *e.ParentID, 0; ; seen++ {
+		if ancestor == id {
+			return errors.New("a task cannot be nested under itself or its subtasks")
+		}
+		t, err := tasks.Get(ctx, ancestor)
+		if err != nil {
+			return fmt.Errorf("parent #%d: %s", ancestor, describeError(err))
+		}
+		if t.ParentID == nil || seen > 100 {
+			return nil
+		}
+		ancestor = *t.ParentID
+	}
+}
+
+// editInEditor opens $VISUAL/$EDITOR on a rendering of e and parses the result.
+func editInEditor(id int, e taskEdit) (taskEdit, error) {
+	f, err := os.CreateTemp("", fmt.Sprintf("todo-%d-*.yaml", id))
+	if err != nil {
+		return e, err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.WriteString(renderEdit(id, e)); err != nil {
+		f.Close()
+		return e, err
+	}
+	f.Close()
+
+	if err := runEditor(f.Name()); err != nil {
+		return e, err
+	}
+	data, err := os.ReadFile(f.Name())
+	if err != nil {
+		return e, err
+	}
+	return parseEdit(string(data))
+}
+
+// runEditor opens $VISUAL, $EDITOR or vi on path and waits for it to exit.
+func runEditor(path string) error {
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	if editor == "" {
+		editor = "vi"
+	}
+	argv := append(strings.Fields(editor), path)
+	cmd := exec.Command(argv[0], argv[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("editor %q failed: %v", editor, err)
+	}
+	return nil
+}
+
+func renderEdit(id int, e taskEdit) string {
+	parent := ""
+	if e.ParentID != nil {
+		parent = strconv.Itoa(*e.ParentID)
+	}
+	due := e.DueDate
+	if e.DueTime != "" {
+		due += " " + e.DueTime
+	}
+	return fmt.Sprintf(`# Editing task #%d. Save and quit to apply; lines starting with # are ignored.
+# due: a date with optional time (2026-02-15 14:00, friday, +3d)
+# priority: P0-P4, parent: task ID (leave empty for none)
+title: %s
+due: %s
+priority: %s
+parent: %s
+`, id, e.Title, due, e.Priority, parent)
+}
+
+func parseEdit(text string) (taskEdit, error) {
+	var e taskEdit
+	seen := map[string]bool{}
+	sc := bufio.NewScanner(strings.NewReader(text))
+	for n := 1; sc.Scan(); n++ {
+		line := strings.TrimSpace(sc.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return e, fmt.Errorf("line %d: expected \"key: value\"", n)
+		}
+		key, value = strings.ToLower(strings.TrimSpace(key)), strings.TrimSpace(value)
+		switch key {
+		case "title":
+			e.Title = value
+		case "due":
+			d, err := dateparse.Parse(value, time.Now())
+			if err != nil {
+				return e, fmt.Errorf("line %d: invalid due date %q", n, value)
+			}
+			e.DueDate, e.DueTime = d.DateString(), d.Time
+		case "priority":
+			e.Priority = strings.ToUpper(value)
+		case "parent":
+			if value == "" {
+				break
+			}
+			pid, err := strconv.Atoi(strings.TrimPrefix(value, "#"))
+			if err != nil {
+				return e, fmt.Errorf("line %d: invalid parent ID %q", n, value)
+			}
+			e.ParentID = &pid
+		default:
+			return e, fmt.Errorf("line %d: unknown field %q", n, key)
+		}
+		seen[key] = true
+	}
+	for _, k := range []string{"title", "due", "priority"} {
+		if !seen[k] {
+			return e, fmt.Errorf("missing %q line", k)
+		}
+	}
+	return e, nil
+}
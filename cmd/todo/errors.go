@@ -0,0 +1,83 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+
+	"todo-tracker/internal/supabase"
+)
+
+// Exit codes, so scripts can tell failure classes apart.
+const (
+	exitError      = 1 // usage errors and anything unclassified
+	exitAuth       = 3 // token missing, expired, revoked or rejected by RLS
+	exitNotFound   = 4
+	exitConstraint = 5 // the database rejected the values
+	exitRateLimit  = 6
+	exitServer     = 7
+	exitNetwork    = 8
+	exitCanceled   = 130 // interrupted with Ctrl+C, as shells report SIGINT
+)
+
+func exitCode(err error) int {
+	switch {
+	case errors.Is(err, context.Canceled):
+		return exitCanceled
+	case errors.Is(err, supabase.ErrAuth):
+		return exitAuth
+	case errors.Is(err, supabase.ErrNotFound):
+		return exitNotFound
+	case errors.Is(err, supabase.ErrConstraint):
+		return exitConstraint
+	case errors.Is(err, supabase.ErrRateLimit):
+		return exitRateLimit
+	case errors.Is(err, supabase.ErrServer):
+		return exitServer
+	case supabase.IsNetworkError(err):
+		return exitNetwork
+	}
+	return exitError
+}
+
+// describeError turns err into a short explanation for the terminal.
+func describeError(err error) string {
+	var apiErr *supabase.APIError
+	detail := err.Error()
+	if errors.As(err, &apiErr) {
+		detail = apiErr.Message
+		if apiErr.Details != "" {
+			detail += " (" + apiErr.Details + ")"
+		}
+	}
+	switch {
+	case errors.Is(err, context.Canceled):
+		return "interrupted"
+	case errors.Is(err, context.DeadlineExceeded):
+		return "Supabase did not respond in time"
+	case supabase.IsRowSecurityError(err):
+		return "not allowed for your user by row-level security: " + detail
+	case errors.Is(err, supabase.ErrAuth):
+		return "not authorized — your token may be expired or revoked; run todo login for a new one: " + detail
+	case errors.Is(err, supabase.ErrNotFound) && apiErr == nil:
+		return err.Error() // may name the missing IDs
+	case errors.Is(err, supabase.ErrNotFound):
+		return "task not found"
+	case errors.Is(err, supabase.ErrConstraint):
+		return "rejected by the database: " + detail
+	case errors.Is(err, supabase.ErrRateLimit):
+		return "rate limited by Supabase, try again shortly"
+	case errors.Is(err, supabase.ErrServer):
+		return "Supabase server error: " + detail
+	case supabase.IsNetworkError(err):
+		return "cannot reach Supabase: " + detail
+	}
+	return detail
+}
+
+// fail reports err under msg and exits with the code for its class.
+func fail(msg string, err error) {
+	fmt.Printf("❌ %s: %s\n", msg, describeError(err))
+	os.Exit(exitCode(err))
+}
@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"todo-tracker/internal/storage"
+)
+
+// historyColumns is the field order of structured history rows, one per
+// changed task field.
+var historyColumns = []string{"n", "at", "command", "undone", "task_id", "field", "before", "after"}
+
+// historyRecord is an action as written by --output json. N is the count
+// `todo undo` needs to reach it, or null once undone.
+type historyRecord struct {
+	N       *int             `json:"n"`
+	At      string           `json:"at"`
+	Command string           `json:"command"`
+	Undone  bool             `json:"undone"`
+	Changes []storage.Change `json:"changes"`
+}
+
+func cmdHistory(args []string) {
+	limit := 10
+	if len(args) > 0 {
+		n, err := strconv.Atoi(args[0])
+		if err != nil || n < 1 || len(args) > 1 {
+			fmt.Println("❌ Usage: todo history [n]")
+			os.Exit(1)
+		}
+		limit = n
+	}
+
+	actions, err := journal.History()
+	if err != nil {
+		fail("Cannot read the change history", err)
+	}
+	slices.Reverse(actions)
+	if len(actions) > limit {
+		actions = actions[:limit]
+	}
+	numbers := undoNumbers(actions)
+
+	if emitHistory(actions, numbers) {
+		return
+	}
+	if len(actions) == 0 {
+		fmt.Println("📜 No changes recorded yet")
+		return
+	}
+	fmt.Print("📜 Recent changes (newest first):\n\n")
+	for i, a := range actions {
+		n, suffix := "  -", " (undone)"
+		if !a.Undone {
+			n, suffix = fmt.Sprintf("%3d", numbers[i]), ""
+		}
+		fmt.Printf("%s  %s  %s%s\n", n, actionTime(a), a.Command, suffix)
+		for _, line := range describeChanges(a, false) {
+			fmt.Println("       " + line)
+		}
+	}
+}
+
+func cmdUndo(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("undo", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	force := fs.Bool("force", false, "revert even if the tasks changed since")
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(exitError)
+	}
+	n := 1
+	if len(positional) > 0 {
+		n, err = strconv.Atoi(positional[0])
+		if err != nil || n < 1 || len(positional) > 1 {
+			fmt.Println("❌ Usage: todo undo [n] [--force]")
+			os.Exit(1)
+		}
+	}
+
+	undone, restored, err := journal.Undo(ctx, n, *force)
+	var changed *storage.ChangedError
+	if errors.As(err, &changed) {
+		fmt.Printf("❌ Cannot undo %q: ", changed.Action.Command)
+		if changed.Field == "" {
+			fmt.Printf("task %d no longer exists\n", changed.TaskID)
+		} else {
+			fmt.Printf("task %d %s was changed since (now %s, expected %s)\n", changed.TaskID, changed.Field,
+				journalValue(changed.Got), journalValue(changed.Want))
+		}
+		fmt.Println("   Run 'todo undo --force' to revert it anyway")
+		os.Exit(exitError)
+	}
+	for _, a := range undone {
+		fmt.Printf("↩️  Undid: %s\n", a.Command)
+		for _, line := range describeChanges(a, true) {
+			fmt.Println("   " + line)
+		}
+	}
+	if err != nil {
+		fail("Failed to undo", err)
+	}
+	if emitTasks(restored...) {
+		return
+	}
+	if len(undone) == 0 {
+		fmt.Println("📜 Nothing to undo")
+	}
+}
+
+// undoNumbers returns, for each action newest first, how many actions
+// `todo undo` must revert to include it. Undone actions get 0.
+func undoNumbers(actions []storage.Action) []int {
+	numbers := make([]int, len(actions))
+	n := 0
+	for i, a := range actions {
+		if !a.Undone {
+			n++
+			numbers[i] = n
+		}
+	}
+	return numbers
+}
+
+// describeChanges renders an action's changes, e.g. "#15 status: Todo →
+// Done", or the other way round when reverted.
+func describeChanges(a storage.Action, reverted bool) []string {
+	var lines []string
+	for _, c := range a.Changes {
+		if c.Created {
+			line := fmt.Sprintf("#%d created", c.TaskID)
+			if reverted {
+				line = fmt.Sprintf("#%d closed (undo cannot delete the tasks a command created)", c.TaskID)
+			}
+			lines = append(lines, line)
+			continue
+		}
+		for _, field := range sortedKeys(c.After) {
+			from, to := journalValue(c.Before[field]), journalValue(c.After[field])
+			if reverted {
+				from, to = to, from
+			}
+			lines = append(lines, fmt.Sprintf("#%d %s: %s → %s", c.TaskID, field, from, to))
+		}
+	}
+	return lines
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// journalValue renders a field value read back from the journal.
+func journalValue(v any) string {
+	switch v := v.(type) {
+	case nil:
+		return "—"
+	case []any:
+		parts := make([]string, len(v))
+		for i, p := range v {
+			parts[i] = fmt.Sprint(p)
+		}
+		if len(parts) == 0 {
+			return "—"
+		}
+		return strings.Join(parts, " ")
+	case float64:
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return fmt.Sprint(v)
+}
+
+func actionTime(a storage.Action) string {
+	at, err := time.Parse(time.RFC3339, a.At)
+	if err != nil {
+		return a.At
+	}
+	return at.Local().Format("Jan _2 15:04")
+}
+
+// emitHistory writes actions in the selected structured format. It
+// returns false in text mode.
+func emitHistory(actions []storage.Action, numbers []int) bool {
+	switch outputFormat {
+	case outputText:
+		return false
+	case outputJSON:
+		records := make([]historyRecord, len(actions))
+		for i, a := range actions {
+			records[i] = historyRecord{At: a.At, Command: a.Command, Undone: a.Undone, Changes: a.Changes}
+			if !a.Undone {
+				records[i].N = &numbers[i]
+			}
+			if records[i].Changes == nil {
+				records[i].Changes = []storage.Change{}
+			}
+		}
+		writeJSON(records)
+		return true
+	}
+
+	var rows [][]string
+	for i, a := range actions {
+		n := ""
+		if !a.Undone {
+			n = strconv.Itoa(numbers[i])
+		}
+		row := func(id int, field, before, after string) {
+			rows = append(rows, []string{n, a.At, a.Command, strconv.FormatBool(a.Undone), strconv.Itoa(id), field, before, after})
+		}
+		for _, c := range a.Changes {
+			if c.Created {
+				row(c.TaskID, "", "", "created")
+				continue
+			}
+			for _, field := range sortedKeys(c.After) {
+				row(c.TaskID, field, journalValue(c.Before[field]), journalValue(c.After[field]))
+			}
+		}
+	}
+	if outputFormat == outputCSV || outputFormat == outputTSV {
+		writeDelimited(historyColumns, rows)
+		return true
+	}
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	if outputFormat == outputTable {
+		fmt.Fprintln(w, strings.ToUpper(strings.Join(historyColumns, "\t")))
+	}
+	for _, r := range rows {
+		fmt.Fprintln(w, strings.Join(r, "\t"))
+	}
+	w.Flush()
+	return true
+}
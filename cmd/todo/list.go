@@ -0,0 +1,278 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+	"strings"
+	"time"
+
+	"todo-tracker/internal/labels"
+	"todo-tracker/internal/query"
+	"todo-tracker/internal/recurrence"
+	"todo-tracker/internal/supabase"
+	"todo-tracker/internal/tree"
+)
+
+// stringList is a flag that may be given more than once.
+type stringList []string
+
+func (l *stringList) String() string { return strings.Join(*l, ",") }
+
+func (l *stringList) Set(v string) error {
+	*l = append(*l, v)
+	return nil
+}
+
+// filterFlags are the task filter flags shared by list and the bulk forms
+// of done and snooze.
+var filterFlags = []string{
+	"tag", "project", "status", "priority", "due-before", "due-after",
+	"overdue", "today", "week", "parent", "search",
+}
+
+// addFilterFlags defines filterFlags on fs. They are read back with
+// applyFilterFlags.
+func addFilterFlags(fs *flag.FlagSet) {
+	fs.Var(&stringList{}, "tag", "only tasks with this tag")
+	fs.String("project", "", "only tasks in this project")
+	fs.String("status", "", "Todo, Done or all")
+	fs.String("priority", "", "P1, P0..P2 or P0,P3")
+	fs.String("due-before", "", "due before this date")
+	fs.String("due-after", "", "due after this date")
+	fs.Bool("overdue", false, "only overdue tasks")
+	fs.Bool("today", false, "only tasks due today")
+	fs.Bool("week", false, "only tasks due by the end of this week")
+	fs.Int("parent", 0, "only subtasks of this task")
+	fs.String("search", "", "title contains this text")
+}
+
+// applyFilterFlags narrows f with the filter flags set on fs and reports
+// whether there were any.
+func applyFilterFlags(fs *flag.FlagSet, f *supabase.Filter, now time.Time) (bool, error) {
+	// Flags are all conditions that narrow the filter.
+	var conds [][3]string
+	fs.Visit(func(fl *flag.Flag) {
+		v := fl.Value.String()
+		switch fl.Name {
+		case "status":
+			conds = append(conds, [3]string{"status", "=", v})
+		case "priority":
+			conds = append(conds, [3]string{"priority", "=", v})
+		case "due-before":
+			conds = append(conds, [3]string{"due", "<", v})
+		case "due-after":
+			conds = append(conds, [3]string{"due", ">", v})
+		case "overdue":
+			conds = append(conds, [3]string{"due", "<", "today"})
+		case "today":
+			conds = append(conds, [3]string{"due", "=", "today"})
+		case "week":
+			conds = append(conds, [3]string{"due", "<=", "eow"})
+		case "parent":
+			conds = append(conds, [3]string{"parent", "=", v})
+		case "search":
+			conds = append(conds, [3]string{"title", "~", v})
+		case "project":
+			conds = append(conds, [3]string{"project", "=", v})
+		case "tag":
+			for _, t := range *fl.Value.(*stringList) {
+				conds = append(conds, [3]string{"tag", "=", t})
+			}
+		}
+	})
+	for _, c := range conds {
+		if err := query.Apply(f, c[0], c[1], c[2], now); err != nil {
+			return false, err
+		}
+	}
+	set := false
+	fs.Visit(func(fl *flag.Flag) { set = set || slices.Contains(filterFlags, fl.Name) })
+	return set, nil
+}
+
+func cmdList(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("list", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	addFilterFlags(fs)
+	sortSpec := fs.String("sort", "", "fields to sort by, e.g. due,-priority")
+	limit := fs.Int("limit", 0, "show at most this many tasks")
+	flat := fs.Bool("flat", false, "one task per line, without subtask trees")
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(exitError)
+	}
+
+	now := time.Now()
+	filter := supabase.Filter{
+		UserID: userID,
+		Status: "Todo",
+		Order:  []string{"priority", "due_date"},
+		Limit:  *limit,
+	}
+	if _, err := applyFilterFlags(fs, &filter, now); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(exitError)
+	}
+
+	// "#work" and "+infra" may be given as plain words; anything else is
+	// a query expression such as "priority<=P1 and due<+7d".
+	tags, proj, expr := labels.Extract(strings.Join(positional, " "))
+	for _, t := range tags {
+		query.Apply(&filter, "tag", "=", t, now)
+	}
+	if proj != "" {
+		filter.Project = proj
+	}
+	if err := query.Parse(&filter, expr, now); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(exitError)
+	}
+	if *sortSpec != "" {
+		if filter.Order, err = query.Sort(*sortSpec); err != nil {
+			fmt.Printf("❌ %v\n", err)
+			os.Exit(exitError)
+		}
+	}
+
+	filtered := len(positional) > 0
+	fs.Visit(func(f *flag.Flag) { filtered = filtered || f.Name != "flat" })
+	found, err := tasks.List(ctx, filter)
+	if err != nil {
+		fail("Failed to fetch tasks", err)
+	}
+	if emitTasks(found...) {
+		return
+	}
+
+	if len(found) == 0 {
+		if filtered {
+			fmt.Println("🔍 No matching tasks")
+		} else {
+			fmt.Println("🎉 No pending tasks!")
+		}
+		return
+	}
+
+	todayDate := now.Format("2006-01-02")
+	if filtered {
+		fmt.Printf("📋 Matching tasks (%d):\n\n", len(found))
+	} else {
+		fmt.Print("📋 All pending tasks:\n\n")
+	}
+
+	if *flat {
+		for _, t := range found {
+			fmt.Println(formatTaskLine(t, todayDate, true))
+		}
+		return
+	}
+	progress := subtaskProgress(ctx, found)
+	tree.Walk(tree.Build(found), func(n *tree.Node, prefix string) {
+		fmt.Println(prefix + formatTaskLine(n.Task, todayDate, true) + progressSuffix(progress[n.Task.ID]))
+	})
+}
+
+// subtaskProgress counts the subtasks of each task, done ones included. A
+// failure only costs the counts, so it is not fatal.
+func subtaskProgress(ctx context.Context, parents []supabase.Task) map[int]tree.Progress {
+	ids := make([]int, len(parents))
+	for i, t := range parents {
+		ids[i] = t.ID
+	}
+	children, err := tasks.List(ctx, supabase.Filter{UserID: userID, ParentIDs: ids})
+	if err != nil {
+		return nil
+	}
+	return tree.Count(children)
+}
+
+// progressSuffix renders subtask progress, e.g. " (2/5 done)".
+func progressSuffix(p tree.Progress) string {
+	if p.Total == 0 {
+		return ""
+	}
+	return fmt.Sprintf(" (%d/%d done)", p.Done, p.Total)
+}
+
+// formatTaskLine renders a task as one line of `todo list` output. Without
+// styling it has no colour or emoji, for --output plain.
+func formatTaskLine(t supabase.Task, today string, styled bool) string {
+	paint := func(code, s string) string {
+		if !styled {
+			return s
+		}
+		return "\033[" + code + "m" + s + "\033[0m"
+	}
+
+	state := ""
+	switch {
+	case t.Status == "Done" && styled:
+		state = " " + paint("32", "✓ done")
+	case t.Status == "Done":
+		state = " (done)"
+	case t.DueDate < today && styled:
+		state = " " + paint("31", "⚠️ overdue")
+	case t.DueDate < today:
+		state = " (overdue)"
+	}
+
+	dueInfo := ""
+	if t.DueDate == today {
+		dueInfo = " " + paint("33", "(today)")
+		if t.DueTime != "" {
+			dueInfo = " " + paint("33", "(today "+t.Clock()+")")
+		}
+	} else {
+		dueInfo = fmt.Sprintf(" — due %s", t.Due())
+	}
+
+	repeat := repeatSuffix(t)
+	if !styled && t.Recurrence != "" {
+		repeat = " (" + recurrence.Describe(t.Recurrence) + ")"
+	}
+
+	return fmt.Sprintf("[id:%d] [%s] %s%s%s%s%s", t.ID, t.Priority, t.Title, labelSuffix(t, styled), dueInfo, repeat, state)
+}
+
+// parseInterspersed parses flags that may appear before, between or after
+// positional arguments and returns the positional ones.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+// labelSuffix renders a task's project and tags, e.g. " +infra #work",
+// in colour when styled.
+func labelSuffix(t supabase.Task, styled bool) string {
+	s := labels.Format(t.Tags, t.Project)
+	switch {
+	case s == "":
+		return ""
+	case !styled:
+		return " " + s
+	}
+	return " \033[36m" + s + "\033[0m"
+}
+
+// repeatSuffix describes a recurring task's rule, e.g. " 🔁 every Mon".
+func repeatSuffix(t supabase.Task) string {
+	if t.Recurrence == "" {
+		return ""
+	}
+	return " 🔁 " + recurrence.Describe(t.Recurrence)
+}
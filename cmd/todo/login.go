@@ -0,0 +1,372 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"todo-tracker/internal/supabase"
+)
+
+// tokenColumns are the fields of `todo tokens` in structured formats.
+var tokenColumns = []string{"id", "name", "created_at", "expires_at", "current"}
+
+// authClient talks to the auth-verify function of the configured project.
+func authClient() *supabase.Client {
+	return supabase.NewClient(settings.Get("supabase_url"), settings.Get("anon_key"))
+}
+
+// requireToken returns the configured token, or exits telling the user to
+// log in.
+func requireToken() string {
+	token := settings.Get("token")
+	if token == "" {
+		fmt.Println("❌ Not logged in. Run todo login")
+		os.Exit(exitAuth)
+	}
+	return token
+}
+
+// cmdLogin gets a token, by pairing with the bot or from stdin, verifies
+// it and saves it in the profile. There is no flag taking the token
+// itself, which would leave it in ps output and the shell history.
+func cmdLogin(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("login", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	paste := fs.Bool("paste", false, "read a token from /token on stdin instead of pairing")
+	name := fs.String("name", defaultTokenName(), "name of the new token")
+	if err := fs.Parse(args); err != nil || fs.NArg() > 0 {
+		fmt.Println("❌ Usage: todo login [--name NAME] [--paste]")
+		os.Exit(exitError)
+	}
+
+	client := authClient()
+	var token string
+	switch {
+	case *paste || !stdinIsTerminal():
+		token = readToken(ctx)
+	default:
+		token = pair(ctx, client, *name)
+	}
+
+	info, err := client.VerifyToken(ctx, token)
+	if err != nil {
+		fail("Token not accepted", err)
+	}
+	saved, err := saveSetting("token", token)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(exitError)
+	}
+	// A token read from the old file now lives in the profile.
+	if settings.Source("token") == "~/.todo-cli-token" {
+		removeLegacyToken()
+	}
+	// The first login creates a profile, which has its own state.
+	if saved != profile {
+		stateDir = profileStateDir(saved)
+	}
+	if cache := tokenCache(); cache != nil {
+		cache.Store(token, info, time.Now())
+	}
+
+	fmt.Printf("✅ Logged in as user %s with token %q%s, saved in %s\n", info.UserID, info.TokenName, expirySuffix(info.ExpiresAt), savedIn("token", saved))
+	warnEnvOverride("token")
+}
+
+func defaultTokenName() string {
+	if host, err := os.Hostname(); err == nil && host != "" {
+		return "CLI on " + host
+	}
+	return "CLI Token"
+}
+
+func stdinIsTerminal() bool {
+	fi, err := os.Stdin.Stat()
+	return err == nil && fi.Mode()&os.ModeCharDevice != 0
+}
+
+func readToken(ctx context.Context) string {
+	if stdinIsTerminal() {
+		fmt.Print("Paste the token from /token: ")
+	}
+	line, err := readLine(ctx)
+	if ctx.Err() != nil {
+		fail("Cancelled", ctx.Err())
+	}
+	token := strings.TrimSpace(line)
+	if token == "" {
+		if err != nil && !errors.Is(err, io.EOF) {
+			fmt.Printf("❌ %v\n", err)
+		} else {
+			fmt.Println("❌ No token given")
+		}
+		os.Exit(exitError)
+	}
+	return token
+}
+
+// readLine reads a line from stdin, giving up when ctx is cancelled: with
+// Ctrl+C handled by the context, a blocked read would otherwise ignore it.
+func readLine(ctx context.Context) (string, error) {
+	type result struct {
+		line string
+		err  error
+	}
+	read := make(chan result, 1)
+	go func() {
+		line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+		read <- result{line, err}
+	}()
+	select {
+	case r := <-read:
+		return r.line, r.err
+	case <-ctx.Done():
+		fmt.Println()
+		return "", ctx.Err()
+	}
+}
+
+// pair shows a code for the user to send to the bot and waits until the
+// bot has created a token for it.
+func pair(ctx context.Context, client *supabase.Client, name string) string {
+	p, err := client.StartPairing(ctx, name)
+	if err != nil {
+		fail("Cannot start login", err)
+	}
+	fmt.Printf("🔗 Send this to the Telegram bot to log in:\n\n    /login %s\n\n", p.Code)
+	fmt.Println("⏳ Waiting for the bot (the code expires in 10 minutes; Ctrl+C to cancel)...")
+
+	interval := time.Duration(p.Interval) * time.Second
+	if interval <= 0 {
+		interval = 3 * time.Second
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			fail("Login cancelled", ctx.Err())
+		case <-time.After(interval):
+		}
+		r, err := client.PollPairing(ctx, p.DeviceCode)
+		if supabase.IsNetworkError(err) {
+			continue // keep waiting through a dropped connection
+		}
+		if err != nil {
+			fail("Login failed", err)
+		}
+		if !r.Pending {
+			return r.Token
+		}
+	}
+}
+
+// cmdLogout revokes the token on the server, unless --keep is given, and
+// removes it from the profile.
+func cmdLogout(ctx context.Context, args []string) {
+	keep := len(args) == 1 && args[0] == "--keep"
+	if len(args) > 0 && !keep {
+		fmt.Println("❌ Usage: todo logout [--keep]")
+		os.Exit(exitError)
+	}
+	token := requireToken()
+
+	if !keep {
+		client := authClient()
+		info, err := client.VerifyToken(ctx, token)
+		if err == nil {
+			_, err = client.RevokeToken(ctx, token, info.TokenID)
+		}
+		switch {
+		case errors.Is(err, supabase.ErrAuth):
+			// Already revoked or expired: nothing to do on the server.
+		case err != nil:
+			fmt.Printf("⚠️  Could not revoke the token on the server (%s); revoke it with /revoke in Telegram\n", describeError(err))
+		default:
+			fmt.Printf("🔒 Revoked token %q\n", info.TokenName)
+		}
+	}
+
+	source := settings.Source("token")
+	switch {
+	case strings.HasPrefix(source, "env "):
+		fmt.Printf("⚠️  %s is set in the environment; unset it to log out completely\n", strings.TrimPrefix(source, "env "))
+	case source == "~/.todo-cli-token":
+		removeLegacyToken()
+	default:
+		if _, err := saveSetting("token", ""); err != nil {
+			fmt.Printf("❌ %v\n", err)
+			os.Exit(exitError)
+		}
+	}
+	if cache := tokenCache(); cache != nil {
+		cache.Clear()
+	}
+	fmt.Println("👋 Logged out")
+}
+
+func removeLegacyToken() {
+	if home, err := os.UserHomeDir(); err == nil {
+		os.Remove(filepath.Join(home, ".todo-cli-token"))
+	}
+}
+
+// cmdWhoami shows who the configured token belongs to.
+func cmdWhoami(ctx context.Context, args []string) {
+	if len(args) > 0 {
+		fmt.Println("❌ Usage: todo whoami")
+		os.Exit(exitError)
+	}
+	if settings.Get("token") == "" && settings.Get("backend") == "local" {
+		if !emitValue(map[string]any{"backend": "local"}, []string{"backend"}, []string{"local"}) {
+			fmt.Println("💾 Local backend, no account")
+		}
+		return
+	}
+
+	info, err := checkToken(ctx, requireToken())
+	if err != nil {
+		fail("Cannot verify token", err)
+	}
+	v := struct {
+		UserID    string  `json:"user_id"`
+		TokenName string  `json:"token_name"`
+		TokenID   int     `json:"token_id"`
+		ExpiresAt *string `json:"expires_at"`
+		Profile   *string `json:"profile"`
+		URL       string  `json:"supabase_url"`
+	}{info.UserID, info.TokenName, info.TokenID, optional(info.ExpiresAt), optional(profile), settings.Get("supabase_url")}
+	row := []string{info.UserID, info.TokenName, strconv.Itoa(info.TokenID), info.ExpiresAt, profile, v.URL}
+	if emitValue(v, []string{"user_id", "token_name", "token_id", "expires_at", "profile", "supabase_url"}, row) {
+		return
+	}
+
+	fmt.Printf("👤 User %s\n", info.UserID)
+	fmt.Printf("🔑 Token %q (id %d)%s\n", info.TokenName, info.TokenID, expirySuffix(info.ExpiresAt))
+	fmt.Printf("⚙️  %s (token from %s)\n", v.URL, settings.Source("token"))
+}
+
+// cmdTokens lists the user's tokens or revokes one, like /revoke.
+func cmdTokens(ctx context.Context, args []string) {
+	usage := "❌ Usage: todo tokens [list] | todo tokens revoke <id>"
+	switch {
+	case len(args) == 0 || len(args) == 1 && args[0] == "list":
+		listTokens(ctx)
+	case len(args) == 2 && args[0] == "revoke":
+		id, err := strconv.Atoi(args[1])
+		if err != nil {
+			fmt.Println("❌ Invalid token ID")
+			os.Exit(exitError)
+		}
+		revokeToken(ctx, id)
+	default:
+		fmt.Println(usage)
+		os.Exit(exitError)
+	}
+}
+
+func listTokens(ctx context.Context) {
+	tokens, err := authClient().ListTokens(ctx, requireToken())
+	if err != nil {
+		fail("Cannot list tokens", err)
+	}
+
+	if emitTokens(tokens) {
+		return
+	}
+	fmt.Println("🔑 Your API tokens:")
+	fmt.Println()
+	for _, t := range tokens {
+		current := ""
+		if t.Current {
+			current = "  ← this CLI"
+		}
+		fmt.Printf("[id:%d] %s%s\n", t.ID, t.Name, current)
+		fmt.Printf("  Created: %s, Expires: %s\n", day(t.CreatedAt), day(t.ExpiresAt))
+	}
+	fmt.Println()
+	fmt.Println("To revoke: todo tokens revoke <id>")
+}
+
+// emitTokens writes tokens in the selected structured format. It returns
+// false in text mode.
+func emitTokens(tokens []supabase.APIToken) bool {
+	switch outputFormat {
+	case outputText:
+		return false
+	case outputJSON:
+		if tokens == nil {
+			tokens = []supabase.APIToken{}
+		}
+		writeJSON(tokens)
+		return true
+	}
+
+	var rows [][]string
+	for _, t := range tokens {
+		rows = append(rows, []string{strconv.Itoa(t.ID), t.Name, t.CreatedAt, t.ExpiresAt, strconv.FormatBool(t.Current)})
+	}
+	if outputFormat == outputCSV || outputFormat == outputTSV {
+		writeDelimited(tokenColumns, rows)
+		return true
+	}
+	w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+	if outputFormat == outputTable {
+		fmt.Fprintln(w, strings.ToUpper(strings.Join(tokenColumns, "\t")))
+	}
+	for _, r := range rows {
+		fmt.Fprintln(w, strings.Join(r, "\t"))
+	}
+	w.Flush()
+	return true
+}
+
+func revokeToken(ctx context.Context, id int) {
+	token := requireToken()
+	client := authClient()
+	info, err := client.VerifyToken(ctx, token)
+	if err != nil {
+		fail("Cannot verify token", err)
+	}
+	revoked, err := client.RevokeToken(ctx, token, id)
+	if errors.Is(err, supabase.ErrNotFound) {
+		fmt.Println("❌ Token not found")
+		os.Exit(exitNotFound)
+	}
+	if err != nil {
+		fail("Failed to revoke token", err)
+	}
+	if emitValue(revoked, []string{"id", "name"}, []string{strconv.Itoa(revoked.ID), revoked.Name}) {
+		return
+	}
+	fmt.Printf("✅ Token revoked: %s\n", revoked.Name)
+	if id == info.TokenID {
+		if cache := tokenCache(); cache != nil {
+			cache.Clear()
+		}
+		fmt.Println("⚠️  That was this CLI's token; run todo login to get a new one")
+	}
+}
+
+// day shortens a timestamp to its date.
+func day(ts string) string {
+	if len(ts) >= 10 {
+		return ts[:10]
+	}
+	return ts
+}
+
+func expirySuffix(expiresAt string) string {
+	if expiresAt == "" {
+		return ""
+	}
+	return " (expires " + day(expiresAt) + ")"
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+
+	"todo-tracker/internal/notes"
+	"todo-tracker/internal/supabase"
+)
+
+// cmdNote edits a task's notes and links in $EDITOR, or appends text to
+// them when some is given.
+func cmdNote(ctx context.Context, args []string) {
+	if len(args) == 0 {
+		fmt.Println("❌ Missing task ID. Usage: todo note <id> [text or URL]")
+		os.Exit(exitError)
+	}
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		fmt.Println("❌ Invalid task ID")
+		os.Exit(exitError)
+	}
+
+	task, err := tasks.Get(ctx, id)
+	if err != nil {
+		fail("Cannot edit notes", err)
+	}
+
+	var text string
+	var links []string
+	if len(args) > 1 {
+		text, links = notes.Add(task.Notes, task.Links, strings.Join(args[1:], " "))
+	} else if text, links, err = editNotes(id, task); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(exitError)
+	}
+
+	fields := map[string]any{}
+	if text != task.Notes {
+		fields["notes"] = text
+		if text == "" {
+			fields["notes"] = nil
+		}
+	}
+	if !slices.Equal(links, task.Links) {
+		fields["links"] = links
+		if links == nil {
+			fields["links"] = []string{}
+		}
+	}
+	if len(fields) == 0 {
+		if emitTasks(*task) {
+			return
+		}
+		fmt.Println("✅ No changes")
+		return
+	}
+
+	updated, err := tasks.UpdateByID(ctx, id, fields)
+	if reportQueued(err) {
+		return
+	}
+	if err != nil {
+		fail("Failed to save notes", err)
+	}
+	if emitTasks(*updated) {
+		return
+	}
+	fmt.Printf("📝 Notes saved for #%d: %s%s\n", updated.ID, updated.Title, notesSummary(*updated))
+}
+
+// editNotes opens $EDITOR on a task's notes and links.
+func editNotes(id int, t *supabase.Task) (string, []string, error) {
+	f, err := os.CreateTemp("", fmt.Sprintf("todo-%d-*.md", id))
+	if err != nil {
+		return "", nil, err
+	}
+	defer os.Remove(f.Name())
+
+	if _, err := f.WriteString(notes.Render(t.Notes, t.Links)); err != nil {
+		f.Close()
+		return "", nil, err
+	}
+	f.Close()
+
+	if err := runEditor(f.Name()); err != nil {
+		return "", nil, err
+	}
+	data, err := os.ReadFile(f.Name())
+	if err != nil {
+		return "", nil, err
+	}
+	return notes.Parse(string(data))
+}
+
+// notesSummary counts a task's note lines and links, e.g. " (3 lines, 1 link)".
+func notesSummary(t supabase.Task) string {
+	var parts []string
+	if t.Notes != "" {
+		parts = append(parts, plural(strings.Count(t.Notes, "\n")+1, "line"))
+	}
+	if len(t.Links) > 0 {
+		parts = append(parts, plural(len(t.Links), "link"))
+	}
+	if len(parts) == 0 {
+		return " (empty)"
+	}
+	return " (" + strings.Join(parts, ", ") + ")"
+}
+
+func plural(n int, noun string) string {
+	if n == 1 {
+		return "1 " + noun
+	}
+	return fmt.Sprintf("%d %ss", n, noun)
+}
+
+// printNotes writes a task's notes and links under `todo show`.
+func printNotes(t supabase.Task) {
+	if t.Notes != "" {
+		fmt.Println("\n📝 Notes:")
+		for _, line := range strings.Split(t.Notes, "\n") {
+			fmt.Println(strings.TrimRight("   "+line, " "))
+		}
+	}
+	if len(t.Links) > 0 {
+		fmt.Println("\n🔗 Links:")
+		for _, l := range t.Links {
+			fmt.Println("   " + l)
+		}
+	}
+}
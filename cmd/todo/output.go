@@ -0,0 +1,229 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"todo-tracker/internal/recurrence"
+	"todo-tracker/internal/supabase"
+)
+
+// Output formats selected with --output or the output setting.
+const (
+	outputText  = "text" // the default: emoji and colour for people
+	outputTable = "table"
+	outputPlain = "plain"
+	outputJSON  = "json"
+	outputCSV   = "csv"
+	outputTSV   = "tsv"
+)
+
+var (
+	outputFormat = outputText
+
+	// stdout receives structured output. In every format except text,
+	// os.Stdout is pointed at stderr so that progress and error messages
+	// never end up in the data a script is reading.
+	stdout io.Writer = os.Stdout
+)
+
+// taskColumns is the field order of every structured task format.
+var taskColumns = []string{
+	"id", "title", "status", "priority", "due_date", "due_time",
+	"parent_id", "project", "tags", "recurrence", "created_at", "snooze_count",
+	"notes", "links",
+}
+
+// taskRecord is a task as written by --output json: every field is always
+// present, in taskColumns order, with null for unset optional values.
+type taskRecord struct {
+	ID         int      `json:"id"`
+	Title      string   `json:"title"`
+	Status     string   `json:"status"`
+	Priority   string   `json:"priority"`
+	DueDate    *string  `json:"due_date"`
+	DueTime    *string  `json:"due_time"`
+	ParentID   *int     `json:"parent_id"`
+	Project    *string  `json:"project"`
+	Tags       []string `json:"tags"`
+	Recurrence *string  `json:"recurrence"`
+	CreatedAt  *string  `json:"created_at"`
+	Snoozed    int      `json:"snooze_count"`
+	Notes      *string  `json:"notes"`
+	Links      []string `json:"links"`
+}
+
+// takeOutputFlag removes a global --output/-o flag from args, wherever it
+// appears, and selects the format, format when there is no flag.
+func takeOutputFlag(args []string, format string) ([]string, error) {
+	var rest []string
+	for i := 0; i < len(args); i++ {
+		a := args[i]
+		switch {
+		case a == "--output" || a == "-o":
+			if i+1 >= len(args) {
+				return nil, fmt.Errorf("%s needs a format (json, csv, tsv, table, plain)", a)
+			}
+			format = args[i+1]
+			i++
+		case strings.HasPrefix(a, "--output="):
+			format = strings.TrimPrefix(a, "--output=")
+		default:
+			rest = append(rest, a)
+		}
+	}
+	return rest, setOutput(format)
+}
+
+func setOutput(format string) error {
+	switch format {
+	case "", outputText:
+		outputFormat = outputText
+		return nil
+	case outputTable, outputPlain, outputJSON, outputCSV, outputTSV:
+		outputFormat = format
+	default:
+		return fmt.Errorf("unknown output format %q (expected json, csv, tsv, table, plain or text)", format)
+	}
+	stdout = os.Stdout
+	os.Stdout = os.Stderr
+	return nil
+}
+
+func structured() bool {
+	return outputFormat != outputText
+}
+
+// emitTasks writes tasks in the selected format. It returns false in text
+// mode, where the caller prints its usual message instead.
+func emitTasks(tasks ...supabase.Task) bool {
+	if !structured() {
+		return false
+	}
+	switch outputFormat {
+	case outputJSON:
+		records := make([]taskRecord, len(tasks))
+		for i, t := range tasks {
+			records[i] = newTaskRecord(t)
+		}
+		writeJSON(records)
+	case outputCSV, outputTSV:
+		rows := make([][]string, len(tasks))
+		for i, t := range tasks {
+			rows[i] = taskRow(t)
+		}
+		writeDelimited(taskColumns, rows)
+	case outputTable:
+		w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tPRI\tSTATUS\tDUE\tPARENT\tTITLE\tLABELS\tREPEAT")
+		for _, t := range tasks {
+			parent := ""
+			if t.ParentID != nil {
+				parent = strconv.Itoa(*t.ParentID)
+			}
+			repeat := ""
+			if t.Recurrence != "" {
+				repeat = recurrence.Describe(t.Recurrence)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n", t.ID, t.Priority, t.Status, t.Due(), parent, t.Title,
+				strings.TrimSpace(labelSuffix(t, false)), repeat)
+		}
+		w.Flush()
+	case outputPlain:
+		today := time.Now().Format("2006-01-02")
+		for _, t := range tasks {
+			fmt.Fprintln(stdout, formatTaskLine(t, today, false))
+		}
+	}
+	return true
+}
+
+// emitValue writes a non-task result: v as JSON, or a single row of values
+// under columns in the other formats. It returns false in text mode.
+func emitValue(v any, columns, values []string) bool {
+	switch outputFormat {
+	case outputText:
+		return false
+	case outputJSON:
+		writeJSON(v)
+	case outputCSV, outputTSV:
+		writeDelimited(columns, [][]string{values})
+	default:
+		w := tabwriter.NewWriter(stdout, 0, 0, 2, ' ', 0)
+		for i, c := range columns {
+			fmt.Fprintf(w, "%s:\t%s\n", c, values[i])
+		}
+		w.Flush()
+	}
+	return true
+}
+
+func newTaskRecord(t supabase.Task) taskRecord {
+	r := taskRecord{
+		ID:         t.ID,
+		Title:      t.Title,
+		Status:     t.Status,
+		Priority:   t.Priority,
+		DueDate:    optional(t.DueDate),
+		DueTime:    optional(t.Clock()),
+		ParentID:   t.ParentID,
+		Project:    optional(t.Project),
+		Tags:       t.Tags,
+		Recurrence: optional(t.Recurrence),
+		CreatedAt:  optional(t.CreatedAt),
+		Snoozed:    t.SnoozeCount,
+		Notes:      optional(t.Notes),
+		Links:      t.Links,
+	}
+	if r.Tags == nil {
+		r.Tags = []string{}
+	}
+	if r.Links == nil {
+		r.Links = []string{}
+	}
+	return r
+}
+
+func optional(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return &s
+}
+
+// taskRow renders t as CSV/TSV fields in taskColumns order. Tags and links
+// are separated by spaces.
+func taskRow(t supabase.Task) []string {
+	parent := ""
+	if t.ParentID != nil {
+		parent = strconv.Itoa(*t.ParentID)
+	}
+	return []string{
+		strconv.Itoa(t.ID), t.Title, t.Status, t.Priority, t.DueDate, t.Clock(),
+		parent, t.Project, strings.Join(t.Tags, " "), t.Recurrence, t.CreatedAt,
+		strconv.Itoa(t.SnoozeCount), t.Notes, strings.Join(t.Links, " "),
+	}
+}
+
+func writeJSON(v any) {
+	enc := json.NewEncoder(stdout)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	enc.Encode(v)
+}
+
+func writeDelimited(header []string, rows [][]string) {
+	w := csv.NewWriter(stdout)
+	if outputFormat == outputTSV {
+		w.Comma = '\t'
+	}
+	w.Write(header)
+	w.WriteAll(rows)
+}
@@ -0,0 +1,56 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"time"
+
+	"todo-tracker/internal/dateparse"
+)
+
+// cmdParseDate shows how cmdAdd would split text into a title and due date.
+func cmdParseDate(args []string) {
+	if len(args) == 0 {
+		fmt.Println("❌ Usage: todo parse-date <text>")
+		os.Exit(exitError)
+	}
+
+	text := strings.Join(args, " ")
+	due, rest, ok := dateparse.Extract(text, time.Now())
+	if emitParsedDate(text, due, rest, ok) {
+		return
+	}
+
+	fmt.Printf("Input:    %s\n", text)
+	if !ok {
+		fmt.Println("Due date: (none found — defaults to tomorrow)")
+		return
+	}
+	fmt.Printf("Due date: %s (%s)\n", due.DateString(), due.Date.Format("Mon"))
+	if due.Time != "" {
+		fmt.Printf("Time:     %s\n", due.Time)
+	}
+	fmt.Printf("Title:    %s\n", rest)
+}
+
+// emitParsedDate writes the parse result for --output formats.
+func emitParsedDate(text string, due dateparse.Result, title string, ok bool) bool {
+	v := struct {
+		Input   string  `json:"input"`
+		DueDate *string `json:"due_date"`
+		DueTime *string `json:"due_time"`
+		Title   string  `json:"title"`
+	}{Input: text, Title: text}
+	if ok {
+		v.DueDate, v.DueTime, v.Title = optional(due.DateString()), optional(due.Time), title
+	}
+	row := []string{v.Input, "", "", v.Title}
+	if v.DueDate != nil {
+		row[1] = *v.DueDate
+	}
+	if v.DueTime != nil {
+		row[2] = *v.DueTime
+	}
+	return emitValue(v, []string{"input", "due_date", "due_time", "title"}, row)
+}
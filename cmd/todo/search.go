@@ -0,0 +1,77 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"todo-tracker/internal/search"
+	"todo-tracker/internal/supabase"
+)
+
+// cmdSearch finds tasks of any status by the words in their titles and
+// notes.
+func cmdSearch(ctx context.Context, args []string) {
+	fs := flag.NewFlagSet("search", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	addFilterFlags(fs)
+	limit := fs.Int("limit", 20, "show at most this many tasks")
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(exitError)
+	}
+	text := strings.Join(positional, " ")
+	q := search.Parse(text)
+	if q.Empty() {
+		fmt.Println(`❌ Missing search words. Usage: todo search <words> [flags], e.g. todo search "invoice -draft"`)
+		os.Exit(exitError)
+	}
+
+	// Completed tasks are included unless --status says otherwise.
+	filter := supabase.Filter{UserID: userID, Limit: *limit}
+	if _, err := applyFilterFlags(fs, &filter, time.Now()); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(exitError)
+	}
+
+	found, err := tasks.Search(ctx, text, filter)
+	if err != nil {
+		fail("Search failed", err)
+	}
+	if emitTasks(found...) {
+		return
+	}
+	if len(found) == 0 {
+		fmt.Printf("🔍 No tasks match %q\n", text)
+		return
+	}
+
+	fmt.Printf("🔍 %d match(es) for %q, best first:\n\n", len(found), text)
+	today := time.Now().Format("2006-01-02")
+	for _, t := range found {
+		t.Title = q.Highlight(t.Title, highlightOn, highlightOff)
+		fmt.Println(formatTaskLine(t, today, true))
+		if line := matchingLine(q, t.Notes); line != "" {
+			fmt.Println("      📝 " + line)
+		}
+	}
+}
+
+const highlightOn, highlightOff = "\033[1;4m", "\033[0m"
+
+// matchingLine returns the first line of notes with a matched word,
+// highlighted, or "".
+func matchingLine(q search.Query, notes string) string {
+	for _, line := range strings.Split(notes, "\n") {
+		line = strings.TrimSpace(line)
+		if h := q.Highlight(line, highlightOn, highlightOff); h != line {
+			return h
+		}
+	}
+	return ""
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"time"
+
+	"todo-tracker/internal/completion"
+	"todo-tracker/internal/supabase"
+	"todo-tracker/internal/tree"
+)
+
+func cmdShow(ctx context.Context, args []string) {
+	if len(args) != 1 {
+		fmt.Println("❌ Usage: todo show <id>")
+		os.Exit(1)
+	}
+	id, err := strconv.Atoi(args[0])
+	if err != nil {
+		fmt.Println("❌ Invalid task ID")
+		os.Exit(1)
+	}
+
+	task, err := tasks.Get(ctx, id)
+	if err != nil {
+		fail("Cannot show task", err)
+	}
+	ancestors, err := ancestorsOf(ctx, *task)
+	if err != nil {
+		fail("Failed to fetch parent tasks", err)
+	}
+	descendants, err := completion.Descendants(ctx, tasks, userID, task.ID)
+	if err != nil {
+		fail("Failed to fetch subtasks", err)
+	}
+
+	// Ancestors root first, then the task, then its subtree: tree.Build
+	// turns that into a single path down to the task.
+	all := append(append(ancestors, *task), descendants...)
+	if emitTasks(all...) {
+		return
+	}
+
+	today := time.Now().Format("2006-01-02")
+	progress := subtaskProgress(ctx, all)
+	tree.Walk(tree.Build(all), func(n *tree.Node, prefix string) {
+		line := formatTaskLine(n.Task, today, true) + progressSuffix(progress[n.Task.ID])
+		if n.Task.ID == task.ID {
+			line = "👉 " + line
+		}
+		fmt.Println(prefix + line)
+	})
+	printNotes(*task)
+}
+
+// ancestorsOf returns t's parent, grandparent and so on, root first.
+func ancestorsOf(ctx context.Context, t supabase.Task) ([]supabase.Task, error) {
+	var chain []supabase.Task
+	seen := map[int]bool{t.ID: true}
+	for t.ParentID != nil && !seen[*t.ParentID] {
+		parent, err := tasks.Get(ctx, *t.ParentID)
+		if err != nil {
+			return nil, err
+		}
+		seen[parent.ID] = true
+		chain = append(chain, *parent)
+		t = *parent
+	}
+	slices.Reverse(chain)
+	return chain, nil
+}
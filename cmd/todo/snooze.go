@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"todo-tracker/internal/dateparse"
+	"todo-tracker/internal/snooze"
+	"todo-tracker/internal/storage"
+	"todo-tracker/internal/supabase"
+)
+
+func cmdSnooze(ctx context.Context, args []string) {
+	fs := newBulkFlags("snooze")
+	skipWeekends := fs.Bool("skip-weekends", false, "count working days and never land on a weekend")
+	sel, when := parseSelection(fs, args, "todo snooze <id>... [duration|date]", true)
+
+	now := time.Now()
+	spec, err := snooze.Parse(when, now)
+	if err != nil {
+		fmt.Printf("❌ %v (try 3d, +2 weeks, monday, next week or 2026-03-01)\n", err)
+		os.Exit(exitError)
+	}
+
+	targets, err := sel.resolve(ctx)
+	if sel.offline(err) && !spec.Relative() {
+		// Offline the tasks cannot be read, so queue the new date
+		// without touching the snooze counts.
+		fields := map[string]any{"due_date": spec.Due("", now, *skipWeekends).Format(dateparse.Layout)}
+		if spec.Time() != "" {
+			fields["due_time"] = spec.Time()
+		}
+		_, err = tasks.Update(ctx, supabase.Filter{IDs: sel.ids}, fields)
+		if reportQueued(err) {
+			return
+		}
+	}
+	if err != nil {
+		fail("Cannot snooze tasks", err)
+	}
+	if len(targets) == 0 {
+		fmt.Println("🔍 No matching tasks")
+		return
+	}
+	sel.confirm(ctx, "snooze", targets)
+
+	snoozed, err := snooze.Apply(ctx, tasks, targets, spec, now, *skipWeekends)
+	if err != nil && !errors.Is(err, storage.ErrQueued) {
+		fail("Failed to snooze tasks", err)
+	}
+
+	if emitTasks(snoozed...) {
+		return
+	}
+	for _, t := range snoozed {
+		fmt.Printf("😴 Snoozed: %s — now due %s (snoozed %s)\n", t.Title, t.Due(), times(t.SnoozeCount))
+	}
+	reportQueued(err)
+}
+
+// times renders a count such as "once" or "3 times".
+func times(n int) string {
+	switch n {
+	case 1:
+		return "once"
+	case 2:
+		return "twice"
+	}
+	return fmt.Sprintf("%d times", n)
+}
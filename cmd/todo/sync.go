@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"todo-tracker/internal/storage"
+	"todo-tracker/internal/supabase"
+)
+
+func cmdSync(ctx context.Context, args []string) {
+	resolve := storage.StopOnConflict
+	for _, a := range args {
+		switch a {
+		case "--force":
+			resolve = storage.ForceConflicts
+		case "--skip":
+			resolve = storage.SkipConflicts
+		default:
+			fmt.Printf("❌ Unknown option: %s. Usage: todo sync [--force|--skip]\n", a)
+			os.Exit(1)
+		}
+	}
+
+	if offline == nil {
+		if !emitSyncReport(storage.SyncReport{}) {
+			if settings.Get("backend") == "local" {
+				fmt.Println("✅ Nothing to sync (local backend)")
+			} else {
+				fmt.Println("✅ Nothing to sync (no state directory for an offline queue)")
+			}
+		}
+		return
+	}
+
+	pending, err := offline.Pending()
+	if err != nil {
+		fail("Failed to read offline queue", err)
+	}
+	if len(pending) == 0 {
+		if !emitSyncReport(storage.SyncReport{}) {
+			fmt.Println("✅ Nothing to sync")
+		}
+		return
+	}
+
+	report, err := offline.Flush(ctx, resolve)
+	if err != nil {
+		fail("Sync failed", err)
+	}
+	if !emitSyncReport(report) {
+		printSyncReport(report)
+	}
+	switch {
+	case len(report.Conflicts) > 0 && report.Pending > 0:
+		os.Exit(exitError)
+	case report.Pending > 0:
+		os.Exit(exitCode(report.Stopped))
+	}
+}
+
+// emitSyncReport writes the counts of a sync for --output formats.
+func emitSyncReport(r storage.SyncReport) bool {
+	v := struct {
+		Applied   int `json:"applied"`
+		Skipped   int `json:"skipped"`
+		Conflicts int `json:"conflicts"`
+		Failed    int `json:"failed"`
+		Pending   int `json:"pending"`
+	}{r.Applied, r.Skipped, len(r.Conflicts), len(r.Failed), r.Pending}
+	return emitValue(v, []string{"applied", "skipped", "conflicts", "failed", "pending"}, []string{
+		strconv.Itoa(v.Applied), strconv.Itoa(v.Skipped), strconv.Itoa(v.Conflicts),
+		strconv.Itoa(v.Failed), strconv.Itoa(v.Pending),
+	})
+}
+
+// printSyncReport describes the outcome of replaying the offline queue.
+func printSyncReport(r storage.SyncReport) {
+	if r.Applied > 0 {
+		fmt.Printf("🔄 Synced %d queued change(s)\n", r.Applied)
+	}
+	for _, c := range r.Conflicts {
+		if c.Field == "" {
+			fmt.Printf("⚠️ Conflict: %s — task #%d no longer exists\n", c.Op, c.TaskID)
+			continue
+		}
+		fmt.Printf("⚠️ Conflict: %s — #%d %s changed on server (%v → %v)\n",
+			c.Op, c.TaskID, c.Field, c.Seen, c.Server)
+	}
+	if r.Skipped > 0 {
+		fmt.Printf("🗑  Discarded %d conflicting change(s)\n", r.Skipped)
+	} else if len(r.Conflicts) > 0 {
+		fmt.Println("   Queue is paused. Run 'todo sync --force' to overwrite or 'todo sync --skip' to discard.")
+	}
+	for _, f := range r.Failed {
+		fmt.Printf("❌ Rejected: %s — %v\n", f.Op, f.Err)
+	}
+	switch {
+	case r.Pending == 0 || len(r.Conflicts) > 0:
+	case r.Stopped == nil || supabase.IsNetworkError(r.Stopped):
+		fmt.Printf("📥 Still offline, %d change(s) left in queue\n", r.Pending)
+	default:
+		fmt.Printf("📥 Sync stopped (%s), %d change(s) left in queue\n", describeError(r.Stopped), r.Pending)
+	}
+}
+
+// reportQueued prints a notice and returns true when err means the change
+// was saved to the offline queue instead of reaching the server.
+func reportQueued(err error) bool {
+	if !errors.Is(err, storage.ErrQueued) {
+		return false
+	}
+	fmt.Println("📥 Offline — change queued. Run 'todo sync' when back online.")
+	return true
+}
+
+// readState returns a small value persisted in the CLI's state directory.
+func readState(name string) string {
+	if stateDir == "" {
+		return ""
+	}
+	data, err := os.ReadFile(filepath.Join(stateDir, name))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// writeState persists a small value in the CLI's state directory.
+func writeState(name, value string) {
+	if stateDir == "" {
+		return
+	}
+	if err := os.MkdirAll(stateDir, 0700); err == nil {
+		os.WriteFile(filepath.Join(stateDir, name), []byte(value+"\n"), 0600)
+	}
+}
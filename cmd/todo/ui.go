@@ -0,0 +1,698 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/term"
+
+	"todo-tracker/internal/agenda"
+	"todo-tracker/internal/completion"
+	"todo-tracker/internal/labels"
+	"todo-tracker/internal/query"
+	"todo-tracker/internal/snooze"
+	"todo-tracker/internal/storage"
+	"todo-tracker/internal/supabase"
+	"todo-tracker/internal/tree"
+)
+
+const (
+	// uiRefresh is how often `todo ui` reloads the tasks on its own.
+	uiRefresh = 30 * time.Second
+	// uiResize is how often the terminal size is checked.
+	uiResize = 250 * time.Millisecond
+)
+
+var (
+	uiTitle    = lipgloss.NewStyle().Bold(true)
+	uiSelected = lipgloss.NewStyle().Reverse(true)
+	uiFaint    = lipgloss.NewStyle().Faint(true)
+	uiLabels   = lipgloss.NewStyle().Foreground(lipgloss.Color("6"))
+	uiError    = lipgloss.NewStyle().Foreground(lipgloss.Color("1"))
+	uiSections = map[string]lipgloss.Style{
+		agenda.Overdue:  lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("1")),
+		agenda.Today:    lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("3")),
+		agenda.Upcoming: lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("6")),
+	}
+	uiPriorities = map[string]lipgloss.Style{
+		"P0": lipgloss.NewStyle().Bold(true).Foreground(lipgloss.Color("1")),
+		"P1": lipgloss.NewStyle().Foreground(lipgloss.Color("3")),
+	}
+)
+
+const uiKeys = `  ↑/k ↓/j  move            g/G  first/last task
+  x, space  mark done       D    done with all subtasks
+  s         snooze          e    edit title
+  a         add subtask     0-4  set priority, +/- raise/lower
+  /         filter          esc  clear filter
+  u         undo            r    refresh (also every 30s)
+  ?         close help      q    quit`
+
+// cmdUI runs the full-screen task list.
+func cmdUI(ctx context.Context, args []string) {
+	if structured() {
+		fmt.Println("❌ todo ui is interactive and has no --output formats")
+		os.Exit(exitError)
+	}
+	if !term.IsTerminal(os.Stdin.Fd()) || !term.IsTerminal(os.Stdout.Fd()) {
+		fmt.Println("❌ todo ui needs a terminal")
+		os.Exit(exitError)
+	}
+	// Sync reports would be printed over the screen.
+	if offline != nil {
+		offline.OnFlush = nil
+	}
+
+	m := &uiModel{ctx: ctx}
+	if err := m.setFilter(strings.Join(args, " ")); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(exitError)
+	}
+	if err := m.run(); err != nil {
+		fmt.Printf("❌ %v\n", err)
+		os.Exit(exitError)
+	}
+}
+
+// run takes over the terminal and handles keys and finished commands until
+// the user quits. Commands run in the background and report back with a
+// message.
+func (m *uiModel) run() error {
+	in, out := os.Stdin, os.Stdout
+	state, err := term.MakeRaw(in.Fd())
+	if err != nil {
+		return err
+	}
+	defer term.Restore(in.Fd(), state)
+	fmt.Fprint(out, "\x1b[?1049h\x1b[?25l") // alternate screen, hide cursor
+	defer fmt.Fprint(out, "\x1b[?25h\x1b[?1049l")
+
+	msgs := make(chan any)
+	start := func(cmd uiCmd) {
+		if cmd != nil {
+			go func() { msgs <- cmd() }()
+		}
+	}
+	go uiReadKeys(in, msgs)
+	refresh := time.NewTicker(uiRefresh)
+	defer refresh.Stop()
+	resize := time.NewTicker(uiResize)
+	defer resize.Stop()
+
+	start(m.load())
+	shown := ""
+	for !m.quit {
+		if w, h, err := term.GetSize(out.Fd()); err == nil && (w != m.width || h != m.height) {
+			m.width, m.height = w, h
+			m.scroll()
+		}
+		// Redraw in place, clearing what is left of each line.
+		if frame := m.View(); frame != shown {
+			fmt.Fprint(out, "\x1b[H"+strings.ReplaceAll(frame, "\n", "\x1b[K\r\n")+"\x1b[K\x1b[J")
+			shown = frame
+		}
+		select {
+		case <-m.ctx.Done():
+			return nil
+		case <-refresh.C:
+			start(m.update(uiTick{}))
+		case <-resize.C:
+		case msg := <-msgs:
+			start(m.update(msg))
+		}
+	}
+	return nil
+}
+
+// uiReadKeys sends the keys typed on in as uiKey messages.
+func uiReadKeys(in *os.File, msgs chan<- any) {
+	buf := make([]byte, 256)
+	for {
+		n, err := in.Read(buf)
+		for _, k := range uiParseKeys(buf[:n]) {
+			msgs <- k
+		}
+		if err != nil {
+			return
+		}
+	}
+}
+
+// uiSequences names the keys terminals send as ESC [ or ESC O sequences.
+var uiSequences = map[string]uiKey{
+	"A": "up", "B": "down", "C": "right", "D": "left",
+	"H": "home", "1~": "home", "7~": "home",
+	"F": "end", "4~": "end", "8~": "end",
+	"3~": "delete", "5~": "pgup", "6~": "pgdown",
+}
+
+var uiControls = map[byte]uiKey{
+	0x01: "ctrl+a", 0x03: "ctrl+c", 0x05: "ctrl+e", 0x15: "ctrl+u",
+	'\r': "enter", '\n': "enter", 0x08: "backspace", 0x7f: "backspace",
+}
+
+// uiParseKeys splits what one read returned into keys. Printable keys are
+// their character; unknown sequences and control characters are dropped.
+func uiParseKeys(b []byte) []uiKey {
+	var keys []uiKey
+	for len(b) > 0 {
+		switch {
+		case b[0] == 0x1b && len(b) > 2 && (b[1] == '[' || b[1] == 'O'):
+			end := 2
+			for end < len(b) && (b[end] < 0x40 || b[end] > 0x7e) {
+				end++
+			}
+			if end == len(b) {
+				return keys
+			}
+			if k, ok := uiSequences[string(b[2:end+1])]; ok {
+				keys = append(keys, k)
+			}
+			b = b[end+1:]
+		case b[0] == 0x1b:
+			keys = append(keys, "esc")
+			b = b[1:]
+		case b[0] < 0x20 || b[0] == 0x7f:
+			if k, ok := uiControls[b[0]]; ok {
+				keys = append(keys, k)
+			}
+			b = b[1:]
+		default:
+			r, size := utf8.DecodeRune(b)
+			keys = append(keys, uiKey(r))
+			b = b[size:]
+		}
+	}
+	return keys
+}
+
+// uiModel is the state of `todo ui`.
+type uiModel struct {
+	ctx    context.Context
+	query  string // the filter as typed
+	filter supabase.Filter
+	tasks  []supabase.Task
+	rows   []uiRow
+	sel    int // index of the selected task among the task rows
+	top    int // first row on screen
+	width  int
+	height int
+
+	loaded bool
+	busy   bool // a change is in flight; changes are made one at a time
+	status string
+	failed bool // status is an error
+	prompt *uiPrompt
+	help   bool
+	quit   bool
+}
+
+// uiRow is a section header, a task, or a blank separator.
+type uiRow struct {
+	header  string
+	section string
+	task    *supabase.Task
+	prefix  string // tree lines
+	n       int    // index among the task rows
+}
+
+// uiPrompt is a one-line input shown in the footer.
+type uiPrompt struct {
+	label  string
+	value  []rune
+	pos    int
+	submit func(value string) uiCmd
+}
+
+// uiCmd is work run in the background; its result is handled by update.
+type uiCmd func() any
+
+// uiKey is a key press: the character typed, or a name like "up",
+// "enter" or "ctrl+c".
+type uiKey string
+
+type (
+	uiLoaded struct {
+		tasks []supabase.Task
+		err   error
+	}
+	// uiDone reports a finished change.
+	uiDone struct {
+		status string
+		failed bool
+	}
+	uiTick struct{}
+)
+
+// setFilter narrows the list with text as `todo list` reads its arguments:
+// #tag and +project words and query conditions.
+func (m *uiModel) setFilter(text string) error {
+	f := supabase.Filter{UserID: userID, Status: "Todo", Order: []string{"priority", "due_date"}}
+	tags, project, expr := labels.Extract(text)
+	for _, t := range tags {
+		query.Apply(&f, "tag", "=", t, time.Now())
+	}
+	if project != "" {
+		f.Project = project
+	}
+	if err := query.Parse(&f, expr, time.Now()); err != nil {
+		return err
+	}
+	m.query, m.filter = strings.TrimSpace(text), f
+	return nil
+}
+
+func (m *uiModel) load() uiCmd {
+	ctx, f := m.ctx, m.filter
+	return func() any {
+		found, err := tasks.List(ctx, f)
+		return uiLoaded{found, err}
+	}
+}
+
+func (m *uiModel) update(msg any) uiCmd {
+	switch msg := msg.(type) {
+	case uiLoaded:
+		if msg.err != nil {
+			m.setStatus("Failed to fetch tasks: "+describeError(msg.err), true)
+			return nil
+		}
+		m.loaded = true
+		m.tasks = msg.tasks
+		m.rebuild()
+	case uiDone:
+		m.busy = false
+		m.setStatus(msg.status, msg.failed)
+		return m.load()
+	case uiTick:
+		return m.load()
+	case uiKey:
+		if m.prompt != nil {
+			return m.editPrompt(msg)
+		}
+		return m.key(msg)
+	}
+	return nil
+}
+
+func (m *uiModel) setStatus(s string, failed bool) {
+	m.status, m.failed = s, failed
+}
+
+// key handles a key press outside of a prompt.
+func (m *uiModel) key(k uiKey) uiCmd {
+	switch k {
+	case "ctrl+c", "q":
+		m.quit = true
+	case "up", "k":
+		m.move(-1)
+	case "down", "j":
+		m.move(1)
+	case "pgup":
+		m.move(-m.page())
+	case "pgdown":
+		m.move(m.page())
+	case "home", "g":
+		m.move(-len(m.rows))
+	case "end", "G":
+		m.move(len(m.rows))
+	case "?":
+		m.help = !m.help
+	case "r":
+		m.setStatus("Refreshing…", false)
+		return m.load()
+	case "/":
+		m.ask("Filter (#tag +project priority<=P1 due<+7d title~text): ", m.query, func(v string) uiCmd {
+			if err := m.setFilter(v); err != nil {
+				m.setStatus(err.Error(), true)
+				return nil
+			}
+			m.sel = 0
+			return m.load()
+		})
+	case "esc":
+		m.help = false
+		if m.query != "" {
+			m.setFilter("")
+			return m.load()
+		}
+	case "u":
+		return m.change(func() (string, error) {
+			undone, _, err := journal.Undo(m.ctx, 1, false)
+			switch {
+			case err != nil:
+				return "Undo failed", err
+			case len(undone) == 0:
+				return "Nothing to undo", nil
+			}
+			return "↩️  Undid: " + undone[0].Command, nil
+		})
+	}
+
+	t := m.selected()
+	if t == nil {
+		return nil
+	}
+	switch k {
+	case "x", " ":
+		return m.complete(*t, false)
+	case "D":
+		return m.complete(*t, true)
+	case "s":
+		m.ask(fmt.Sprintf("Snooze #%d to (3d, monday, next week; empty for tomorrow): ", t.ID), "", func(v string) uiCmd {
+			now := time.Now()
+			spec, err := snooze.Parse(v, now)
+			if err != nil {
+				m.setStatus(err.Error(), true)
+				return nil
+			}
+			return m.change(func() (string, error) {
+				journal.Begin(strings.TrimSpace(fmt.Sprintf("ui snooze %d %s", t.ID, v)))
+				done, err := snooze.Apply(m.ctx, tasks, []supabase.Task{*t}, spec, now, false)
+				if err != nil {
+					return "Failed to snooze", err
+				}
+				if len(done) == 0 {
+					return "Failed to snooze", supabase.ErrNotFound
+				}
+				return fmt.Sprintf("😴 Snoozed: %s — now due %s", done[0].Title, done[0].Due()), nil
+			})
+		})
+	case "e":
+		m.ask(fmt.Sprintf("Title of #%d: ", t.ID), t.Title, func(v string) uiCmd {
+			v = strings.TrimSpace(v)
+			if v == "" || v == t.Title {
+				return nil
+			}
+			return m.change(func() (string, error) {
+				journal.Begin(fmt.Sprintf("ui edit %d", t.ID))
+				if _, err := tasks.UpdateByID(m.ctx, t.ID, map[string]any{"title": v}); err != nil {
+					return "Failed to edit task", err
+				}
+				return fmt.Sprintf("✅ Renamed #%d: %s", t.ID, v), nil
+			})
+		})
+	case "a":
+		m.ask(fmt.Sprintf("New subtask of #%d: ", t.ID), "", func(v string) uiCmd {
+			if strings.TrimSpace(v) == "" {
+				return nil
+			}
+			return m.change(func() (string, error) {
+				journal.Begin(fmt.Sprintf("ui subtask %d", t.ID))
+				created, err := tasks.Create(m.ctx, newSubtask(*t, v))
+				if err != nil {
+					return "Failed to add subtask", err
+				}
+				return fmt.Sprintf("✅ Subtask added: %s (under #%d)", created.Title, t.ID), nil
+			})
+		})
+	case "0", "1", "2", "3", "4":
+		return m.setPriority(*t, "P"+string(k))
+	case "+", "=":
+		if i := slices.Index(query.Priorities, t.Priority); i > 0 {
+			return m.setPriority(*t, query.Priorities[i-1])
+		}
+	case "-":
+		if i := slices.Index(query.Priorities, t.Priority); i >= 0 && i < len(query.Priorities)-1 {
+			return m.setPriority(*t, query.Priorities[i+1])
+		}
+	}
+	return nil
+}
+
+// change runs fn in the background unless another change is running, and
+// reports its outcome with a uiDone. fn returns the status to show, which
+// on failure is followed by a description of the error.
+func (m *uiModel) change(fn func() (string, error)) uiCmd {
+	if m.busy {
+		m.setStatus("Still saving the last change…", true)
+		return nil
+	}
+	m.busy = true
+	return func() any {
+		status, err := fn()
+		var open *completion.OpenSubtasksError
+		switch {
+		case errors.Is(err, storage.ErrQueued):
+			return uiDone{status: "📥 Offline — change queued. Run 'todo sync' when back online."}
+		case errors.As(err, &open):
+			return uiDone{status: status, failed: true} // status explains it
+		case err != nil:
+			return uiDone{status: status + ": " + describeError(err), failed: true}
+		}
+		return uiDone{status: status}
+	}
+}
+
+func (m *uiModel) complete(t supabase.Task, cascade bool) uiCmd {
+	return m.change(func() (string, error) {
+		command := fmt.Sprintf("ui done %d", t.ID)
+		if cascade {
+			command += " --cascade"
+		}
+		journal.Begin(command)
+		opt := completion.Options{Cascade: cascade, AutoParent: true}
+		res, err := completion.CompleteAll(m.ctx, tasks, []supabase.Task{t}, opt, time.Now())
+		var open *completion.OpenSubtasksError
+		if errors.As(err, &open) {
+			return fmt.Sprintf("#%d has %d open subtask(s); press D to complete them too", t.ID, len(open.Open)), err
+		}
+		if len(res.Tasks) == 0 {
+			return "Failed to complete task", err
+		}
+		status := "✅ Done: " + t.Title
+		if n := len(res.Subtasks); n > 0 {
+			status += fmt.Sprintf(", with %d subtask(s)", n)
+		}
+		for _, p := range res.Parents {
+			status += fmt.Sprintf("; all subtasks done, so #%d is done too", p.ID)
+		}
+		for _, next := range res.Scheduled {
+			status += fmt.Sprintf("; 🔁 next one due %s", next.Due())
+		}
+		return status, err
+	})
+}
+
+func (m *uiModel) setPriority(t supabase.Task, p string) uiCmd {
+	if p == t.Priority {
+		return nil
+	}
+	return m.change(func() (string, error) {
+		journal.Begin(fmt.Sprintf("ui priority %d %s", t.ID, p))
+		if _, err := tasks.UpdateByID(m.ctx, t.ID, map[string]any{"priority": p}); err != nil {
+			return "Failed to change priority", err
+		}
+		return fmt.Sprintf("✅ #%d is now %s", t.ID, p), nil
+	})
+}
+
+// ask opens a prompt with an initial value.
+func (m *uiModel) ask(label, value string, submit func(string) uiCmd) {
+	v := []rune(value)
+	m.prompt = &uiPrompt{label: label, value: v, pos: len(v), submit: submit}
+}
+
+func (m *uiModel) editPrompt(k uiKey) uiCmd {
+	p := m.prompt
+	switch k {
+	case "enter":
+		m.prompt = nil
+		return p.submit(string(p.value))
+	case "esc", "ctrl+c":
+		m.prompt = nil
+	case "left":
+		p.pos = max(p.pos-1, 0)
+	case "right":
+		p.pos = min(p.pos+1, len(p.value))
+	case "home", "ctrl+a":
+		p.pos = 0
+	case "end", "ctrl+e":
+		p.pos = len(p.value)
+	case "ctrl+u":
+		p.value, p.pos = p.value[p.pos:], 0
+	case "backspace":
+		if p.pos > 0 {
+			p.value = slices.Delete(p.value, p.pos-1, p.pos)
+			p.pos--
+		}
+	case "delete":
+		if p.pos < len(p.value) {
+			p.value = slices.Delete(p.value, p.pos, p.pos+1)
+		}
+	default:
+		if r := []rune(k); len(r) == 1 {
+			p.value = slices.Insert(p.value, p.pos, r[0])
+			p.pos++
+		}
+	}
+	return nil
+}
+
+// rebuild lays the tasks out in sections, keeping the selected task
+// selected when it is still listed.
+func (m *uiModel) rebuild() {
+	selID := 0
+	if t := m.selected(); t != nil {
+		selID = t.ID
+	}
+	m.rows = nil
+	n := 0
+	for _, s := range agenda.Group(m.tasks, time.Now().Format("2006-01-02")) {
+		if len(s.Tasks) == 0 {
+			continue
+		}
+		if len(m.rows) > 0 {
+			m.rows = append(m.rows, uiRow{n: -1})
+		}
+		m.rows = append(m.rows, uiRow{header: fmt.Sprintf("%s (%d)", s.Name, len(s.Tasks)), section: s.Name, n: -1})
+		tree.Walk(tree.Build(s.Tasks), func(node *tree.Node, prefix string) {
+			t := node.Task
+			if t.ID == selID {
+				m.sel = n
+			}
+			m.rows = append(m.rows, uiRow{task: &t, section: s.Name, prefix: prefix, n: n})
+			n++
+		})
+	}
+	m.move(0)
+}
+
+func (m *uiModel) count() int {
+	n := 0
+	for _, r := range m.rows {
+		if r.task != nil {
+			n++
+		}
+	}
+	return n
+}
+
+func (m *uiModel) selected() *supabase.Task {
+	for _, r := range m.rows {
+		if r.task != nil && r.n == m.sel {
+			return r.task
+		}
+	}
+	return nil
+}
+
+func (m *uiModel) move(d int) {
+	m.sel = max(min(m.sel+d, m.count()-1), 0)
+	m.scroll()
+}
+
+// page is the number of rows that fit between the title and the footer.
+func (m *uiModel) page() int {
+	return max(m.height-4, 1)
+}
+
+// scroll keeps the selected row, and its section header when that is just
+// above it, on screen.
+func (m *uiModel) scroll() {
+	row := 0
+	for i, r := range m.rows {
+		if r.task != nil && r.n == m.sel {
+			row = i
+		}
+	}
+	first := row
+	if first > 0 && m.rows[first-1].header != "" {
+		first--
+	}
+	if first < m.top {
+		m.top = first
+	}
+	if row >= m.top+m.page() {
+		m.top = row - m.page() + 1
+	}
+	m.top = max(min(m.top, len(m.rows)-m.page()), 0)
+}
+
+func (m *uiModel) View() string {
+	if m.width == 0 {
+		return ""
+	}
+	fit := lipgloss.NewStyle().MaxWidth(m.width)
+
+	title := fmt.Sprintf("todo ui — %d open task(s)", m.count())
+	if m.query != "" {
+		title += " matching " + m.query
+	}
+	lines := []string{uiTitle.Render(title), ""}
+
+	switch {
+	case m.help:
+		lines = append(lines, strings.Split(uiKeys, "\n")...)
+	case !m.loaded:
+		lines = append(lines, "Loading…")
+	case len(m.rows) == 0 && m.query != "":
+		lines = append(lines, "🔍 No matching tasks — esc clears the filter")
+	case len(m.rows) == 0:
+		lines = append(lines, "🎉 No pending tasks!")
+	default:
+		for _, r := range m.rows[m.top:min(m.top+m.page(), len(m.rows))] {
+			lines = append(lines, m.renderRow(r))
+		}
+	}
+	for len(lines) < m.height-2 {
+		lines = append(lines, "")
+	}
+
+	status := uiFaint.Render(m.status)
+	if m.failed {
+		status = uiError.Render("❌ " + m.status)
+	}
+	footer := uiFaint.Render("x done  s snooze  e edit  a subtask  0-4 priority  / filter  u undo  r refresh  ? help  q quit")
+	if m.prompt != nil {
+		p := m.prompt
+		footer = p.label + string(p.value[:p.pos]) + uiSelected.Render(" ") + string(p.value[p.pos:])
+		if p.pos < len(p.value) {
+			footer = p.label + string(p.value[:p.pos]) + uiSelected.Render(string(p.value[p.pos])) + string(p.value[p.pos+1:])
+		}
+	}
+	lines = append(lines, status, footer)
+
+	for i, l := range lines {
+		lines[i] = fit.Render(l)
+	}
+	return strings.Join(lines, "\n")
+}
+
+func (m *uiModel) renderRow(r uiRow) string {
+	switch {
+	case r.header != "":
+		return uiSections[r.section].Render(r.header)
+	case r.task == nil:
+		return ""
+	}
+	t := *r.task
+	due := t.Due()
+	if r.section == agenda.Today {
+		due = "today"
+		if t.DueTime != "" {
+			due += " " + t.Clock()
+		}
+	}
+	labelText := labels.Format(t.Tags, t.Project)
+
+	if r.n == m.sel {
+		line := fmt.Sprintf("%s%-4d %s %s", r.prefix, t.ID, t.Priority, t.Title)
+		if labelText != "" {
+			line += " " + labelText
+		}
+		return uiSelected.Render(line + "  " + due + repeatSuffix(t))
+	}
+	line := fmt.Sprintf("%s%-4d %s %s", uiFaint.Render(r.prefix), t.ID, uiPriorities[t.Priority].Render(t.Priority), t.Title)
+	if labelText != "" {
+		line += " " + uiLabels.Render(labelText)
+	}
+	return line + "  " + uiFaint.Render(due) + repeatSuffix(t)
+}
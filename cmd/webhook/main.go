@@ -2,16 +2,29 @@ package main
 
 import (
 	"bytes"
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"net/http"
 	"os"
 	"regexp"
+	"slices"
 	"strconv"
 	"strings"
 	"time"
+
+	"todo-tracker/internal/completion"
+	"todo-tracker/internal/dateparse"
+	"todo-tracker/internal/labels"
+	"todo-tracker/internal/notes"
+	"todo-tracker/internal/query"
+	"todo-tracker/internal/recurrence"
+	"todo-tracker/internal/search"
+	"todo-tracker/internal/snooze"
+	"todo-tracker/internal/supabase"
 )
 
 // Telegram types
@@ -28,28 +41,27 @@ type Chat struct {
 	ID int64 `json:"id"`
 }
 
-// Supabase task
-type Task struct {
-	ID        int     `json:"id,omitempty"`
-	Title     string  `json:"title"`
-	DueDate   string  `json:"due_date,omitempty"`
-	Priority  string  `json:"priority,omitempty"`
-	Status    string  `json:"status,omitempty"`
-	ParentID  *int    `json:"parent_id,omitempty"`
-	UserID    string  `json:"user_id"`
-	CreatedAt string  `json:"created_at,omitempty"`
-}
+// updateTimeout bounds handling of one Telegram update, including retries.
+const updateTimeout = 20 * time.Second
 
 var (
-	supabaseURL string
-	supabaseKey string
-	botToken    string
+	supabaseURL    string
+	supabaseKey    string
+	botToken       string
+	store          *supabase.TaskStore
+	telegramClient = &http.Client{Timeout: 10 * time.Second}
 )
 
 func init() {
 	supabaseURL = os.Getenv("SUPABASE_URL")
 	supabaseKey = os.Getenv("SUPABASE_SERVICE_ROLE_KEY")
 	botToken = os.Getenv("TELEGRAM_BOT_TOKEN")
+	store = supabase.NewTaskStore(supabase.NewClient(supabaseURL, supabaseKey))
+}
+
+// userTasks returns the task store scoped to a Telegram chat.
+func userTasks(chatID int64) *supabase.TaskStore {
+	return store.ForUser(strconv.FormatInt(chatID, 10))
 }
 
 func main() {
@@ -63,8 +75,16 @@ func main() {
 		w.Write([]byte("OK"))
 	})
 
+	srv := &http.Server{
+		Addr:              ":" + port,
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       10 * time.Second,
+		WriteTimeout:      updateTimeout + 5*time.Second,
+		IdleTimeout:       60 * time.Second,
+	}
+
 	log.Printf("Starting webhook server on port %s", port)
-	log.Fatal(http.ListenAndServe(":"+port, nil))
+	log.Fatal(srv.ListenAndServe())
 }
 
 func handleWebhook(w http.ResponseWriter, r *http.Request) {
@@ -90,33 +110,41 @@ func handleWebhook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	ctx, cancel := context.WithTimeout(r.Context(), updateTimeout)
+	defer cancel()
 	chatID := update.Message.Chat.ID
 	text := update.Message.Text
 
 	var response string
 	switch {
 	case strings.HasPrefix(text, "/add"):
-		response = handleAdd(chatID, text)
+		response = handleAdd(ctx, chatID, text)
 	case strings.HasPrefix(text, "/list"):
-		response = handleList(chatID)
+		response = handleList(ctx, chatID, text)
 	case strings.HasPrefix(text, "/done"):
-		response = handleDone(chatID, text)
+		response = handleDone(ctx, chatID, text)
 	case strings.HasPrefix(text, "/snooze"):
-		response = handleSnooze(chatID, text)
+		response = handleSnooze(ctx, chatID, text)
+	case strings.HasPrefix(text, "/note"):
+		response = handleNote(ctx, chatID, text)
+	case strings.HasPrefix(text, "/search"):
+		response = handleSearch(ctx, chatID, text)
 	case strings.HasPrefix(text, "/subtask"):
-		response = handleSubtask(chatID, text)
+		response = handleSubtask(ctx, chatID, text)
 	case strings.HasPrefix(text, "/start"):
-		response = "👋 Welcome to TODO Tracker!\n\nCommands:\n/add <task> - Add task\n/list - Show tasks\n/done <id> - Complete task\n/snooze <id> - Postpone to tomorrow\n/subtask <id> <task> - Add subtask"
+		response = "👋 Welcome to TODO Tracker!\n\nCommands:\n/add <task> - Add task (\"every mon\" repeats it)\n/list [#tag] [+project] - Show tasks\n/done <id>... [cascade] - Complete tasks, e.g. /done 3 5 or /done 10-14 (cascade: with their subtasks)\n/snooze <id>... [3d|monday|next week] [skip-weekends] - Postpone (default: tomorrow)\n/note <id> [text or URL] - Show or add to a task's notes and links\n/search <words> [todo|done] - Find tasks, done ones included\n/subtask <id> <task> - Add subtask"
 	default:
-		response = "❌ Unknown command. Use /add, /list, /done, /snooze, or /subtask"
+		response = "❌ Unknown command. Use /add, /list, /done, /snooze, /note, /search, or /subtask"
 	}
 
-	sendTelegram(chatID, response)
+	if err := sendTelegram(chatID, response); err != nil {
+		log.Printf("sendTelegram to %d: %v", chatID, err)
+	}
 	w.WriteHeader(http.StatusOK)
 }
 
-// /add [P#] <title> [date]
-func handleAdd(chatID int64, text string) string {
+// /add [P#] <title with an optional date, e.g. "friday 14:00">
+func handleAdd(ctx context.Context, chatID int64, text string) string {
 	text = strings.TrimPrefix(text, "/add")
 	text = strings.TrimSpace(text)
 
@@ -125,7 +153,6 @@ func handleAdd(chatID int64, text string) string {
 	}
 
 	priority := "P1"
-	dueDate := time.Now().AddDate(0, 0, 1).Format("2006-01-02") // tomorrow
 
 	// Parse priority [P0-P4]
 	prioRegex := regexp.MustCompile(`\[P([0-4])\]`)
@@ -135,46 +162,70 @@ func handleAdd(chatID int64, text string) string {
 		text = strings.TrimSpace(text)
 	}
 
-	// Parse date
-	words := strings.Fields(text)
-	if len(words) > 1 {
-		lastWord := words[len(words)-1]
-		if lastWord == "today" {
-			dueDate = time.Now().Format("2006-01-02")
-			text = strings.Join(words[:len(words)-1], " ")
-		} else if lastWord == "tomorrow" {
-			dueDate = time.Now().AddDate(0, 0, 1).Format("2006-01-02")
-			text = strings.Join(words[:len(words)-1], " ")
-		} else if matched, _ := regexp.MatchString(`^\d{4}-\d{2}-\d{2}$`, lastWord); matched {
-			dueDate = lastWord
-			text = strings.Join(words[:len(words)-1], " ")
-		}
+	// Parse tags and project, e.g. "#work +infra"
+	tags, project, text := labels.Extract(text)
+
+	// Parse a repeat rule such as "every mon" or "monthly on 1st"
+	var rule *recurrence.Rule
+	if r, rest, ok := recurrence.Extract(text); ok {
+		rule, text = &r, rest
 	}
 
-	task := Task{
-		Title:    text,
-		DueDate:  dueDate,
-		Priority: priority,
-		Status:   "Todo",
-		UserID:   fmt.Sprintf("%d", chatID),
+	// Parse a due date anywhere in the text, defaulting to tomorrow
+	now := time.Now()
+	due := dateparse.Result{Date: now.AddDate(0, 0, 1)}
+	if result, rest, ok := dateparse.Extract(text, now); ok && rest != "" {
+		due, text = result, rest
+	}
+
+	recurs := ""
+	if rule != nil {
+		due.Date = rule.First(due.Date)
+		if due.Time == "" {
+			due.Time = rule.At
+		}
+		recurs = rule.String()
 	}
 
-	created, err := createTask(task)
+	created, err := userTasks(chatID).Create(ctx, supabase.Task{
+		Title:      text,
+		DueDate:    due.DateString(),
+		DueTime:    due.Time,
+		Priority:   priority,
+		Status:     "Todo",
+		Recurrence: recurs,
+		Tags:       tags,
+		Project:    project,
+	})
 	if err != nil {
-		return "❌ Failed to add task: " + err.Error()
+		return errorReply("add task", err)
 	}
 
-	return fmt.Sprintf("✅ Task added: %s — due %s [%s]", created.Title, created.DueDate, created.Priority)
+	return fmt.Sprintf("✅ Task added: %s%s — due %s [%s]%s", created.Title, labelSuffix(*created), created.Due(), created.Priority, repeatSuffix(*created))
 }
 
-func handleList(chatID int64) string {
-	today := time.Now().Format("2006-01-02")
-	url := fmt.Sprintf("%s/rest/v1/tasks?user_id=eq.%d&status=eq.Todo&due_date=lte.%s&order=priority.asc,due_date.asc",
-		supabaseURL, chatID, today)
+// /list [#tag ...] [+project]
+func handleList(ctx context.Context, chatID int64, text string) string {
+	tags, project, rest := labels.Extract(strings.TrimPrefix(text, "/list"))
+	if rest != "" {
+		return "❌ Usage: /list [#tag] [+project]"
+	}
 
-	tasks, err := queryTasks(url)
+	// Filtered lists include upcoming tasks; the plain list is today's.
+	today := time.Now().Format("2006-01-02")
+	dueTo := today
+	if len(tags) > 0 || project != "" {
+		dueTo = ""
+	}
+	tasks, err := userTasks(chatID).List(ctx, supabase.Filter{
+		Status:  "Todo",
+		DueTo:   dueTo,
+		Tags:    tags,
+		Project: project,
+		Order:   []string{"priority.asc", "due_date.asc"},
+	})
 	if err != nil {
-		return "❌ Failed to fetch tasks: " + err.Error()
+		return errorReply("fetch tasks", err)
 	}
 
 	if len(tasks) == 0 {
@@ -185,7 +236,7 @@ func handleList(chatID int64) string {
 	sb.WriteString("📋 Your tasks:\n\n")
 	for i, t := range tasks {
 		status := "⬜"
-		sb.WriteString(fmt.Sprintf("%s [%d] [%s] %s", status, t.ID, t.Priority, t.Title))
+		sb.WriteString(fmt.Sprintf("%s [%d] [%s] %s%s%s", status, t.ID, t.Priority, t.Title, labelSuffix(t), repeatSuffix(t)))
 		if t.DueDate < today {
 			sb.WriteString(" ⚠️ overdue")
 		}
@@ -199,159 +250,298 @@ func handleList(chatID int64) string {
 	return sb.String()
 }
 
-func handleDone(chatID int64, text string) string {
+func handleDone(ctx context.Context, chatID int64, text string) string {
 	text = strings.TrimPrefix(text, "/done")
-	text = strings.TrimSpace(text)
-
-	id, err := strconv.Atoi(text)
+	fields := strings.Fields(text)
+	cascade := slices.Contains(fields, "cascade") || slices.Contains(fields, "--cascade")
+	fields = slices.DeleteFunc(fields, func(f string) bool { return f == "cascade" || f == "--cascade" })
+	if len(fields) == 0 {
+		return "❌ Usage: /done <id>... [cascade], e.g. /done 3 5 8 or /done 10-14"
+	}
+	ids, err := query.IDs(fields)
 	if err != nil {
-		return "❌ Invalid task ID. Usage: /done <id>"
+		return "❌ Invalid task ID. Usage: /done <id>... [cascade]"
 	}
 
-	task, err := getTask(id, chatID)
+	tasks := userTasks(chatID)
+	targets, err := tasks.List(ctx, supabase.Filter{IDs: ids})
 	if err != nil {
-		return "❌ Task not found"
+		return errorReply("fetch tasks", err)
+	}
+	if len(targets) < len(ids) {
+		var missing []string
+		for _, id := range ids {
+			if !slices.ContainsFunc(targets, func(t supabase.Task) bool { return t.ID == id }) {
+				missing = append(missing, strconv.Itoa(id))
+			}
+		}
+		return "❌ Task not found: " + strings.Join(missing, ", ")
 	}
 
-	if err := updateTaskStatus(id, chatID, "Done"); err != nil {
-		return "❌ Failed to update task"
+	opt := completion.Options{Cascade: cascade, AutoParent: true}
+	res, err := completion.CompleteAll(ctx, tasks, targets, opt, time.Now())
+	var open *completion.OpenSubtasksError
+	if errors.As(err, &open) {
+		lines := []string{fmt.Sprintf("❌ %d open subtask(s) would be left under finished tasks:", len(open.Open))}
+		for _, t := range open.Open {
+			lines = append(lines, fmt.Sprintf("⬜ [%d] %s", t.ID, t.Title))
+		}
+		lines = append(lines, fmt.Sprintf("Finish them first, or send /done %s cascade", strings.Join(fields, " ")))
+		return strings.Join(lines, "\n")
+	}
+	if len(res.Tasks) == 0 {
+		return errorReply("update task", err)
 	}
 
-	return fmt.Sprintf("✅ Marked as done: %s", task.Title)
+	var lines []string
+	for _, t := range res.Tasks {
+		lines = append(lines, fmt.Sprintf("✅ Marked as done: %s", t.Title))
+	}
+	if n := len(res.Subtasks); n > 0 {
+		lines = append(lines, fmt.Sprintf("✅ Also completed %d subtask(s)", n))
+	}
+	for _, p := range res.Parents {
+		lines = append(lines, fmt.Sprintf("✅ All subtasks done, so [%d] %s is done too", p.ID, p.Title))
+	}
+	for _, next := range res.Scheduled {
+		lines = append(lines, fmt.Sprintf("🔁 Next: [%d] due %s", next.ID, next.Due()))
+	}
+	if err != nil {
+		lines = append(lines, errorReply("finish completing the tasks", err))
+	}
+	return strings.Join(lines, "\n")
 }
 
-func handleSnooze(chatID int64, text string) string {
-	text = strings.TrimPrefix(text, "/snooze")
-	text = strings.TrimSpace(text)
-
-	id, err := strconv.Atoi(text)
+// handleNote serves /note <id> [text]: without text, it shows the task's
+// notes and links
+func handleNote(ctx context.Context, chatID int64, text string) string {
+	idText, rest, _ := strings.Cut(strings.TrimSpace(strings.TrimPrefix(text, "/note")), " ")
+	id, err := strconv.Atoi(idText)
 	if err != nil {
-		return "❌ Invalid task ID. Usage: /snooze <id>"
+		return "❌ Invalid task ID. Usage: /note <id> [text or URL]"
 	}
 
-	task, err := getTask(id, chatID)
+	tasks := userTasks(chatID)
+	task, err := tasks.Get(ctx, id)
 	if err != nil {
-		return "❌ Task not found"
+		return errorReply("fetch task", err)
 	}
-
-	tomorrow := time.Now().AddDate(0, 0, 1).Format("2006-01-02")
-	if err := updateTaskDueDate(id, chatID, tomorrow); err != nil {
-		return "❌ Failed to snooze task"
+	if strings.TrimSpace(rest) == "" {
+		if task.Notes == "" && len(task.Links) == 0 {
+			return fmt.Sprintf("📝 %s has no notes yet. Add some with /note %d <text>", task.Title, id)
+		}
+		var sb strings.Builder
+		sb.WriteString(fmt.Sprintf("📝 %s\n", task.Title))
+		if task.Notes != "" {
+			sb.WriteString("\n" + task.Notes + "\n")
+		}
+		for _, l := range task.Links {
+			sb.WriteString("\n🔗 " + l)
+		}
+		return strings.TrimRight(sb.String(), "\n")
 	}
 
-	return fmt.Sprintf("✅ Snoozed: %s — now due tomorrow", task.Title)
+	rest = strings.TrimSpace(rest)
+	text, links := notes.Add(task.Notes, task.Links, rest)
+	if !notes.IsLink(rest) {
+		if _, err := tasks.UpdateByID(ctx, id, map[string]any{"notes": text}); err != nil {
+			return errorReply("save note", err)
+		}
+		return fmt.Sprintf("📝 Note added to %s", task.Title)
+	}
+	if slices.Contains(task.Links, rest) {
+		return fmt.Sprintf("🔗 %s already has that link", task.Title)
+	}
+	updated, err := tasks.UpdateByID(ctx, id, map[string]any{"links": links})
+	if err != nil {
+		return errorReply("save link", err)
+	}
+	return fmt.Sprintf("🔗 Link added to %s (%d in all)", updated.Title, len(updated.Links))
 }
 
-func handleSubtask(chatID int64, text string) string {
-	text = strings.TrimPrefix(text, "/subtask")
-	text = strings.TrimSpace(text)
-
-	parts := strings.SplitN(text, " ", 2)
-	if len(parts) < 2 {
-		return "❌ Usage: /subtask <parent_id> <task title>"
+// /search <words> [todo|done]
+func handleSearch(ctx context.Context, chatID int64, text string) string {
+	fields := strings.Fields(strings.TrimPrefix(text, "/search"))
+	status := ""
+	if n := len(fields); n > 1 {
+		switch strings.ToLower(fields[n-1]) {
+		case "todo":
+			status, fields = "Todo", fields[:n-1]
+		case "done":
+			status, fields = "Done", fields[:n-1]
+		}
 	}
-
-	parentID, err := strconv.Atoi(parts[0])
-	if err != nil {
-		return "❌ Invalid parent ID"
+	words := strings.Join(fields, " ")
+	if search.Parse(words).Empty() {
+		return "❌ Usage: /search <words> [todo|done], e.g. /search invoice or /search dentist done"
 	}
 
-	parent, err := getTask(parentID, chatID)
+	found, err := userTasks(chatID).Search(ctx, words, supabase.Filter{Status: status, Limit: 11})
 	if err != nil {
-		return "❌ Parent task not found"
+		return errorReply("search tasks", err)
+	}
+	if len(found) == 0 {
+		return fmt.Sprintf("🔍 No tasks match %q", words)
 	}
 
-	task := Task{
-		Title:    parts[1],
-		DueDate:  parent.DueDate,
-		Priority: parent.Priority,
-		Status:   "Todo",
-		ParentID: &parentID,
-		UserID:   fmt.Sprintf("%d", chatID),
+	today := time.Now().Format("2006-01-02")
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("🔍 Tasks matching %q:\n\n", words))
+	for i, t := range found {
+		if i == 10 {
+			sb.WriteString("...and more, add words to narrow it down\n")
+			break
+		}
+		status := "⬜"
+		if t.Status == "Done" {
+			status = "✅"
+		}
+		sb.WriteString(fmt.Sprintf("%s [%d] [%s] %s%s — due %s", status, t.ID, t.Priority, t.Title, labelSuffix(t), t.Due()))
+		if t.Status != "Done" && t.DueDate < today {
+			sb.WriteString(" ⚠️ overdue")
+		}
+		sb.WriteString("\n")
 	}
+	return sb.String()
+}
 
-	_, err = createTask(task)
-	if err != nil {
-		return "❌ Failed to add subtask"
+// labelSuffix renders a task's project and tags, e.g. " +infra #work".
+func labelSuffix(t supabase.Task) string {
+	if s := labels.Format(t.Tags, t.Project); s != "" {
+		return " " + s
 	}
+	return ""
+}
 
-	return fmt.Sprintf("✅ Subtask added to '%s': %s", parent.Title, parts[1])
+// repeatSuffix describes a recurring task's rule, e.g. " 🔁 every Mon".
+func repeatSuffix(t supabase.Task) string {
+	if t.Recurrence == "" {
+		return ""
+	}
+	return " 🔁 " + recurrence.Describe(t.Recurrence)
 }
 
-// Supabase helpers
-func createTask(task Task) (*Task, error) {
-	url := supabaseURL + "/rest/v1/tasks"
-	body, _ := json.Marshal(task)
+func handleSnooze(ctx context.Context, chatID int64, text string) string {
+	text = strings.TrimPrefix(text, "/snooze")
+	fields := strings.Fields(text)
+	skipWeekends := slices.Contains(fields, "skip-weekends") || slices.Contains(fields, "--skip-weekends")
+	fields = slices.DeleteFunc(fields, func(f string) bool { return f == "skip-weekends" || f == "--skip-weekends" })
 
-	req, _ := http.NewRequest("POST", url, bytes.NewBuffer(body))
-	req.Header.Set("apikey", supabaseKey)
-	req.Header.Set("Authorization", "Bearer "+supabaseKey)
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Prefer", "return=representation")
+	n := 0
+	for n < len(fields) {
+		if _, err := query.IDs(fields[n : n+1]); err != nil {
+			break
+		}
+		n++
+	}
+	ids, _ := query.IDs(fields[:n])
+	if len(ids) == 0 {
+		return "❌ Invalid task ID. Usage: /snooze <id>... [3d | monday | next week] [skip-weekends]"
+	}
+	now := time.Now()
+	spec, err := snooze.Parse(strings.Join(fields[n:], " "), now)
+	if err != nil {
+		return "❌ " + err.Error() + ". Try 3d, +2 weeks, monday, next week or 2026-03-01"
+	}
 
-	resp, err := http.DefaultClient.Do(req)
+	tasks := userTasks(chatID)
+	targets, err := tasks.List(ctx, supabase.Filter{IDs: ids})
 	if err != nil {
-		return nil, err
+		return errorReply("fetch tasks", err)
 	}
-	defer resp.Body.Close()
+	if len(targets) < len(ids) {
+		var missing []string
+		for _, id := range ids {
+			if !slices.ContainsFunc(targets, func(t supabase.Task) bool { return t.ID == id }) {
+				missing = append(missing, strconv.Itoa(id))
+			}
+		}
+		return "❌ Task not found: " + strings.Join(missing, ", ")
+	}
+	snoozed, err := snooze.Apply(ctx, tasks, targets, spec, now, skipWeekends)
+	var lines []string
+	for _, t := range snoozed {
+		lines = append(lines, fmt.Sprintf("😴 Snoozed: %s — now due %s (snoozed %s)", t.Title, t.Due(), times(t.SnoozeCount)))
+	}
+	if err != nil {
+		lines = append(lines, errorReply("snooze task", err))
+	}
+	return strings.Join(lines, "\n")
+}
 
-	var tasks []Task
-	json.NewDecoder(resp.Body).Decode(&tasks)
-	if len(tasks) == 0 {
-		return nil, fmt.Errorf("no task returned")
+// times renders a count such as "once" or "3 times".
+func times(n int) string {
+	switch n {
+	case 1:
+		return "once"
+	case 2:
+		return "twice"
 	}
-	return &tasks[0], nil
+	return fmt.Sprintf("%d times", n)
 }
 
-func queryTasks(url string) ([]Task, error) {
-	req, _ := http.NewRequest("GET", url, nil)
-	req.Header.Set("apikey", supabaseKey)
-	req.Header.Set("Authorization", "Bearer "+supabaseKey)
+func handleSubtask(ctx context.Context, chatID int64, text string) string {
+	text = strings.TrimPrefix(text, "/subtask")
+	text = strings.TrimSpace(text)
 
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return nil, err
+	parts := strings.SplitN(text, " ", 2)
+	if len(parts) < 2 {
+		return "❌ Usage: /subtask <parent_id> <task title>"
 	}
-	defer resp.Body.Close()
 
-	var tasks []Task
-	json.NewDecoder(resp.Body).Decode(&tasks)
-	return tasks, nil
-}
+	parentID, err := strconv.Atoi(parts[0])
+	if err != nil {
+		return "❌ Invalid parent ID"
+	}
 
-func getTask(id int, chatID int64) (*Task, error) {
-	url := fmt.Sprintf("%s/rest/v1/tasks?id=eq.%d&user_id=eq.%d", supabaseURL, id, chatID)
-	tasks, err := queryTasks(url)
-	if err != nil || len(tasks) == 0 {
-		return nil, fmt.Errorf("not found")
+	tasks := userTasks(chatID)
+	parent, err := tasks.Get(ctx, parentID)
+	if errors.Is(err, supabase.ErrNotFound) {
+		return "❌ Parent task not found"
+	}
+	if err != nil {
+		return errorReply("add subtask", err)
 	}
-	return &tasks[0], nil
-}
 
-func updateTaskStatus(id int, chatID int64, status string) error {
-	url := fmt.Sprintf("%s/rest/v1/tasks?id=eq.%d&user_id=eq.%d", supabaseURL, id, chatID)
-	body, _ := json.Marshal(map[string]string{"status": status})
+	// Subtasks inherit the parent's project and tags
+	tags, project, title := labels.Inherit(parts[1], parent.Tags, parent.Project)
 
-	req, _ := http.NewRequest("PATCH", url, bytes.NewBuffer(body))
-	req.Header.Set("apikey", supabaseKey)
-	req.Header.Set("Authorization", "Bearer "+supabaseKey)
-	req.Header.Set("Content-Type", "application/json")
+	_, err = tasks.Create(ctx, supabase.Task{
+		Title:    title,
+		DueDate:  parent.DueDate,
+		Priority: parent.Priority,
+		Status:   "Todo",
+		ParentID: &parentID,
+		Tags:     tags,
+		Project:  project,
+	})
+	if err != nil {
+		return errorReply("add subtask", err)
+	}
 
-	_, err := http.DefaultClient.Do(req)
-	return err
+	return fmt.Sprintf("✅ Subtask added to '%s': %s", parent.Title, title)
 }
 
-func updateTaskDueDate(id int, chatID int64, dueDate string) error {
-	url := fmt.Sprintf("%s/rest/v1/tasks?id=eq.%d&user_id=eq.%d", supabaseURL, id, chatID)
-	body, _ := json.Marshal(map[string]string{"due_date": dueDate})
-
-	req, _ := http.NewRequest("PATCH", url, bytes.NewBuffer(body))
-	req.Header.Set("apikey", supabaseKey)
-	req.Header.Set("Authorization", "Bearer "+supabaseKey)
-	req.Header.Set("Content-Type", "application/json")
-
-	_, err := http.DefaultClient.Do(req)
-	return err
+// errorReply turns a task store error into a brief bot reply.
+func errorReply(action string, err error) string {
+	log.Printf("%s: %v", action, err)
+	switch {
+	case errors.Is(err, supabase.ErrNotFound):
+		return "❌ Task not found"
+	case errors.Is(err, supabase.ErrConstraint):
+		var apiErr *supabase.APIError
+		if errors.As(err, &apiErr) {
+			return "❌ Invalid task: " + apiErr.Message
+		}
+		return "❌ Invalid task"
+	case errors.Is(err, supabase.ErrRateLimit):
+		return "❌ Too many requests, try again in a minute"
+	case errors.Is(err, supabase.ErrAuth):
+		return "❌ Bot can't access the task database (check its service key)"
+	case errors.Is(err, supabase.ErrServer), supabase.IsNetworkError(err):
+		return "❌ Task database is unavailable, try again later"
+	}
+	return "❌ Failed to " + action
 }
 
 // Telegram helper
@@ -362,10 +552,15 @@ func sendTelegram(chatID int64, text string) error {
 		"text":    text,
 	})
 
-	resp, err := http.Post(url, "application/json", bytes.NewBuffer(body))
+	resp, err := telegramClient.Post(url, "application/json", bytes.NewBuffer(body))
 	if err != nil {
 		return err
 	}
 	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(resp.Body)
+		return fmt.Errorf("telegram: %d %s", resp.StatusCode, strings.TrimSpace(string(body)))
+	}
 	return nil
 }
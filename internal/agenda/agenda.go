@@ -0,0 +1,39 @@
+// Package agenda groups tasks by when they are due, the way the daily
+// digest lays them out: Overdue, Today and Upcoming.
+package agenda
+
+import "todo-tracker/internal/supabase"
+
+// Section names, in display order.
+const (
+	Overdue  = "Overdue"
+	Today    = "Today"
+	Upcoming = "Upcoming"
+)
+
+// Section is one group of tasks.
+type Section struct {
+	Name  string
+	Tasks []supabase.Task
+}
+
+// Group splits tasks into the Overdue, Today and Upcoming sections relative
+// to today (YYYY-MM-DD), keeping their order within each. All three
+// sections are returned, empty or not. Tasks without a due date are
+// upcoming.
+func Group(tasks []supabase.Task, today string) []Section {
+	sections := []Section{{Name: Overdue}, {Name: Today}, {Name: Upcoming}}
+	for _, t := range tasks {
+		switch {
+		case t.DueDate == "":
+			sections[2].Tasks = append(sections[2].Tasks, t)
+		case t.DueDate < today:
+			sections[0].Tasks = append(sections[0].Tasks, t)
+		case t.DueDate == today:
+			sections[1].Tasks = append(sections[1].Tasks, t)
+		default:
+			sections[2].Tasks = append(sections[2].Tasks, t)
+		}
+	}
+	return sections
+}
@@ -0,0 +1,39 @@
+package agenda
+
+import (
+	"testing"
+
+	"todo-tracker/internal/supabase"
+)
+
+func TestGroup(t *testing.T) {
+	tasks := []supabase.Task{
+		{ID: 1, DueDate: "2026-02-07"},
+		{ID: 2, DueDate: "2026-02-05"},
+		{ID: 3, DueDate: "2026-02-01"},
+		{ID: 4},
+		{ID: 5, DueDate: "2026-02-05"},
+	}
+	want := map[string][]int{Overdue: {3}, Today: {2, 5}, Upcoming: {1, 4}}
+
+	sections := Group(tasks, "2026-02-05")
+	if len(sections) != 3 || sections[0].Name != Overdue || sections[1].Name != Today || sections[2].Name != Upcoming {
+		t.Fatalf("sections = %+v", sections)
+	}
+	for _, s := range sections {
+		var ids []int
+		for _, task := range s.Tasks {
+			ids = append(ids, task.ID)
+		}
+		if len(ids) != len(want[s.Name]) {
+			t.Errorf("%s = %v, want %v", s.Name, ids, want[s.Name])
+			continue
+		}
+		for i := range ids {
+			if ids[i] != want[s.Name][i] {
+				t.Errorf("%s = %v, want %v", s.Name, ids, want[s.Name])
+				break
+			}
+		}
+	}
+}
@@ -0,0 +1,256 @@
+// Package completion enforces the subtask rules for marking tasks done: a
+// parent is only Done once all of its subtasks are, and reopening a subtask
+// reopens the parents above it.
+package completion
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"todo-tracker/internal/recurrence"
+	"todo-tracker/internal/supabase"
+)
+
+// ErrOpenSubtasks is matched by an *OpenSubtasksError.
+var ErrOpenSubtasks = errors.New("task has open subtasks")
+
+// OpenSubtasksError refuses to complete a task whose subtree is not done.
+type OpenSubtasksError struct {
+	Open []supabase.Task // open tasks anywhere below the task, parents first
+}
+
+func (e *OpenSubtasksError) Error() string {
+	return fmt.Sprintf("%v (%d)", ErrOpenSubtasks, len(e.Open))
+}
+
+func (e *OpenSubtasksError) Is(target error) bool { return target == ErrOpenSubtasks }
+
+// Store is the subset of task storage the rules need.
+type Store interface {
+	List(ctx context.Context, f supabase.Filter) ([]supabase.Task, error)
+	Get(ctx context.Context, id int) (*supabase.Task, error)
+	Create(ctx context.Context, t supabase.Task) (*supabase.Task, error)
+	Update(ctx context.Context, f supabase.Filter, fields map[string]any) ([]supabase.Task, error)
+	UpdateByID(ctx context.Context, id int, fields map[string]any) (*supabase.Task, error)
+}
+
+// Options controls Complete.
+type Options struct {
+	Cascade    bool // complete open subtasks instead of refusing
+	AutoParent bool // complete a parent when its last open subtask is done
+}
+
+// Result lists what Complete changed.
+type Result struct {
+	Tasks     []supabase.Task // the tasks that were asked for
+	Subtasks  []supabase.Task // open subtasks completed by Cascade
+	Parents   []supabase.Task // parents completed by AutoParent, nearest first
+	Scheduled []supabase.Task // next occurrences of recurring tasks
+}
+
+// Changed returns every task Complete wrote, in the order above.
+func (r Result) Changed() []supabase.Task {
+	out := append(slices.Clone(r.Tasks), r.Subtasks...)
+	out = append(out, r.Parents...)
+	return append(out, r.Scheduled...)
+}
+
+// Complete marks the task with the given ID done; see CompleteAll.
+func Complete(ctx context.Context, s Store, id int, opt Options, now time.Time) (Result, error) {
+	task, err := s.Get(ctx, id)
+	if err != nil {
+		return Result{}, err
+	}
+	return CompleteAll(ctx, s, []supabase.Task{*task}, opt, now)
+}
+
+// CompleteAll marks tasks done in a single update. If any of them has open
+// subtasks outside tasks it returns an *OpenSubtasksError, or with
+// opt.Cascade completes those subtasks in the same update. Completed
+// recurring tasks are advanced to their next occurrence; subtasks of a
+// recurring task are copied along with it rather than advanced on their
+// own.
+//
+// Any error other than an *OpenSubtasksError may come with a partial
+// Result, so callers can report what did change.
+func CompleteAll(ctx context.Context, s Store, tasks []supabase.Task, opt Options, now time.Time) (Result, error) {
+	var res Result
+	if len(tasks) == 0 {
+		return res, nil
+	}
+	selected := map[int]bool{}
+	ids := make([]int, len(tasks))
+	for i, t := range tasks {
+		selected[t.ID] = true
+		ids[i] = t.ID
+	}
+	below, err := Descendants(ctx, s, tasks[0].UserID, ids...)
+	if err != nil {
+		return res, err
+	}
+	var open []supabase.Task
+	for _, t := range below {
+		if t.Status != "Done" && !selected[t.ID] {
+			open = append(open, t)
+		}
+	}
+	if len(open) > 0 && !opt.Cascade {
+		return res, &OpenSubtasksError{Open: open}
+	}
+
+	// One update for the tasks and their subtasks, so that a failure never
+	// leaves a Done parent above open subtasks.
+	for _, t := range open {
+		ids = append(ids, t.ID)
+	}
+	updated, err := s.Update(ctx, supabase.Filter{IDs: ids}, map[string]any{"status": "Done"})
+	if err != nil {
+		return res, err
+	}
+	done := map[int]bool{}
+	for _, t := range updated {
+		done[t.ID] = true
+		if selected[t.ID] {
+			res.Tasks = append(res.Tasks, t)
+		} else {
+			res.Subtasks = append(res.Subtasks, t)
+		}
+	}
+	if len(res.Tasks) == 0 {
+		return res, supabase.ErrNotFound
+	}
+
+	if opt.AutoParent {
+		for _, t := range res.Tasks {
+			if t.ParentID == nil || done[*t.ParentID] {
+				continue
+			}
+			parents, err := completeParents(ctx, s, t)
+			res.Parents = append(res.Parents, parents...)
+			for _, p := range parents {
+				done[p.ID] = true
+			}
+			if err != nil {
+				return res, err
+			}
+		}
+	}
+
+	// Advance every completed recurring task that is not carried along by
+	// a completed recurring parent.
+	completed := res.Changed()
+	recurring := map[int]bool{}
+	for _, t := range completed {
+		if t.Recurrence != "" {
+			recurring[t.ID] = true
+		}
+	}
+	for _, t := range completed {
+		if t.ParentID != nil && recurring[*t.ParentID] {
+			continue
+		}
+		next, err := recurrence.Advance(ctx, s, t, now)
+		if err != nil {
+			return res, err
+		}
+		if next != nil {
+			res.Scheduled = append(res.Scheduled, *next)
+		}
+	}
+	return res, nil
+}
+
+// completeParents walks up from t, completing each parent whose subtasks
+// are now all done.
+func completeParents(ctx context.Context, s Store, t supabase.Task) ([]supabase.Task, error) {
+	var parents []supabase.Task
+	seen := map[int]bool{t.ID: true}
+	for t.ParentID != nil && !seen[*t.ParentID] {
+		seen[*t.ParentID] = true
+		open, err := s.List(ctx, supabase.Filter{UserID: t.UserID, ParentID: t.ParentID, Status: "Todo", Limit: 1})
+		if err != nil {
+			return parents, err
+		}
+		if len(open) > 0 {
+			break
+		}
+		parent, err := s.Get(ctx, *t.ParentID)
+		if err != nil {
+			return parents, err
+		}
+		if parent.Status == "Done" {
+			break
+		}
+		parent, err = s.UpdateByID(ctx, parent.ID, map[string]any{"status": "Done"})
+		if err != nil {
+			return parents, err
+		}
+		parents = append(parents, *parent)
+		t = *parent
+	}
+	return parents, nil
+}
+
+// Reopen marks a task Todo again along with every Done parent above it,
+// since a parent cannot be done while one of its subtasks is open. It
+// returns the reopened tasks, the task itself first.
+func Reopen(ctx context.Context, s Store, id int) ([]supabase.Task, error) {
+	task, err := s.UpdateByID(ctx, id, map[string]any{"status": "Todo"})
+	if err != nil {
+		return nil, err
+	}
+	reopened := []supabase.Task{*task}
+	var ids []int
+	seen := map[int]bool{task.ID: true}
+	for t := *task; t.ParentID != nil && !seen[*t.ParentID]; {
+		seen[*t.ParentID] = true
+		parent, err := s.Get(ctx, *t.ParentID)
+		if err != nil {
+			return reopened, err
+		}
+		if parent.Status != "Done" {
+			break
+		}
+		ids = append(ids, parent.ID)
+		t = *parent
+	}
+	if len(ids) == 0 {
+		return reopened, nil
+	}
+	parents, err := s.Update(ctx, supabase.Filter{IDs: ids}, map[string]any{"status": "Todo"})
+	return append(reopened, parents...), err
+}
+
+// Descendants returns every task below the given ones, whatever its
+// status, parents before their subtasks. It fetches one level of the tree
+// per request.
+func Descendants(ctx context.Context, s Store, userID string, ids ...int) ([]supabase.Task, error) {
+	var all []supabase.Task
+	seen := map[int]bool{}
+	for _, id := range ids {
+		seen[id] = true
+	}
+	level := slices.Clone(ids)
+	for len(level) > 0 {
+		children, err := s.List(ctx, supabase.Filter{
+			UserID:    userID,
+			ParentIDs: level,
+			Order:     []string{"priority", "due_date"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		level = nil
+		for _, c := range children {
+			if !seen[c.ID] {
+				seen[c.ID] = true
+				all = append(all, c)
+				level = append(level, c.ID)
+			}
+		}
+	}
+	return all, nil
+}
@@ -0,0 +1,137 @@
+package completion
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"todo-tracker/internal/storage"
+	"todo-tracker/internal/supabase"
+)
+
+var now = time.Date(2026, 2, 9, 12, 0, 0, 0, time.UTC)
+
+// newTree stores 1 > (2 > 4, 3) and returns the store.
+func newTree(t *testing.T) *storage.Local {
+	ctx := context.Background()
+	s := storage.NewLocal(filepath.Join(t.TempDir(), "tasks.json"))
+	root, _ := s.Create(ctx, supabase.Task{Title: "Launch"})
+	a, _ := s.Create(ctx, supabase.Task{Title: "Backend", ParentID: &root.ID})
+	s.Create(ctx, supabase.Task{Title: "Frontend", ParentID: &root.ID})
+	s.Create(ctx, supabase.Task{Title: "API", ParentID: &a.ID})
+	return s
+}
+
+func status(t *testing.T, s *storage.Local, id int) string {
+	task, err := s.Get(context.Background(), id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return task.Status
+}
+
+func TestCompleteRefusesOpenSubtasks(t *testing.T) {
+	s := newTree(t)
+	_, err := Complete(context.Background(), s, 1, Options{}, now)
+	var open *OpenSubtasksError
+	if !errors.As(err, &open) || !errors.Is(err, ErrOpenSubtasks) {
+		t.Fatalf("err = %v, want OpenSubtasksError", err)
+	}
+	if len(open.Open) != 3 || open.Open[0].ID != 2 || open.Open[2].ID != 4 {
+		t.Errorf("open = %+v", open.Open)
+	}
+	if status(t, s, 1) != "Todo" {
+		t.Error("refused task was completed")
+	}
+}
+
+func TestCompleteCascade(t *testing.T) {
+	s := newTree(t)
+	res, err := Complete(context.Background(), s, 1, Options{Cascade: true}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Subtasks) != 3 || len(res.Tasks) != 1 || res.Tasks[0].Status != "Done" {
+		t.Errorf("result = %+v", res)
+	}
+	for id := 1; id <= 4; id++ {
+		if status(t, s, id) != "Done" {
+			t.Errorf("task %d not done", id)
+		}
+	}
+}
+
+func TestCompleteAutoParent(t *testing.T) {
+	s := newTree(t)
+	ctx := context.Background()
+	res, err := Complete(ctx, s, 4, Options{AutoParent: true}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Backend's only subtask is done; Launch still has Frontend open.
+	if len(res.Parents) != 1 || res.Parents[0].ID != 2 || status(t, s, 1) != "Todo" {
+		t.Fatalf("parents = %+v", res.Parents)
+	}
+	res, err = Complete(ctx, s, 3, Options{AutoParent: true}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Parents) != 1 || res.Parents[0].ID != 1 {
+		t.Errorf("parents = %+v", res.Parents)
+	}
+
+	// Without AutoParent the parent stays open.
+	s = newTree(t)
+	Complete(ctx, s, 4, Options{}, now)
+	if status(t, s, 2) != "Todo" {
+		t.Error("parent completed without AutoParent")
+	}
+}
+
+func TestCompleteAdvancesRecurringParentOnly(t *testing.T) {
+	ctx := context.Background()
+	s := storage.NewLocal(filepath.Join(t.TempDir(), "tasks.json"))
+	parent, _ := s.Create(ctx, supabase.Task{Title: "Review", DueDate: "2026-02-09", Recurrence: "FREQ=WEEKLY;BYDAY=MO"})
+	s.Create(ctx, supabase.Task{Title: "Notes", DueDate: "2026-02-09", ParentID: &parent.ID, Recurrence: "FREQ=DAILY"})
+
+	res, err := Complete(ctx, s, parent.ID, Options{Cascade: true}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Scheduled) != 1 || res.Scheduled[0].DueDate != "2026-02-16" {
+		t.Errorf("scheduled = %+v", res.Scheduled)
+	}
+}
+
+func TestReopenReopensParents(t *testing.T) {
+	s := newTree(t)
+	ctx := context.Background()
+	if _, err := Complete(ctx, s, 1, Options{Cascade: true}, now); err != nil {
+		t.Fatal(err)
+	}
+	reopened, err := Reopen(ctx, s, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(reopened) != 3 || status(t, s, 1) != "Todo" || status(t, s, 2) != "Todo" || status(t, s, 3) != "Done" {
+		t.Errorf("reopened = %+v", reopened)
+	}
+}
+
+func TestCompleteAllInOneUpdate(t *testing.T) {
+	s := newTree(t)
+	ctx := context.Background()
+	backend, _ := s.Get(ctx, 2)
+	api, _ := s.Get(ctx, 4)
+
+	// Backend's only subtask is part of the selection, so nothing is open.
+	res, err := CompleteAll(ctx, s, []supabase.Task{*backend, *api}, Options{}, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(res.Tasks) != 2 || len(res.Subtasks) != 0 || status(t, s, 1) != "Todo" {
+		t.Errorf("result = %+v", res)
+	}
+}
@@ -0,0 +1,229 @@
+package config
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+
+	"todo-tracker/internal/secrets"
+)
+
+// isolate points HOME at an empty directory and clears every variable a
+// setting could be read from.
+func isolate(t *testing.T) string {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(ProfileEnv, "")
+	for _, k := range Keys {
+		for _, env := range k.Env {
+			t.Setenv(env, "")
+		}
+	}
+	return home
+}
+
+func TestResolvePrecedence(t *testing.T) {
+	home := isolate(t)
+	f := &File{Profiles: map[string]map[string]string{
+		"work": {"token": "work-token", "backend": "local", "local_file": "~/work.json"},
+	}}
+
+	s, err := Resolve(f, "work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if s.Get("token") != "work-token" || s.Source("token") != "profile work" {
+		t.Errorf("token = %q from %q", s.Get("token"), s.Source("token"))
+	}
+	if s.Get("local_file") != filepath.Join(home, "work.json") {
+		t.Errorf("local_file = %q", s.Get("local_file"))
+	}
+	if s.Source("supabase_url") != "default" || s.Get("supabase_url") == "" {
+		t.Errorf("supabase_url = %q from %q", s.Get("supabase_url"), s.Source("supabase_url"))
+	}
+
+	// The environment beats the profile, the newer name the older one.
+	t.Setenv("TODO_CLI_API_TOKEN", "old-env")
+	t.Setenv("TODO_CLI_TOKEN", "env-token")
+	if s, _ := Resolve(f, "work"); s.Get("token") != "env-token" || s.Source("token") != "env TODO_CLI_TOKEN" {
+		t.Errorf("token = %q from %q", s.Get("token"), s.Source("token"))
+	}
+
+	// ~/.todo-cli-token comes after the profile.
+	t.Setenv("TODO_CLI_TOKEN", "")
+	t.Setenv("TODO_CLI_API_TOKEN", "")
+	os.WriteFile(filepath.Join(home, ".todo-cli-token"), []byte("file-token\n"), 0600)
+	if s, _ := Resolve(f, "work"); s.Get("token") != "work-token" {
+		t.Errorf("profile token = %q", s.Get("token"))
+	}
+	if s, _ := Resolve(f, ""); s.Get("token") != "file-token" || s.Source("token") != "~/.todo-cli-token" {
+		t.Errorf("legacy token = %q from %q", s.Get("token"), s.Source("token"))
+	}
+
+	if _, err := Resolve(f, "home"); err == nil || !strings.Contains(err.Error(), "profiles: work") {
+		t.Errorf("missing profile err = %v", err)
+	}
+}
+
+func TestSelectProfile(t *testing.T) {
+	isolate(t)
+	f := &File{Profile: "personal"}
+	if got := SelectProfile(f, ""); got != "personal" {
+		t.Errorf("default = %q", got)
+	}
+	t.Setenv(ProfileEnv, "work")
+	if got := SelectProfile(f, ""); got != "work" {
+		t.Errorf("env = %q", got)
+	}
+	if got := SelectProfile(f, "local"); got != "local" {
+		t.Errorf("flag = %q", got)
+	}
+}
+
+func TestSetSaveLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "todo", "config.toml")
+	f, err := Load(path)
+	if err != nil || f.Profile != "" || len(f.Profiles) != 0 {
+		t.Fatalf("Load(missing) = %+v, %v", f, err)
+	}
+
+	for _, kv := range [][3]string{{"", "profile", "personal"}, {"personal", "token", "t0k"}, {"local", "backend", "local"}, {"personal", "output", "json"}, {"personal", "output", ""}} {
+		if err := f.Set(kv[0], kv[1], kv[2]); err != nil {
+			t.Fatalf("Set%v: %v", kv, err)
+		}
+	}
+	if err := f.Set("personal", "colour", "blue"); err == nil {
+		t.Error("unknown key accepted")
+	}
+	if err := f.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := os.Stat(path); info.Mode().Perm() != 0600 {
+		t.Errorf("mode = %v", info.Mode())
+	}
+
+	got, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Profile != "personal" || !slices.Equal(got.Names(), []string{"local", "personal"}) {
+		t.Errorf("Load = %+v", got)
+	}
+	if p := got.Profiles["personal"]; p["token"] != "t0k" || p["output"] != "" {
+		t.Errorf("personal = %v", p)
+	}
+
+	os.WriteFile(path, []byte("[profiles.x]\ncolour = \"blue\"\n"), 0600)
+	if _, err := Load(path); err == nil || !strings.Contains(err.Error(), `"colour"`) {
+		t.Errorf("Load(unknown key) err = %v", err)
+	}
+
+	// Settings of the removed service role mode are dropped, not errors.
+	os.WriteFile(path, []byte("[profiles.x]\ntoken = \"t\"\nservice_role_key = \"k\"\nchat_id = \"42\"\n"), 0600)
+	got, err = Load(path)
+	if err != nil || len(got.Dropped) != 2 || !strings.HasPrefix(got.Dropped[1], `service_role_key in profile "x"`) || len(got.Profiles["x"]) != 1 {
+		t.Errorf("Load(retired keys) = %+v, %v", got, err)
+	}
+	got.Save(path)
+	if data, _ := os.ReadFile(path); strings.Contains(string(data), "service_role_key") {
+		t.Errorf("saved retired key: %s", data)
+	}
+}
+
+func TestTakeProfileFlag(t *testing.T) {
+	profile, rest, err := TakeProfileFlag([]string{"list", "--profile", "work", "--flat"})
+	if err != nil || profile != "work" || !slices.Equal(rest, []string{"list", "--flat"}) {
+		t.Errorf("got %q %v %v", profile, rest, err)
+	}
+	if profile, _, _ := TakeProfileFlag([]string{"--profile=local", "ls"}); profile != "local" {
+		t.Errorf("--profile= got %q", profile)
+	}
+	if _, _, err := TakeProfileFlag([]string{"ls", "--profile"}); err == nil {
+		t.Error("missing name accepted")
+	}
+}
+
+// memStore is a secrets.Store in memory.
+type memStore map[string]string
+
+func (m memStore) Get(name string) (string, error) {
+	v, ok := m[name]
+	if !ok {
+		return "", secrets.ErrNotFound
+	}
+	return v, nil
+}
+func (m memStore) Set(name, value string) error { m[name] = value; return nil }
+func (m memStore) Delete(name string) error     { delete(m, name); return nil }
+func (m memStore) String() string               { return "keyring" }
+
+func TestLoadSecrets(t *testing.T) {
+	home := isolate(t)
+	os.WriteFile(filepath.Join(home, ".todo-cli-token"), []byte("legacy\n"), 0600)
+	f := &File{Profiles: map[string]map[string]string{
+		"work": {"secret_store": "keyring"},
+		"home": {"secret_store": "keyring", "token": "plain"},
+	}}
+	s, err := Resolve(f, "work")
+	if err != nil {
+		t.Fatal(err)
+	}
+	store := memStore{"work/token": "from-keyring", "work/backend": "local", "home/token": "stored"}
+	if err := s.LoadSecrets(store); err != nil {
+		t.Fatal(err)
+	}
+	if s.Get("token") != "from-keyring" || s.Source("token") != "keyring" {
+		t.Errorf("token = %q from %q", s.Get("token"), s.Source("token"))
+	}
+	// Only credentials are read, and a value in the profile itself wins.
+	if s.Get("backend") != "supabase" {
+		t.Errorf("backend = %q", s.Get("backend"))
+	}
+	s, _ = Resolve(f, "home")
+	s.LoadSecrets(store)
+	if s.Get("token") != "plain" {
+		t.Errorf("token in profile = %q", s.Get("token"))
+	}
+
+	t.Setenv("TODO_CLI_TOKEN", "env-token")
+	s, _ = Resolve(f, "work")
+	s.LoadSecrets(store)
+	if s.Get("token") != "env-token" {
+		t.Errorf("token with env = %q", s.Get("token"))
+	}
+}
+
+func TestInsecureFiles(t *testing.T) {
+	home := isolate(t)
+	path := filepath.Join(home, "config.toml")
+	env := filepath.Join(home, ".env")
+	harmless := filepath.Join(home, "other.env")
+	f := &File{Profiles: map[string]map[string]string{"work": {"token": "t"}}}
+	if err := f.Save(path); err != nil {
+		t.Fatal(err)
+	}
+	os.Chmod(path, 0644)
+	os.WriteFile(env, []byte("SUPABASE_SERVICE_ROLE_KEY=k\n"), 0644)
+	os.WriteFile(harmless, []byte("TODO_CLI_BACKEND=local\n"), 0644)
+	os.WriteFile(filepath.Join(home, ".todo-cli-token"), []byte("t\n"), 0600)
+
+	var paths []string
+	for _, err := range f.InsecureFiles(path, env, harmless) {
+		var perm *secrets.PermissionError
+		if errors.As(err, &perm) {
+			paths = append(paths, perm.Path)
+		}
+	}
+	if !slices.Equal(paths, []string{path, env}) {
+		t.Errorf("insecure = %v", paths)
+	}
+
+	// Without credentials the config file may be shared.
+	f.Set("work", "token", "")
+	if errs := f.InsecureFiles(path); len(errs) != 0 {
+		t.Errorf("insecure without credentials = %v", errs)
+	}
+}
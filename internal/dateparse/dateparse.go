@@ -0,0 +1,431 @@
+// Package dateparse understands the due date expressions accepted by the
+// CLI and the Telegram bot, such as "tomorrow 14:00", "next mon", "+3d",
+// "in 2 weeks", "eom", "feb 15" and "15/02".
+//
+// Weekday names mean the next such day after today ("fri" on a Friday is a
+// week away); "next <weekday>" means that day in the following Monday-based
+// week. "eow" is the coming Sunday and "eom" the last day of the month.
+// Month-day forms without a year roll over to next year once passed. Day and
+// month in numeric forms are ordered day first (15/02).
+package dateparse
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Layout is the date format stored in the tasks table.
+const Layout = "2006-01-02"
+
+// Result is a parsed due date, at midnight in the reference time's location.
+type Result struct {
+	Date time.Time
+	Time string // "HH:MM", or empty when no time was given
+}
+
+// DateString returns the date in the tasks table's YYYY-MM-DD format.
+func (r Result) DateString() string {
+	return r.Date.Format(Layout)
+}
+
+func (r Result) String() string {
+	if r.Time == "" {
+		return r.DateString()
+	}
+	return r.DateString() + " " + r.Time
+}
+
+// ErrNoDate is returned by Parse when the input is not a date expression.
+var ErrNoDate = errors.New("not a recognised date")
+
+// Parse interprets all of s as a date expression relative to now.
+func Parse(s string, now time.Time) (Result, error) {
+	words := strings.Fields(s)
+	if len(words) == 0 {
+		return Result{}, ErrNoDate
+	}
+	r, n, ok := match(normalize(words), now, true)
+	if !ok || n != len(words) {
+		return Result{}, fmt.Errorf("%w: %q", ErrNoDate, s)
+	}
+	return r, nil
+}
+
+// Extract finds the last date expression anywhere in text and returns it
+// together with text with the expression (and a leading "on", "by", "due"
+// or "at") removed. ok is false when text contains no date expression.
+func Extract(text string, now time.Time) (r Result, rest string, ok bool) {
+	words := strings.Fields(text)
+	norm := normalize(words)
+	for i := len(words) - 1; i >= 0; i-- {
+		r, n, ok := matchAt(norm, i, now)
+		if !ok {
+			continue
+		}
+		// Prefer a longer expression ending at the same word, so
+		// "tomorrow at 2pm" wins over "at 2pm".
+		end := i + n
+		for j := i - 1; j >= 0 && j >= i-4; j-- {
+			if r2, n2, ok := matchAt(norm, j, now); ok && j+n2 == end {
+				r, i, n = r2, j, n2
+			}
+		}
+		start := i
+		if start > 0 && isPreposition(norm[start-1]) && len(words) > n+1 {
+			start--
+		}
+		kept := append(append([]string{}, words[:start]...), words[i+n:]...)
+		return r, strings.Join(kept, " "), true
+	}
+	return Result{}, text, false
+}
+
+// matchAt matches an expression starting at norm[i]. Abbreviated weekdays
+// such as "sun" or "wed" only count after a preposition or at the very end,
+// so titles like "Buy sun cream" are left alone.
+func matchAt(norm []string, i int, now time.Time) (Result, int, bool) {
+	if r, n, ok := match(norm[i:], now, false); ok {
+		return r, n, true
+	}
+	r, n, ok := match(norm[i:], now, true)
+	if !ok || (i+n != len(norm) && !(i > 0 && isPreposition(norm[i-1]))) {
+		return Result{}, 0, false
+	}
+	return r, n, true
+}
+
+func isPreposition(w string) bool {
+	switch w {
+	case "on", "by", "due", "at":
+		return true
+	}
+	return false
+}
+
+// normalize lowercases words and strips trailing punctuation.
+func normalize(words []string) []string {
+	out := make([]string, len(words))
+	for i, w := range words {
+		out[i] = strings.TrimRight(strings.ToLower(w), ",.;!?")
+	}
+	return out
+}
+
+// match parses a date expression with an optional trailing time at the
+// start of words and returns how many words it consumed. A time on its own
+// only counts when introduced by "at".
+func match(words []string, now time.Time, abbrev bool) (Result, int, bool) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+
+	if d, n, ok := matchDate(words, today, abbrev); ok {
+		r := Result{Date: d}
+		rest := words[n:]
+		if len(rest) > 0 && rest[0] == "at" {
+			if t, ok := parseClock(firstOrEmpty(rest[1:])); ok {
+				r.Time = t
+				return r, n + 2, true
+			}
+		} else if t, ok := parseClock(firstOrEmpty(rest)); ok {
+			r.Time = t
+			return r, n + 1, true
+		}
+		return r, n, true
+	}
+
+	if len(words) >= 2 && words[0] == "at" {
+		if t, ok := parseClock(words[1]); ok {
+			d := today
+			if t <= now.Format("15:04") {
+				d = today.AddDate(0, 0, 1)
+			}
+			return Result{Date: d, Time: t}, 2, true
+		}
+	}
+	return Result{}, 0, false
+}
+
+func firstOrEmpty(words []string) string {
+	if len(words) == 0 {
+		return ""
+	}
+	return words[0]
+}
+
+var (
+	isoDate   = regexp.MustCompile(`^(\d{4})-(\d{2})-(\d{2})$`)
+	offset    = regexp.MustCompile(`^\+(\d+)([dwmy])$`)
+	dayMonth  = regexp.MustCompile(`^(\d{1,2})/(\d{1,2})(?:/(\d{4}))?$`)
+	dayNumber = regexp.MustCompile(`^(\d{1,2})(?:st|nd|rd|th)?$`)
+	clock24   = regexp.MustCompile(`^(\d{1,2}):(\d{2})$`)
+	clock12   = regexp.MustCompile(`^(\d{1,2})(?::(\d{2}))?(am|pm)$`)
+)
+
+var weekdays = map[string]time.Weekday{
+	"sunday": time.Sunday, "monday": time.Monday, "tuesday": time.Tuesday,
+	"wednesday": time.Wednesday, "thursday": time.Thursday, "friday": time.Friday,
+	"saturday": time.Saturday,
+}
+
+var weekdayAbbrevs = map[string]time.Weekday{
+	"sun": time.Sunday, "mon": time.Monday, "tue": time.Tuesday, "tues": time.Tuesday,
+	"wed": time.Wednesday, "thu": time.Thursday, "thur": time.Thursday, "thurs": time.Thursday,
+	"fri": time.Friday, "sat": time.Saturday,
+}
+
+var months = map[string]time.Month{
+	"jan": time.January, "january": time.January, "feb": time.February, "february": time.February,
+	"mar": time.March, "march": time.March, "apr": time.April, "april": time.April,
+	"may": time.May, "jun": time.June, "june": time.June, "jul": time.July, "july": time.July,
+	"aug": time.August, "august": time.August, "sep": time.September, "sept": time.September,
+	"september": time.September, "oct": time.October, "october": time.October,
+	"nov": time.November, "november": time.November, "dec": time.December, "december": time.December,
+}
+
+func weekday(w string, abbrev bool) (time.Weekday, bool) {
+	if d, ok := weekdays[w]; ok {
+		return d, true
+	}
+	if abbrev {
+		d, ok := weekdayAbbrevs[w]
+		return d, ok
+	}
+	return 0, false
+}
+
+// Weekday parses a full or abbreviated weekday name ("monday", "mon").
+func Weekday(w string) (time.Weekday, bool) {
+	return weekday(strings.ToLower(w), true)
+}
+
+// Clock parses a time of day such as "14:00", "9am" or "2:30pm" and returns
+// it as "HH:MM".
+func Clock(w string) (string, bool) {
+	return parseClock(strings.ToLower(w))
+}
+
+// matchDate parses the date part of an expression at the start of words.
+func matchDate(words []string, today time.Time, abbrev bool) (time.Time, int, bool) {
+	if len(words) == 0 {
+		return time.Time{}, 0, false
+	}
+	w := words[0]
+
+	// Multi-word forms first so "next mon" is not read as "next" + "mon".
+	if len(words) >= 3 {
+		if w == "in" {
+			if d, ok := inOffset(words[1], words[2], today); ok {
+				return d, 3, true
+			}
+		}
+		if w == "end" && words[1] == "of" {
+			switch words[2] {
+			case "week":
+				return endOfWeek(today), 3, true
+			case "month":
+				return endOfMonth(today), 3, true
+			}
+		}
+	}
+	if len(words) >= 2 {
+		switch w {
+		case "next":
+			if words[1] == "week" {
+				return startOfWeek(today).AddDate(0, 0, 7), 2, true
+			}
+			if words[1] == "month" {
+				return time.Date(today.Year(), today.Month()+1, 1, 0, 0, 0, 0, today.Location()), 2, true
+			}
+			if d, ok := weekday(words[1], true); ok {
+				return startOfWeek(today).AddDate(0, 0, 7+daysFromMonday(d)), 2, true
+			}
+		case "this":
+			if d, ok := weekday(words[1], true); ok {
+				return nextWeekday(today, d, 0), 2, true
+			}
+		}
+		if m, ok := months[w]; ok {
+			if day, ok := dayOfMonth(words[1]); ok {
+				if y, ok := year(words[2:]); ok {
+					return monthDay(today, y, m, day, 3)
+				}
+				return monthDay(today, 0, m, day, 2)
+			}
+		}
+		if day, ok := dayOfMonth(w); ok {
+			if m, ok := months[words[1]]; ok {
+				if y, ok := year(words[2:]); ok {
+					return monthDay(today, y, m, day, 3)
+				}
+				return monthDay(today, 0, m, day, 2)
+			}
+		}
+	}
+
+	switch w {
+	case "today", "tod":
+		return today, 1, true
+	case "tomorrow", "tmrw", "tmr":
+		return today.AddDate(0, 0, 1), 1, true
+	case "eow":
+		return endOfWeek(today), 1, true
+	case "eom":
+		return endOfMonth(today), 1, true
+	}
+	if d, ok := weekday(w, abbrev); ok {
+		return nextWeekday(today, d, 1), 1, true
+	}
+	if m := isoDate.FindStringSubmatch(w); m != nil {
+		d, err := time.ParseInLocation(Layout, w, today.Location())
+		return d, 1, err == nil
+	}
+	if m := offset.FindStringSubmatch(w); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		return addUnit(today, n, m[2]), 1, true
+	}
+	if m := dayMonth.FindStringSubmatch(w); m != nil {
+		day, _ := strconv.Atoi(m[1])
+		mon, _ := strconv.Atoi(m[2])
+		y := 0
+		if m[3] != "" {
+			y, _ = strconv.Atoi(m[3])
+		}
+		if mon < 1 || mon > 12 {
+			return time.Time{}, 0, false
+		}
+		return monthDay(today, y, time.Month(mon), day, 1)
+	}
+	return time.Time{}, 0, false
+}
+
+func inOffset(count, unit string, today time.Time) (time.Time, bool) {
+	n, err := strconv.Atoi(count)
+	if count == "a" || count == "an" || count == "one" {
+		n, err = 1, nil
+	}
+	if err != nil || n < 0 {
+		return time.Time{}, false
+	}
+	switch strings.TrimSuffix(unit, "s") {
+	case "day":
+		return addUnit(today, n, "d"), true
+	case "week":
+		return addUnit(today, n, "w"), true
+	case "month":
+		return addUnit(today, n, "m"), true
+	case "year":
+		return addUnit(today, n, "y"), true
+	}
+	return time.Time{}, false
+}
+
+func addUnit(d time.Time, n int, unit string) time.Time {
+	switch unit {
+	case "d":
+		return d.AddDate(0, 0, n)
+	case "w":
+		return d.AddDate(0, 0, 7*n)
+	case "m":
+		return AddMonths(d, n)
+	case "y":
+		return AddMonths(d, 12*n)
+	}
+	return d
+}
+
+// AddMonths moves d by n months, clamping to the last day of the target
+// month (Jan 31 + 1 month is Feb 28/29, not Mar 3).
+func AddMonths(d time.Time, n int) time.Time {
+	first := time.Date(d.Year(), d.Month()+time.Month(n), 1, 0, 0, 0, 0, d.Location())
+	last := endOfMonth(first).Day()
+	return first.AddDate(0, 0, min(d.Day(), last)-1)
+}
+
+func dayOfMonth(w string) (int, bool) {
+	m := dayNumber.FindStringSubmatch(w)
+	if m == nil {
+		return 0, false
+	}
+	day, _ := strconv.Atoi(m[1])
+	return day, day >= 1 && day <= 31
+}
+
+func year(words []string) (int, bool) {
+	if len(words) == 0 || len(words[0]) != 4 {
+		return 0, false
+	}
+	y, err := strconv.Atoi(words[0])
+	return y, err == nil
+}
+
+// monthDay builds a date, rolling over to next year when no year is given
+// and the date has already passed.
+func monthDay(today time.Time, y int, m time.Month, day, n int) (time.Time, int, bool) {
+	explicit := y != 0
+	if !explicit {
+		y = today.Year()
+	}
+	d := time.Date(y, m, day, 0, 0, 0, 0, today.Location())
+	if d.Month() != m {
+		return time.Time{}, 0, false // e.g. 31/02
+	}
+	if !explicit && d.Before(today) {
+		d = d.AddDate(1, 0, 0)
+	}
+	return d, n, true
+}
+
+// nextWeekday returns the first d at least minDays after today.
+func nextWeekday(today time.Time, d time.Weekday, minDays int) time.Time {
+	ahead := (int(d) - int(today.Weekday()) + 7) % 7
+	if ahead < minDays {
+		ahead += 7
+	}
+	return today.AddDate(0, 0, ahead)
+}
+
+func daysFromMonday(d time.Weekday) int {
+	return (int(d) + 6) % 7
+}
+
+func startOfWeek(today time.Time) time.Time {
+	return today.AddDate(0, 0, -daysFromMonday(today.Weekday()))
+}
+
+func endOfWeek(today time.Time) time.Time {
+	return startOfWeek(today).AddDate(0, 0, 6)
+}
+
+func endOfMonth(today time.Time) time.Time {
+	return time.Date(today.Year(), today.Month()+1, 0, 0, 0, 0, 0, today.Location())
+}
+
+// parseClock accepts "14:00", "9:30", "9am" and "2:30pm" and returns "HH:MM".
+func parseClock(w string) (string, bool) {
+	var h, m int
+	if g := clock24.FindStringSubmatch(w); g != nil {
+		h, _ = strconv.Atoi(g[1])
+		m, _ = strconv.Atoi(g[2])
+	} else if g := clock12.FindStringSubmatch(w); g != nil {
+		h, _ = strconv.Atoi(g[1])
+		if g[2] != "" {
+			m, _ = strconv.Atoi(g[2])
+		}
+		if h < 1 || h > 12 {
+			return "", false
+		}
+		h %= 12
+		if g[3] == "pm" {
+			h += 12
+		}
+	} else {
+		return "", false
+	}
+	if h > 23 || m > 59 {
+		return "", false
+	}
+	return fmt.Sprintf("%02d:%02d", h, m), true
+}
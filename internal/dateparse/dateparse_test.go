@@ -0,0 +1,121 @@
+package dateparse
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+// Wednesday 4 February 2026, 10:00.
+var now = time.Date(2026, time.February, 4, 10, 0, 0, 0, time.UTC)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"today", "2026-02-04"},
+		{"tomorrow", "2026-02-05"},
+		{"tmrw", "2026-02-05"},
+		{"2026-03-15", "2026-03-15"},
+		{"friday", "2026-02-06"},
+		{"fri", "2026-02-06"},
+		{"wednesday", "2026-02-11"}, // same weekday means next week
+		{"this wed", "2026-02-04"},
+		{"next mon", "2026-02-09"},
+		{"next friday", "2026-02-13"},
+		{"next week", "2026-02-09"},
+		{"next month", "2026-03-01"},
+		{"+3d", "2026-02-07"},
+		{"+2w", "2026-02-18"},
+		{"+1m", "2026-03-04"},
+		{"in 2 weeks", "2026-02-18"},
+		{"in 1 day", "2026-02-05"},
+		{"in a month", "2026-03-04"},
+		{"eow", "2026-02-08"},
+		{"end of week", "2026-02-08"},
+		{"eom", "2026-02-28"},
+		{"end of month", "2026-02-28"},
+		{"feb 15", "2026-02-15"},
+		{"15 feb", "2026-02-15"},
+		{"February 15th", "2026-02-15"},
+		{"jan 10", "2027-01-10"}, // already passed this year
+		{"feb 15 2028", "2028-02-15"},
+		{"15/02", "2026-02-15"},
+		{"03/02", "2027-02-03"},
+		{"15/02/2027", "2027-02-15"},
+		{"tomorrow 14:00", "2026-02-05 14:00"},
+		{"friday at 9am", "2026-02-06 09:00"},
+		{"feb 15 2:30pm", "2026-02-15 14:30"},
+		{"at 15:00", "2026-02-04 15:00"},
+		{"at 9:00", "2026-02-05 09:00"}, // already past today
+		{"Tomorrow,", "2026-02-05"},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in, now)
+		if err != nil {
+			t.Errorf("Parse(%q) error: %v", tt.in, err)
+			continue
+		}
+		if got.String() != tt.want {
+			t.Errorf("Parse(%q) = %s, want %s", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseRejects(t *testing.T) {
+	for _, in := range []string{"", "someday", "31/02", "13/13", "feb 30", "25:00", "tomorrow lunch", "+3x", "in 2 fortnights"} {
+		if got, err := Parse(in, now); !errors.Is(err, ErrNoDate) {
+			t.Errorf("Parse(%q) = %v, %v; want ErrNoDate", in, got, err)
+		}
+	}
+}
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		in    string
+		want  string // "" when no date should be found
+		title string
+	}{
+		{"Buy groceries", "", "Buy groceries"},
+		{"Buy groceries tomorrow", "2026-02-05", "Buy groceries"},
+		{"Meeting today", "2026-02-04", "Meeting"},
+		{"Report 2026-02-15", "2026-02-15", "Report"},
+		{"Call mom friday about the trip", "2026-02-06", "Call mom about the trip"},
+		{"Pay rent by eom", "2026-02-28", "Pay rent"},
+		{"Dentist on next mon at 14:00", "2026-02-09 14:00", "Dentist"},
+		{"Standup prep tomorrow at 2pm", "2026-02-05 14:00", "Standup prep"},
+		{"Submit report in 2 weeks please", "2026-02-18", "Submit report please"},
+		{"Invoice 15/02", "2026-02-15", "Invoice"},
+		{"Buy sun cream", "", "Buy sun cream"},
+		{"Water plants sat", "2026-02-07", "Water plants"},
+		{"Water plants on sat morning", "2026-02-07", "Water plants morning"},
+		{"Call at 16:30", "2026-02-04 16:30", "Call"},
+		{"tomorrow", "2026-02-05", ""},
+	}
+	for _, tt := range tests {
+		got, rest, ok := Extract(tt.in, now)
+		if tt.want == "" {
+			if ok {
+				t.Errorf("Extract(%q) found %s, want none", tt.in, got)
+			}
+			if rest != tt.title {
+				t.Errorf("Extract(%q) rest = %q, want input unchanged", tt.in, rest)
+			}
+			continue
+		}
+		if !ok || got.String() != tt.want || rest != tt.title {
+			t.Errorf("Extract(%q) = %s, %q, %v; want %s, %q", tt.in, got, rest, ok, tt.want, tt.title)
+		}
+	}
+}
+
+func TestAddMonthsClamps(t *testing.T) {
+	jan31 := time.Date(2026, time.January, 31, 0, 0, 0, 0, time.UTC)
+	if got := AddMonths(jan31, 1).Format(Layout); got != "2026-02-28" {
+		t.Errorf("Jan 31 + 1 month = %s, want 2026-02-28", got)
+	}
+	if got := AddMonths(jan31, 13).Format(Layout); got != "2027-02-28" {
+		t.Errorf("Jan 31 + 13 months = %s, want 2027-02-28", got)
+	}
+}
@@ -0,0 +1,77 @@
+// Package labels parses the tags (#work) and project (+infra) written in
+// task text by the CLI and the Telegram bot.
+package labels
+
+import (
+	"regexp"
+	"strings"
+)
+
+var (
+	tagWord     = regexp.MustCompile(`^#(\pL[\pL\pN_/-]*)$`)
+	projectWord = regexp.MustCompile(`^(?:\+|project:)(\pL[\pL\pN_/-]*)$`)
+)
+
+// Extract removes every "#tag" and a "+project" (or "project:name") from
+// text and returns them lowercased. Tags are deduplicated in order of
+// appearance; when several projects are given the last one wins. Words like
+// "#5" or "+3d" that don't start with a letter are left in the text.
+func Extract(text string) (tags []string, project, rest string) {
+	var kept []string
+	for _, w := range strings.Fields(text) {
+		if m := tagWord.FindStringSubmatch(w); m != nil {
+			tags = appendTag(tags, m[1])
+			continue
+		}
+		if m := projectWord.FindStringSubmatch(w); m != nil {
+			project = strings.ToLower(m[1])
+			continue
+		}
+		kept = append(kept, w)
+	}
+	return tags, project, strings.Join(kept, " ")
+}
+
+// Inherit is Extract for the text of a subtask: the subtask keeps its
+// parent's tags, followed by any new ones, and its parent's project unless
+// text names another.
+func Inherit(text string, parentTags []string, parentProject string) (tags []string, project, rest string) {
+	extra, project, rest := Extract(text)
+	if project == "" {
+		project = parentProject
+	}
+	tags = append([]string(nil), parentTags...)
+	for _, t := range extra {
+		tags = appendTag(tags, t)
+	}
+	return tags, project, rest
+}
+
+// Tag normalizes a tag given on its own, e.g. as a --tag flag value, by
+// lowercasing it and dropping a leading #.
+func Tag(s string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(s), "#"))
+}
+
+// Format renders tags and project the way they are written, e.g.
+// "+infra #work #home", or "" when there are none.
+func Format(tags []string, project string) string {
+	var parts []string
+	if project != "" {
+		parts = append(parts, "+"+project)
+	}
+	for _, t := range tags {
+		parts = append(parts, "#"+t)
+	}
+	return strings.Join(parts, " ")
+}
+
+func appendTag(tags []string, tag string) []string {
+	tag = strings.ToLower(tag)
+	for _, t := range tags {
+		if t == tag {
+			return tags
+		}
+	}
+	return append(tags, tag)
+}
@@ -0,0 +1,47 @@
+package labels
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		in, tags, project, rest string
+	}{
+		{"Buy milk", "", "", "Buy milk"},
+		{"Deploy #work #Urgent +infra friday", "work,urgent", "infra", "Deploy friday"},
+		{"#home Fix sink #home", "home", "", "Fix sink"},
+		{"Plan project:Q3-launch +3d", "", "q3-launch", "Plan +3d"},
+		{"Close issue #123", "", "", "Close issue #123"},
+		{"Review +ops +infra", "", "infra", "Review"},
+	}
+	for _, tt := range tests {
+		tags, project, rest := Extract(tt.in)
+		if strings.Join(tags, ",") != tt.tags || project != tt.project || rest != tt.rest {
+			t.Errorf("Extract(%q) = %v, %q, %q; want %s, %q, %q", tt.in, tags, project, rest, tt.tags, tt.project, tt.rest)
+		}
+	}
+}
+
+func TestInherit(t *testing.T) {
+	tags, project, rest := Inherit("Buy milk #home #errand", []string{"errand", "weekly"}, "house")
+	if strings.Join(tags, ",") != "errand,weekly,home" || project != "house" || rest != "Buy milk" {
+		t.Errorf("Inherit = %v, %q, %q", tags, project, rest)
+	}
+	if _, project, _ := Inherit("Fix CI +infra", nil, "house"); project != "infra" {
+		t.Errorf("Inherit project = %q, want infra", project)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	if got := Format([]string{"work", "home"}, "infra"); got != "+infra #work #home" {
+		t.Errorf("Format = %q", got)
+	}
+	if got := Format(nil, ""); got != "" {
+		t.Errorf("Format(empty) = %q", got)
+	}
+	if got := Tag(" #Work"); got != "work" {
+		t.Errorf("Tag = %q", got)
+	}
+}
@@ -0,0 +1,126 @@
+// Package notes handles the markdown notes and links attached to a task in
+// their two plain-text forms: the file `todo note` opens in an editor, and
+// the indented block obsidian-sync writes under a task's checkbox line.
+package notes
+
+import (
+	"errors"
+	"slices"
+	"strings"
+)
+
+// Marker separates the notes from the links in the editor file.
+const Marker = "<!-- Links below, one per line. Everything above is the task's notes (markdown). -->"
+
+// linkPrefix starts a link line in an Obsidian block.
+const linkPrefix = "🔗 "
+
+// ErrNoMarker is returned by Parse when the marker line was removed.
+var ErrNoMarker = errors.New("the links marker line is missing")
+
+// Render returns the editor file for notes and links.
+func Render(notes string, links []string) string {
+	var sb strings.Builder
+	if notes != "" {
+		sb.WriteString(notes + "\n")
+	}
+	sb.WriteString("\n" + Marker + "\n")
+	for _, l := range links {
+		sb.WriteString(l + "\n")
+	}
+	return sb.String()
+}
+
+// Parse reads an editor file written by Render.
+func Parse(text string) (string, []string, error) {
+	above, below, ok := strings.Cut(text, Marker)
+	if !ok {
+		return "", nil, ErrNoMarker
+	}
+	var links []string
+	for _, line := range strings.Split(below, "\n") {
+		links = addLink(links, strings.TrimPrefix(strings.TrimSpace(line), "- "))
+	}
+	return clean(above), links, nil
+}
+
+// Block renders notes and links as lines indented under a list item, links
+// last, each starting with 🔗. It returns "" when there is neither.
+func Block(notes string, links []string) string {
+	var sb strings.Builder
+	if notes != "" {
+		for _, line := range strings.Split(notes, "\n") {
+			if strings.TrimSpace(line) == "" {
+				sb.WriteString("\n")
+			} else {
+				sb.WriteString("    " + line + "\n")
+			}
+		}
+	}
+	for _, l := range links {
+		sb.WriteString("    " + linkPrefix + l + "\n")
+	}
+	return sb.String()
+}
+
+// ParseBlock reads the lines of a block written by Block, after the
+// checkbox line. One level of indentation (a tab or up to four spaces) is
+// removed from each line.
+func ParseBlock(lines []string) (string, []string) {
+	var body []string
+	var links []string
+	for _, line := range lines {
+		line = dedent(line)
+		if l, ok := strings.CutPrefix(strings.TrimSpace(line), linkPrefix); ok {
+			links = addLink(links, strings.TrimSpace(l))
+			continue
+		}
+		body = append(body, line)
+	}
+	return clean(strings.Join(body, "\n")), links
+}
+
+// Add appends text to a task's notes as a new paragraph, or to its links
+// when text is a single URL.
+func Add(notes string, links []string, text string) (string, []string) {
+	text = strings.TrimSpace(text)
+	if IsLink(text) {
+		return notes, addLink(slices.Clone(links), text)
+	}
+	if notes == "" {
+		return clean(text), links
+	}
+	return clean(notes + "\n\n" + text), links
+}
+
+// IsLink reports whether s is a single http(s) URL.
+func IsLink(s string) bool {
+	return (strings.HasPrefix(s, "https://") || strings.HasPrefix(s, "http://")) && !strings.ContainsAny(s, " \t\n")
+}
+
+func addLink(links []string, l string) []string {
+	if l == "" || slices.Contains(links, l) {
+		return links
+	}
+	return append(links, l)
+}
+
+// clean trims blank lines around notes and trailing spaces from each line.
+func clean(notes string) string {
+	lines := strings.Split(notes, "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight(line, " \t\r")
+	}
+	return strings.Trim(strings.Join(lines, "\n"), "\n")
+}
+
+func dedent(line string) string {
+	if strings.HasPrefix(line, "\t") {
+		return line[1:]
+	}
+	n := 0
+	for n < 4 && n < len(line) && line[n] == ' ' {
+		n++
+	}
+	return line[n:]
+}
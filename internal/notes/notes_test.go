@@ -0,0 +1,57 @@
+package notes
+
+import (
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+)
+
+const sample = "Call **before** noon.\n\n- ask about the invoice\n      indented code"
+
+var sampleLinks = []string{"https://example.com/a", "https://example.com/b"}
+
+func TestRenderParse(t *testing.T) {
+	notes, links, err := Parse(Render(sample, sampleLinks))
+	if err != nil || notes != sample || !slices.Equal(links, sampleLinks) {
+		t.Errorf("round trip = %q %v %v", notes, links, err)
+	}
+
+	notes, links, err = Parse("  New notes  \n\n" + Marker + "\n- https://x.test\n\nhttps://x.test\n")
+	if err != nil || notes != "  New notes" || !slices.Equal(links, []string{"https://x.test"}) {
+		t.Errorf("Parse = %q %v %v", notes, links, err)
+	}
+
+	if _, _, err := Parse("just notes"); !errors.Is(err, ErrNoMarker) {
+		t.Errorf("Parse without marker err = %v", err)
+	}
+}
+
+func TestBlock(t *testing.T) {
+	block := Block(sample, sampleLinks)
+	if !strings.HasPrefix(block, "    Call **before** noon.\n\n    - ask") || !strings.HasSuffix(block, "    🔗 https://example.com/b\n") {
+		t.Errorf("Block =\n%s", block)
+	}
+	lines := strings.Split(strings.TrimSuffix(block, "\n"), "\n")
+	if notes, links := ParseBlock(lines); notes != sample || !slices.Equal(links, sampleLinks) {
+		t.Errorf("ParseBlock = %q %v", notes, links)
+	}
+	if Block("", nil) != "" {
+		t.Error("empty block is not empty")
+	}
+	if notes, links := ParseBlock([]string{"\tfrom a tab", "", ""}); notes != "from a tab" || links != nil {
+		t.Errorf("ParseBlock(tab) = %q %v", notes, links)
+	}
+}
+
+func TestAdd(t *testing.T) {
+	notes, links := Add("", nil, "First thought")
+	notes, links = Add(notes, links, "https://example.com")
+	notes, links = Add(notes, links, " Second thought ")
+	if notes != "First thought\n\nSecond thought" || !slices.Equal(links, []string{"https://example.com"}) {
+		t.Errorf("Add = %q %v", notes, links)
+	}
+	if _, links := Add(notes, links, "https://example.com"); len(links) != 1 {
+		t.Errorf("duplicate link added: %v", links)
+	}
+}
@@ -0,0 +1,292 @@
+// Package query narrows a task filter from `todo list` flags and from a
+// small expression language such as "priority<=P1 and due<+7d".
+//
+// An expression is one or more conditions joined by "and". Each condition
+// is a field, an operator and a value:
+//
+//	priority (p)   = != < <= > >=   P0-P4; "=" also takes P0..P2 or P0,P1
+//	due            = < <= > >=      any date dateparse understands
+//	status         = !=             Todo, Done or all
+//	parent         =                task ID
+//	tag            =                tag name, with or without #
+//	project        =                project name
+//	title          ~                case-insensitive substring
+//
+// Conditions only ever narrow the filter, so combining an expression with
+// flags means "all of them".
+package query
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"todo-tracker/internal/dateparse"
+	"todo-tracker/internal/labels"
+	"todo-tracker/internal/supabase"
+)
+
+// ErrSyntax is wrapped by every error about a malformed expression.
+var ErrSyntax = errors.New("invalid query")
+
+// Priorities lists the priorities from highest to lowest.
+var Priorities = []string{"P0", "P1", "P2", "P3", "P4"}
+
+var (
+	andWord   = regexp.MustCompile(`(?i)\s+and\s+`)
+	condition = regexp.MustCompile(`^\s*([A-Za-z_]+)\s*(<=|>=|!=|=|<|>|~)\s*(.+?)\s*$`)
+)
+
+// Parse applies every condition in expr to f relative to now.
+func Parse(f *supabase.Filter, expr string, now time.Time) error {
+	if strings.TrimSpace(expr) == "" {
+		return nil
+	}
+	for _, c := range andWord.Split(strings.TrimSpace(expr), -1) {
+		m := condition.FindStringSubmatch(c)
+		if m == nil {
+			return fmt.Errorf("%w: %q is not a condition like priority<=P1", ErrSyntax, c)
+		}
+		if err := Apply(f, m[1], m[2], strings.Trim(m[3], `"'`), now); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Apply narrows f with a single condition.
+func Apply(f *supabase.Filter, field, op, value string, now time.Time) error {
+	switch strings.ToLower(field) {
+	case "priority", "p":
+		return applyPriority(f, op, value)
+	case "due", "due_date":
+		return applyDue(f, op, value, now)
+	case "status":
+		return applyStatus(f, op, value)
+	case "parent", "parent_id":
+		if op != "=" {
+			return unsupported(field, op)
+		}
+		id, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("%w: parent must be a task ID, got %q", ErrSyntax, value)
+		}
+		f.ParentID = &id
+	case "tag", "tags":
+		if op != "=" {
+			return unsupported(field, op)
+		}
+		if tag := labels.Tag(value); !slices.Contains(f.Tags, tag) {
+			f.Tags = append(f.Tags, tag)
+		}
+	case "project":
+		if op != "=" {
+			return unsupported(field, op)
+		}
+		f.Project = strings.ToLower(strings.TrimPrefix(value, "+"))
+	case "title", "search":
+		if op != "~" && op != "=" {
+			return unsupported(field, op)
+		}
+		f.Search = value
+	default:
+		return fmt.Errorf("%w: unknown field %q", ErrSyntax, field)
+	}
+	return nil
+}
+
+func unsupported(field, op string) error {
+	return fmt.Errorf("%w: %s does not support %s", ErrSyntax, field, op)
+}
+
+func applyPriority(f *supabase.Filter, op, value string) error {
+	if op == "~" {
+		return unsupported("priority", op)
+	}
+	var allowed []string
+	if op == "=" {
+		set, err := prioritySet(value)
+		if err != nil {
+			return err
+		}
+		allowed = set
+	} else {
+		i := slices.Index(Priorities, strings.ToUpper(value))
+		if i < 0 {
+			return fmt.Errorf("%w: priority must be P0-P4, got %q", ErrSyntax, value)
+		}
+		for j, p := range Priorities {
+			// Lower numbers are higher priorities, but comparisons read
+			// as numbers: priority<=P1 means P0 or P1.
+			if (op == "<" && j < i) || (op == "<=" && j <= i) || (op == ">" && j > i) ||
+				(op == ">=" && j >= i) || (op == "!=" && j != i) {
+				allowed = append(allowed, p)
+			}
+		}
+	}
+
+	if len(f.Priorities) > 0 {
+		allowed = slices.DeleteFunc(allowed, func(p string) bool { return !slices.Contains(f.Priorities, p) })
+	}
+	if len(allowed) == 0 {
+		allowed = []string{"none"} // contradictory conditions match nothing
+	}
+	f.Priorities = allowed
+	return nil
+}
+
+// prioritySet parses "P1", "P0,P2" or the range "P0..P2".
+func prioritySet(value string) ([]string, error) {
+	value = strings.ToUpper(strings.ReplaceAll(value, " ", ""))
+	if lo, hi, ok := strings.Cut(value, ".."); ok {
+		i, j := slices.Index(Priorities, lo), slices.Index(Priorities, hi)
+		if i < 0 || j < 0 || i > j {
+			return nil, fmt.Errorf("%w: bad priority range %q", ErrSyntax, value)
+		}
+		return slices.Clone(Priorities[i : j+1]), nil
+	}
+	var set []string
+	for _, p := range strings.Split(value, ",") {
+		if !slices.Contains(Priorities, p) {
+			return nil, fmt.Errorf("%w: priority must be P0-P4, got %q", ErrSyntax, p)
+		}
+		if !slices.Contains(set, p) {
+			set = append(set, p)
+		}
+	}
+	return set, nil
+}
+
+func applyDue(f *supabase.Filter, op, value string, now time.Time) error {
+	d, err := dateparse.Parse(value, now)
+	if err != nil {
+		return fmt.Errorf("%w: %v", ErrSyntax, err)
+	}
+	day := d.Date
+	switch op {
+	case "=":
+		narrowFrom(f, day)
+		narrowTo(f, day)
+	case "<":
+		narrowTo(f, day.AddDate(0, 0, -1))
+	case "<=":
+		narrowTo(f, day)
+	case ">":
+		narrowFrom(f, day.AddDate(0, 0, 1))
+	case ">=":
+		narrowFrom(f, day)
+	default:
+		return unsupported("due", op)
+	}
+	return nil
+}
+
+func narrowFrom(f *supabase.Filter, d time.Time) {
+	if s := d.Format(dateparse.Layout); f.DueFrom == "" || s > f.DueFrom {
+		f.DueFrom = s
+	}
+}
+
+func narrowTo(f *supabase.Filter, d time.Time) {
+	if s := d.Format(dateparse.Layout); f.DueTo == "" || s < f.DueTo {
+		f.DueTo = s
+	}
+}
+
+func applyStatus(f *supabase.Filter, op, value string) error {
+	var status string
+	switch strings.ToLower(value) {
+	case "todo", "open":
+		status = "Todo"
+	case "done":
+		status = "Done"
+	case "all", "any":
+		if op != "=" {
+			return unsupported("status", op)
+		}
+		f.Status = ""
+		return nil
+	default:
+		return fmt.Errorf("%w: status must be Todo, Done or all, got %q", ErrSyntax, value)
+	}
+	switch op {
+	case "=":
+		f.Status = status
+	case "!=":
+		f.Status = map[string]string{"Todo": "Done", "Done": "Todo"}[status]
+	default:
+		return unsupported("status", op)
+	}
+	return nil
+}
+
+// IDs parses task IDs given as separate words, comma lists and inclusive
+// ranges, e.g. "3", "5,8" and "10-14". Duplicates are dropped.
+func IDs(args []string) ([]int, error) {
+	var ids []int
+	add := func(id int) {
+		if !slices.Contains(ids, id) {
+			ids = append(ids, id)
+		}
+	}
+	for _, arg := range args {
+		for _, part := range strings.Split(arg, ",") {
+			if part == "" {
+				continue
+			}
+			lo, hi, isRange := strings.Cut(part, "-")
+			first, err := strconv.Atoi(lo)
+			if err != nil || first < 1 || lo[0] == '+' {
+				return nil, fmt.Errorf("%w: %q is not a task ID", ErrSyntax, part)
+			}
+			if !isRange {
+				add(first)
+				continue
+			}
+			last, err := strconv.Atoi(hi)
+			if err != nil || last < first || hi[0] == '+' {
+				return nil, fmt.Errorf("%w: %q is not a range like 10-14", ErrSyntax, part)
+			}
+			if last-first >= maxRange {
+				return nil, fmt.Errorf("%w: range %q is longer than %d tasks", ErrSyntax, part, maxRange)
+			}
+			for id := first; id <= last; id++ {
+				add(id)
+			}
+		}
+	}
+	return ids, nil
+}
+
+// maxRange bounds an ID range so a typo cannot expand into a huge request.
+const maxRange = 500
+
+// sortColumns maps the names accepted by --sort to table columns.
+var sortColumns = map[string]string{
+	"id": "id", "title": "title", "due": "due_date", "due_date": "due_date",
+	"priority": "priority", "status": "status", "parent": "parent_id",
+	"created": "created_at", "created_at": "created_at", "project": "project",
+}
+
+// Sort turns a --sort value such as "due,-priority" into PostgREST order
+// terms. A leading "-" sorts that field descending.
+func Sort(spec string) ([]string, error) {
+	var order []string
+	for _, field := range strings.Split(spec, ",") {
+		field = strings.TrimSpace(field)
+		dir := "asc"
+		if strings.HasPrefix(field, "-") {
+			field, dir = field[1:], "desc"
+		}
+		col, ok := sortColumns[strings.ToLower(field)]
+		if !ok {
+			return nil, fmt.Errorf("%w: cannot sort by %q", ErrSyntax, field)
+		}
+		order = append(order, col+"."+dir)
+	}
+	return order, nil
+}
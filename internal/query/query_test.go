@@ -0,0 +1,114 @@
+package query
+
+import (
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	"todo-tracker/internal/supabase"
+)
+
+// Wednesday 4 February 2026, 10:00.
+var now = time.Date(2026, time.February, 4, 10, 0, 0, 0, time.UTC)
+
+func TestParse(t *testing.T) {
+	parent := 7
+	tests := []struct {
+		expr string
+		want supabase.Filter
+	}{
+		{"priority<=P1", supabase.Filter{Priorities: []string{"P0", "P1"}}},
+		{"p>P2", supabase.Filter{Priorities: []string{"P3", "P4"}}},
+		{"priority=P0..P2", supabase.Filter{Priorities: []string{"P0", "P1", "P2"}}},
+		{"priority = P1,P3", supabase.Filter{Priorities: []string{"P1", "P3"}}},
+		{"priority<=P2 and priority>=P1", supabase.Filter{Priorities: []string{"P1", "P2"}}},
+		{"due<+7d", supabase.Filter{DueTo: "2026-02-10"}},
+		{"due<=friday", supabase.Filter{DueTo: "2026-02-06"}},
+		{"due>today and due<=eom", supabase.Filter{DueFrom: "2026-02-05", DueTo: "2026-02-28"}},
+		{"due=tomorrow", supabase.Filter{DueFrom: "2026-02-05", DueTo: "2026-02-05"}},
+		{"priority<=P1 AND due<+7d", supabase.Filter{Priorities: []string{"P0", "P1"}, DueTo: "2026-02-10"}},
+		{"status=done", supabase.Filter{Status: "Done"}},
+		{"status!=done", supabase.Filter{Status: "Todo"}},
+		{"parent=7", supabase.Filter{ParentID: &parent}},
+		{"tag=#Work and project=infra", supabase.Filter{Tags: []string{"work"}, Project: "infra"}},
+		{`title~"quarterly report"`, supabase.Filter{Search: "quarterly report"}},
+	}
+	for _, tt := range tests {
+		f := supabase.Filter{}
+		if err := Parse(&f, tt.expr, now); err != nil {
+			t.Errorf("Parse(%q): %v", tt.expr, err)
+			continue
+		}
+		if !reflect.DeepEqual(f, tt.want) {
+			t.Errorf("Parse(%q) = %+v, want %+v", tt.expr, f, tt.want)
+		}
+	}
+}
+
+func TestParseNarrowsExistingFilter(t *testing.T) {
+	f := supabase.Filter{Status: "Todo", DueTo: "2026-02-08", Priorities: []string{"P0", "P1", "P2"}}
+	if err := Parse(&f, "due<=eom and priority>=P1", now); err != nil {
+		t.Fatal(err)
+	}
+	if f.DueTo != "2026-02-08" || !reflect.DeepEqual(f.Priorities, []string{"P1", "P2"}) || f.Status != "Todo" {
+		t.Errorf("filter = %+v", f)
+	}
+
+	if err := Parse(&f, "priority=P4", now); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(f.Priorities, []string{"none"}) {
+		t.Errorf("contradictory priorities = %v, want none", f.Priorities)
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	for _, expr := range []string{
+		"priority",
+		"size>3",
+		"priority<=P7",
+		"priority=P3..P1",
+		"due<someday",
+		"due!=today",
+		"status=maybe",
+		"parent>3",
+		"parent=abc",
+		"priority~P1",
+	} {
+		f := supabase.Filter{}
+		if err := Parse(&f, expr, now); !errors.Is(err, ErrSyntax) {
+			t.Errorf("Parse(%q) err = %v, want ErrSyntax", expr, err)
+		}
+	}
+}
+
+func TestSort(t *testing.T) {
+	got, err := Sort("due, -priority,created")
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"due_date.asc", "priority.desc", "created_at.asc"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Sort = %v, want %v", got, want)
+	}
+	if _, err := Sort("size"); !errors.Is(err, ErrSyntax) {
+		t.Errorf("Sort(size) err = %v", err)
+	}
+}
+
+func TestIDs(t *testing.T) {
+	ids, err := IDs([]string{"3", "5,8", "10-12", "8"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(ids) != "[3 5 8 10 11 12]" {
+		t.Errorf("IDs = %v", ids)
+	}
+	for _, bad := range []string{"x", "0", "5-3", "1-", "-4", "1-100000", "+3", "1-+4"} {
+		if _, err := IDs([]string{bad}); !errors.Is(err, ErrSyntax) {
+			t.Errorf("IDs(%q) err = %v, want ErrSyntax", bad, err)
+		}
+	}
+}
@@ -0,0 +1,118 @@
+package recurrence
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"todo-tracker/internal/dateparse"
+	"todo-tracker/internal/supabase"
+)
+
+// Store is the part of a task store Advance needs.
+type Store interface {
+	List(ctx context.Context, f supabase.Filter) ([]supabase.Task, error)
+	Create(ctx context.Context, t supabase.Task) (*supabase.Task, error)
+}
+
+// Advance creates the occurrence that follows the completed recurring task
+// t, due on the next date of its rule after both its due date and now, and
+// copies t's subtasks under it as open tasks shifted by the same number of
+// days. Notes and links carry over to the new tasks. It returns nil when t does not repeat. If a matching open
+// occurrence already exists (the task was completed twice), that one is
+// returned and nothing is created.
+func Advance(ctx context.Context, s Store, t supabase.Task, now time.Time) (*supabase.Task, error) {
+	if t.Recurrence == "" {
+		return nil, nil
+	}
+	rule, err := Parse(t.Recurrence)
+	if err != nil {
+		return nil, fmt.Errorf("task %d: %w", t.ID, err)
+	}
+
+	prev := date(now)
+	if d, err := time.ParseInLocation(dateparse.Layout, t.DueDate, now.Location()); err == nil {
+		prev = d
+	}
+	due := rule.NextAfter(prev, now)
+	dueDate := due.Format(dateparse.Layout)
+
+	existing, err := s.List(ctx, supabase.Filter{Status: "Todo", DueFrom: dueDate, DueTo: dueDate})
+	if err != nil {
+		return nil, err
+	}
+	for _, e := range existing {
+		if e.Title == t.Title && e.Recurrence == t.Recurrence && sameParent(e.ParentID, t.ParentID) {
+			return &e, nil
+		}
+	}
+
+	next, err := s.Create(ctx, supabase.Task{
+		Title:      t.Title,
+		DueDate:    dueDate,
+		DueTime:    t.DueTime,
+		Priority:   t.Priority,
+		Status:     "Todo",
+		ParentID:   t.ParentID,
+		UserID:     t.UserID,
+		Recurrence: t.Recurrence,
+		Tags:       t.Tags,
+		Project:    t.Project,
+		Notes:      t.Notes,
+		Links:      t.Links,
+	})
+	if err != nil {
+		return nil, err
+	}
+	if err := copySubtasks(ctx, s, t.ID, next.ID, days(prev, due), now.Location()); err != nil {
+		return next, err
+	}
+	return next, nil
+}
+
+// copySubtasks recreates the subtree under from as open tasks under to.
+func copySubtasks(ctx context.Context, s Store, from, to, shift int, loc *time.Location) error {
+	children, err := s.List(ctx, supabase.Filter{ParentID: &from, Order: []string{"id"}})
+	if err != nil {
+		return err
+	}
+	for _, c := range children {
+		due := c.DueDate
+		if d, err := time.ParseInLocation(dateparse.Layout, c.DueDate, loc); err == nil {
+			due = d.AddDate(0, 0, shift).Format(dateparse.Layout)
+		}
+		parent := to
+		copied, err := s.Create(ctx, supabase.Task{
+			Title:    c.Title,
+			DueDate:  due,
+			DueTime:  c.DueTime,
+			Priority: c.Priority,
+			Status:   "Todo",
+			ParentID: &parent,
+			UserID:   c.UserID,
+			Tags:     c.Tags,
+			Project:  c.Project,
+			Notes:    c.Notes,
+			Links:    c.Links,
+		})
+		if err != nil {
+			return err
+		}
+		if err := copySubtasks(ctx, s, c.ID, copied.ID, shift, loc); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// days counts calendar days from a to b, ignoring DST changes.
+func days(a, b time.Time) int {
+	return int(b.Sub(a).Hours()/24 + 0.5)
+}
+
+func sameParent(a, b *int) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	return *a == *b
+}
@@ -0,0 +1,511 @@
+// Package recurrence parses repeat rules for recurring tasks and computes
+// their next occurrence.
+//
+// Rules are written the way people say them ("every mon", "every 2 weeks",
+// "monthly on 1st", "every weekday") and stored in the tasks table in a
+// subset of RFC 5545 RRULE syntax ("FREQ=WEEKLY;BYDAY=MO").
+package recurrence
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"todo-tracker/internal/dateparse"
+)
+
+// Freq is how often a rule repeats.
+type Freq int
+
+const (
+	Daily Freq = iota + 1
+	Weekly
+	Monthly
+	Yearly
+)
+
+var freqNames = map[Freq]string{Daily: "DAILY", Weekly: "WEEKLY", Monthly: "MONTHLY", Yearly: "YEARLY"}
+
+// LastDay as a MonthDay means the last day of the month.
+const LastDay = -1
+
+// Rule is a parsed repeat rule.
+type Rule struct {
+	Freq     Freq
+	Interval int            // repeat every Interval days/weeks/months/years; at least 1
+	Weekdays []time.Weekday // weekly rules only; empty means the due date's weekday
+	MonthDay int            // monthly rules only; 1-31 or LastDay, 0 means the due date's day
+
+	// At is a time of day written with the rule ("every mon 9am"), as
+	// HH:MM. It belongs in the task's due_time and is not part of String.
+	At string
+}
+
+// ErrNoRule is returned by Parse when the input is not a repeat rule.
+var ErrNoRule = errors.New("not a recognised repeat rule")
+
+// Parse reads a rule written either in words ("every 2 weeks on mon") or
+// as an RRULE ("FREQ=WEEKLY;INTERVAL=2;BYDAY=MO").
+func Parse(s string) (Rule, error) {
+	s = strings.TrimSpace(s)
+	upper := strings.ToUpper(s)
+	if strings.HasPrefix(upper, "RRULE:") || strings.HasPrefix(upper, "FREQ=") {
+		return parseRRule(strings.TrimPrefix(upper, "RRULE:"))
+	}
+	words := normalize(strings.Fields(s))
+	r, n, ok := match(words)
+	if !ok || n != len(words) {
+		return Rule{}, fmt.Errorf("%w: %q", ErrNoRule, s)
+	}
+	return r, nil
+}
+
+// Extract finds a repeat rule anywhere in text and returns it together with
+// text with the rule removed. Rules starting with "every" may appear
+// anywhere; bare "daily", "weekly", "monthly" and "yearly" only count at the
+// end of the text so that titles like "Weekly review every fri" keep their
+// first word.
+func Extract(text string) (r Rule, rest string, ok bool) {
+	words := strings.Fields(text)
+	norm := normalize(words)
+	for i := range norm {
+		r, n, ok := match(norm[i:])
+		if !ok {
+			continue
+		}
+		n += r.matchAt(norm[i+n:])
+		if norm[i] != "every" && i+n != len(norm) {
+			continue
+		}
+		if i+n == len(norm) && i == 0 {
+			continue // the whole text is the rule; keep it as the title
+		}
+		rest := append(append([]string{}, words[:i]...), words[i+n:]...)
+		return r, strings.Join(rest, " "), true
+	}
+	return Rule{}, text, false
+}
+
+// String returns the rule in RRULE syntax, as stored in the tasks table.
+func (r Rule) String() string {
+	parts := []string{"FREQ=" + freqNames[r.Freq]}
+	if r.Interval > 1 {
+		parts = append(parts, "INTERVAL="+strconv.Itoa(r.Interval))
+	}
+	if len(r.Weekdays) > 0 {
+		days := make([]string, len(r.Weekdays))
+		for i, d := range r.Weekdays {
+			days[i] = rruleDays[d]
+		}
+		parts = append(parts, "BYDAY="+strings.Join(days, ","))
+	}
+	if r.MonthDay != 0 {
+		parts = append(parts, "BYMONTHDAY="+strconv.Itoa(r.MonthDay))
+	}
+	return strings.Join(parts, ";")
+}
+
+// Describe returns the rule in words, e.g. "every 2 weeks on Mon, Thu".
+func (r Rule) Describe() string {
+	if r.Freq == Weekly && r.Interval <= 1 {
+		switch {
+		case sameDays(r.Weekdays, workweek):
+			return "every weekday"
+		case sameDays(r.Weekdays, weekend):
+			return "every weekend"
+		case len(r.Weekdays) > 0:
+			return "every " + dayList(r.Weekdays)
+		}
+	}
+
+	unit := map[Freq]string{Daily: "day", Weekly: "week", Monthly: "month", Yearly: "year"}[r.Freq]
+	s := "every " + unit
+	if r.Interval > 1 {
+		s = fmt.Sprintf("every %d %ss", r.Interval, unit)
+	}
+	switch {
+	case len(r.Weekdays) > 0:
+		s += " on " + dayList(r.Weekdays)
+	case r.MonthDay == LastDay:
+		s += " on the last day"
+	case r.MonthDay > 0:
+		s += " on the " + ordinal(r.MonthDay)
+	}
+	return s
+}
+
+// Describe returns the stored rule rrule in words, or rrule itself when it
+// cannot be parsed.
+func Describe(rrule string) string {
+	r, err := Parse(rrule)
+	if err != nil {
+		return rrule
+	}
+	return r.Describe()
+}
+
+// First returns the first occurrence on or after from.
+func (r Rule) First(from time.Time) time.Time {
+	d := date(from)
+	for i := 0; i < 366 && !r.matches(d); i++ {
+		d = d.AddDate(0, 0, 1)
+	}
+	return d
+}
+
+// Next returns the occurrence following prev, which is assumed to be an
+// occurrence itself (or the first due date of the task).
+func (r Rule) Next(prev time.Time) time.Time {
+	prev = date(prev)
+	n := max(r.Interval, 1)
+	switch r.Freq {
+	case Daily:
+		return prev.AddDate(0, 0, n)
+	case Weekly:
+		if len(r.Weekdays) == 0 {
+			return prev.AddDate(0, 0, 7*n)
+		}
+		// Later in the same Monday-based week, else the first matching
+		// day n weeks on.
+		for d := prev.AddDate(0, 0, 1); d.Weekday() != time.Monday; d = d.AddDate(0, 0, 1) {
+			if r.matches(d) {
+				return d
+			}
+		}
+		monday := prev.AddDate(0, 0, -daysFromMonday(prev.Weekday())+7*n)
+		return r.First(monday)
+	case Monthly:
+		if r.MonthDay == 0 {
+			return dateparse.AddMonths(prev, n)
+		}
+		if d := r.inMonth(prev); d.After(prev) {
+			return d
+		}
+		return r.inMonth(dateparse.AddMonths(firstOfMonth(prev), n))
+	case Yearly:
+		return dateparse.AddMonths(prev, 12*n)
+	}
+	return prev
+}
+
+// NextAfter returns the first occurrence following prev that is also after
+// today, so completing an overdue task schedules it in the future rather
+// than creating a backlog of missed occurrences.
+func (r Rule) NextAfter(prev, today time.Time) time.Time {
+	today = date(today)
+	d := r.Next(prev)
+	for i := 0; i < 10000 && !d.After(today); i++ {
+		d = r.Next(d)
+	}
+	return d
+}
+
+func (r Rule) matches(d time.Time) bool {
+	switch r.Freq {
+	case Weekly:
+		return len(r.Weekdays) == 0 || containsDay(r.Weekdays, d.Weekday())
+	case Monthly:
+		return r.MonthDay == 0 || d.Equal(r.inMonth(d))
+	}
+	return true
+}
+
+// inMonth returns the rule's day in d's month, clamped to the month's end.
+func (r Rule) inMonth(d time.Time) time.Time {
+	last := firstOfMonth(d).AddDate(0, 1, -1)
+	if r.MonthDay == LastDay || r.MonthDay > last.Day() {
+		return last
+	}
+	return firstOfMonth(d).AddDate(0, 0, r.MonthDay-1)
+}
+
+var (
+	numberWord  = regexp.MustCompile(`^\d+$`)
+	ordinalWord = regexp.MustCompile(`^(\d{1,2})(?:st|nd|rd|th)?$`)
+)
+
+var (
+	workweek = []time.Weekday{time.Monday, time.Tuesday, time.Wednesday, time.Thursday, time.Friday}
+	weekend  = []time.Weekday{time.Saturday, time.Sunday}
+)
+
+var adverbs = map[string]Freq{
+	"daily": Daily, "weekly": Weekly, "monthly": Monthly, "yearly": Yearly, "annually": Yearly,
+}
+
+var units = map[string]Freq{
+	"day": Daily, "days": Daily, "week": Weekly, "weeks": Weekly,
+	"month": Monthly, "months": Monthly, "year": Yearly, "years": Yearly,
+}
+
+// match parses the longest rule at the start of words and returns how many
+// words it consumed.
+func match(words []string) (Rule, int, bool) {
+	if len(words) == 0 {
+		return Rule{}, 0, false
+	}
+	if f, ok := adverbs[words[0]]; ok {
+		r := Rule{Freq: f, Interval: 1}
+		return r, 1 + r.matchOn(words[1:]), true
+	}
+	if words[0] != "every" || len(words) < 2 {
+		return Rule{}, 0, false
+	}
+
+	n := 1
+	r := Rule{Interval: 1}
+	switch w := words[1]; {
+	case w == "weekday" || w == "weekdays":
+		r.Freq, r.Weekdays = Weekly, workweek
+		return r, 2, true
+	case w == "weekend" || w == "weekends":
+		r.Freq, r.Weekdays = Weekly, weekend
+		return r, 2, true
+	case w == "other":
+		r.Interval = 2
+		n++
+	case numberWord.MatchString(w):
+		r.Interval, _ = strconv.Atoi(w)
+		if r.Interval < 1 {
+			return Rule{}, 0, false
+		}
+		n++
+	}
+	if n < len(words) {
+		if f, ok := units[words[n]]; ok {
+			r.Freq = f
+			return r, n + 1 + r.matchOn(words[n+1:]), true
+		}
+	}
+
+	// "every mon", "every mon and thu", "every other fri"
+	days, m := dayListAt(words[n:])
+	if m == 0 {
+		return Rule{}, 0, false
+	}
+	r.Freq, r.Weekdays = Weekly, days
+	return r, n + m, true
+}
+
+// matchAt parses an optional "[at] 9am" following the rule into r.At and
+// returns how many words it consumed.
+func (r *Rule) matchAt(words []string) int {
+	n := 0
+	if len(words) > 1 && words[0] == "at" {
+		n++
+	}
+	if n < len(words) {
+		if t, ok := dateparse.Clock(words[n]); ok {
+			r.At = t
+			return n + 1
+		}
+	}
+	return 0
+}
+
+// matchOn parses an optional "on mon, wed" (weekly) or "on [the] 1st|last
+// [day]" (monthly) suffix into r and returns how many words it consumed.
+func (r *Rule) matchOn(words []string) int {
+	if len(words) < 2 || words[0] != "on" {
+		return 0
+	}
+	switch r.Freq {
+	case Weekly:
+		days, n := dayListAt(words[1:])
+		if n == 0 {
+			return 0
+		}
+		r.Weekdays = days
+		return 1 + n
+	case Monthly:
+		n := 1
+		if words[n] == "the" {
+			n++
+		}
+		if n >= len(words) {
+			return 0
+		}
+		if words[n] == "last" {
+			r.MonthDay = LastDay
+			n++
+			if n < len(words) && words[n] == "day" {
+				n++
+			}
+			return n
+		}
+		if m := ordinalWord.FindStringSubmatch(words[n]); m != nil {
+			day, _ := strconv.Atoi(m[1])
+			if day >= 1 && day <= 31 {
+				r.MonthDay = day
+				return n + 1
+			}
+		}
+	}
+	return 0
+}
+
+// dayListAt parses weekday names separated by commas or "and" at the start
+// of words, returning them in Monday-first order.
+func dayListAt(words []string) ([]time.Weekday, int) {
+	var days []time.Weekday
+	n := 0
+	for n < len(words) {
+		w := words[n]
+		if len(days) > 0 && w == "and" {
+			if n+1 < len(words) {
+				if _, ok := weekdayName(words[n+1]); ok {
+					n++
+					continue
+				}
+			}
+			break
+		}
+		d, ok := weekdayName(w)
+		if !ok {
+			break
+		}
+		if !containsDay(days, d) {
+			days = append(days, d)
+		}
+		n++
+	}
+	sortDays(days)
+	return days, n
+}
+
+func weekdayName(w string) (time.Weekday, bool) {
+	if d, ok := dateparse.Weekday(w); ok {
+		return d, true
+	}
+	return dateparse.Weekday(strings.TrimSuffix(w, "s")) // "mondays"
+}
+
+// normalize lowercases words and splits "mon,wed" into separate words.
+func normalize(words []string) []string {
+	var out []string
+	for _, w := range words {
+		for _, part := range strings.Split(strings.ToLower(w), ",") {
+			if part = strings.TrimRight(part, ".;!?"); part != "" {
+				out = append(out, part)
+			}
+		}
+	}
+	return out
+}
+
+var rruleDays = map[time.Weekday]string{
+	time.Monday: "MO", time.Tuesday: "TU", time.Wednesday: "WE", time.Thursday: "TH",
+	time.Friday: "FR", time.Saturday: "SA", time.Sunday: "SU",
+}
+
+func parseRRule(s string) (Rule, error) {
+	r := Rule{Interval: 1}
+	for _, part := range strings.Split(s, ";") {
+		key, value, _ := strings.Cut(part, "=")
+		switch key {
+		case "FREQ":
+			for f, name := range freqNames {
+				if name == value {
+					r.Freq = f
+				}
+			}
+		case "INTERVAL":
+			n, err := strconv.Atoi(value)
+			if err != nil || n < 1 {
+				return Rule{}, fmt.Errorf("%w: bad INTERVAL %q", ErrNoRule, value)
+			}
+			r.Interval = n
+		case "BYDAY":
+			for _, v := range strings.Split(value, ",") {
+				found := false
+				for d, name := range rruleDays {
+					if name == v {
+						r.Weekdays, found = append(r.Weekdays, d), true
+					}
+				}
+				if !found {
+					return Rule{}, fmt.Errorf("%w: bad BYDAY %q", ErrNoRule, v)
+				}
+			}
+			sortDays(r.Weekdays)
+		case "BYMONTHDAY":
+			n, err := strconv.Atoi(value)
+			if err != nil || n == 0 || n < LastDay || n > 31 {
+				return Rule{}, fmt.Errorf("%w: bad BYMONTHDAY %q", ErrNoRule, value)
+			}
+			r.MonthDay = n
+		default:
+			return Rule{}, fmt.Errorf("%w: unsupported %q", ErrNoRule, part)
+		}
+	}
+	if r.Freq == 0 {
+		return Rule{}, fmt.Errorf("%w: missing FREQ", ErrNoRule)
+	}
+	return r, nil
+}
+
+func date(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, t.Location())
+}
+
+func firstOfMonth(t time.Time) time.Time {
+	return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, t.Location())
+}
+
+func daysFromMonday(d time.Weekday) int {
+	return (int(d) + 6) % 7
+}
+
+func sortDays(days []time.Weekday) {
+	for i := 1; i < len(days); i++ {
+		for j := i; j > 0 && daysFromMonday(days[j]) < daysFromMonday(days[j-1]); j-- {
+			days[j], days[j-1] = days[j-1], days[j]
+		}
+	}
+}
+
+func containsDay(days []time.Weekday, d time.Weekday) bool {
+	for _, x := range days {
+		if x == d {
+			return true
+		}
+	}
+	return false
+}
+
+func sameDays(a, b []time.Weekday) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for _, d := range b {
+		if !containsDay(a, d) {
+			return false
+		}
+	}
+	return true
+}
+
+func dayList(days []time.Weekday) string {
+	names := make([]string, len(days))
+	for i, d := range days {
+		names[i] = d.String()[:3]
+	}
+	return strings.Join(names, ", ")
+}
+
+func ordinal(n int) string {
+	suffix := "th"
+	switch {
+	case n%100 >= 11 && n%100 <= 13:
+	case n%10 == 1:
+		suffix = "st"
+	case n%10 == 2:
+		suffix = "nd"
+	case n%10 == 3:
+		suffix = "rd"
+	}
+	return strconv.Itoa(n) + suffix
+}
@@ -0,0 +1,187 @@
+package recurrence
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"todo-tracker/internal/storage"
+	"todo-tracker/internal/supabase"
+)
+
+func day(s string) time.Time {
+	d, err := time.Parse("2006-01-02", s)
+	if err != nil {
+		panic(err)
+	}
+	return d
+}
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in, rrule, words string
+	}{
+		{"daily", "FREQ=DAILY", "every day"},
+		{"every day", "FREQ=DAILY", "every day"},
+		{"every 3 days", "FREQ=DAILY;INTERVAL=3", "every 3 days"},
+		{"weekly", "FREQ=WEEKLY", "every week"},
+		{"every mon", "FREQ=WEEKLY;BYDAY=MO", "every Mon"},
+		{"every Thursday and mon", "FREQ=WEEKLY;BYDAY=MO,TH", "every Mon, Thu"},
+		{"every mon,wed,fri", "FREQ=WEEKLY;BYDAY=MO,WE,FR", "every Mon, Wed, Fri"},
+		{"every weekday", "FREQ=WEEKLY;BYDAY=MO,TU,WE,TH,FR", "every weekday"},
+		{"every weekend", "FREQ=WEEKLY;BYDAY=SA,SU", "every weekend"},
+		{"every 2 weeks", "FREQ=WEEKLY;INTERVAL=2", "every 2 weeks"},
+		{"every other fri", "FREQ=WEEKLY;INTERVAL=2;BYDAY=FR", "every 2 weeks on Fri"},
+		{"every 2 weeks on tue", "FREQ=WEEKLY;INTERVAL=2;BYDAY=TU", "every 2 weeks on Tue"},
+		{"monthly", "FREQ=MONTHLY", "every month"},
+		{"monthly on 1st", "FREQ=MONTHLY;BYMONTHDAY=1", "every month on the 1st"},
+		{"every month on the 15th", "FREQ=MONTHLY;BYMONTHDAY=15", "every month on the 15th"},
+		{"monthly on the last day", "FREQ=MONTHLY;BYMONTHDAY=-1", "every month on the last day"},
+		{"every 3 months", "FREQ=MONTHLY;INTERVAL=3", "every 3 months"},
+		{"yearly", "FREQ=YEARLY", "every year"},
+		{"FREQ=WEEKLY;INTERVAL=2;BYDAY=MO", "FREQ=WEEKLY;INTERVAL=2;BYDAY=MO", "every 2 weeks on Mon"},
+		{"RRULE:FREQ=MONTHLY;BYMONTHDAY=-1", "FREQ=MONTHLY;BYMONTHDAY=-1", "every month on the last day"},
+	}
+	for _, tt := range tests {
+		r, err := Parse(tt.in)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.in, err)
+			continue
+		}
+		if got := r.String(); got != tt.rrule {
+			t.Errorf("Parse(%q).String() = %q, want %q", tt.in, got, tt.rrule)
+		}
+		if got := r.Describe(); got != tt.words {
+			t.Errorf("Parse(%q).Describe() = %q, want %q", tt.in, got, tt.words)
+		}
+	}
+
+	for _, in := range []string{"", "every", "every chapter", "every 0 days", "sometimes", "FREQ=HOURLY", "FREQ=DAILY;COUNT=3"} {
+		if _, err := Parse(in); err == nil {
+			t.Errorf("Parse(%q) succeeded, want error", in)
+		}
+	}
+}
+
+func TestExtract(t *testing.T) {
+	tests := []struct {
+		in, rest, rrule, at string
+	}{
+		{"Standup prep every mon", "Standup prep", "FREQ=WEEKLY;BYDAY=MO", ""},
+		{"Standup prep every mon 9am", "Standup prep", "FREQ=WEEKLY;BYDAY=MO", "09:00"},
+		{"Standup prep every mon at 9:30 sharp", "Standup prep sharp", "FREQ=WEEKLY;BYDAY=MO", "09:30"},
+		{"Pay invoices monthly on 1st", "Pay invoices", "FREQ=MONTHLY;BYMONTHDAY=1", ""},
+		{"Weekly review every fri", "Weekly review", "FREQ=WEEKLY;BYDAY=FR", ""},
+		{"Water plants every 3 days", "Water plants", "FREQ=DAILY;INTERVAL=3", ""},
+		{"Stretch daily", "Stretch", "FREQ=DAILY", ""},
+		{"Stretch daily 7am", "Stretch", "FREQ=DAILY", "07:00"},
+	}
+	for _, tt := range tests {
+		r, rest, ok := Extract(tt.in)
+		if !ok {
+			t.Errorf("Extract(%q) found no rule", tt.in)
+			continue
+		}
+		if rest != tt.rest || r.String() != tt.rrule || r.At != tt.at {
+			t.Errorf("Extract(%q) = %q at %q, %q; want %q at %q, %q", tt.in, r, r.At, rest, tt.rrule, tt.at, tt.rest)
+		}
+	}
+
+	for _, in := range []string{"Daily standup", "Read every chapter", "every mon", "Buy milk"} {
+		if r, _, ok := Extract(in); ok {
+			t.Errorf("Extract(%q) = %q, want no rule", in, r)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	tests := []struct {
+		rule, prev, want string
+	}{
+		{"every day", "2026-02-04", "2026-02-05"},
+		{"every 3 days", "2026-02-04", "2026-02-07"},
+		{"weekly", "2026-02-04", "2026-02-11"},
+		{"every mon", "2026-02-09", "2026-02-16"},
+		{"every mon and thu", "2026-02-09", "2026-02-12"},
+		{"every mon and thu", "2026-02-12", "2026-02-16"},
+		{"every weekday", "2026-02-06", "2026-02-09"}, // Fri -> Mon
+		{"every other fri", "2026-02-06", "2026-02-20"},
+		{"every 2 weeks on mon, wed", "2026-02-09", "2026-02-11"},
+		{"every 2 weeks on mon, wed", "2026-02-11", "2026-02-23"},
+		{"monthly", "2026-01-15", "2026-02-15"},
+		{"monthly", "2026-01-31", "2026-02-28"},
+		{"monthly on 1st", "2026-02-01", "2026-03-01"},
+		{"monthly on 15th", "2026-02-04", "2026-02-15"}, // first due not on the 15th
+		{"monthly on 31st", "2026-01-31", "2026-02-28"},
+		{"monthly on the last day", "2026-02-28", "2026-03-31"},
+		{"yearly", "2024-02-29", "2025-02-28"},
+	}
+	for _, tt := range tests {
+		r, err := Parse(tt.rule)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := r.Next(day(tt.prev)).Format("2006-01-02"); got != tt.want {
+			t.Errorf("%q.Next(%s) = %s, want %s", tt.rule, tt.prev, got, tt.want)
+		}
+	}
+}
+
+func TestFirstAndNextAfter(t *testing.T) {
+	r, _ := Parse("every mon")
+	if got := r.First(day("2026-02-04")).Format("2006-01-02"); got != "2026-02-09" {
+		t.Errorf("First = %s, want 2026-02-09", got)
+	}
+	if got := r.First(day("2026-02-09")).Format("2006-01-02"); got != "2026-02-09" {
+		t.Errorf("First on a Monday = %s, want the same day", got)
+	}
+
+	// Completing a task three weeks overdue skips the missed occurrences.
+	if got := r.NextAfter(day("2026-01-12"), day("2026-02-04")).Format("2006-01-02"); got != "2026-02-09" {
+		t.Errorf("NextAfter = %s, want 2026-02-09", got)
+	}
+}
+
+func TestAdvance(t *testing.T) {
+	ctx := context.Background()
+	s := storage.NewLocal(filepath.Join(t.TempDir(), "tasks.json"))
+	parent, _ := s.Create(ctx, supabase.Task{Title: "Standup prep", DueDate: "2026-02-09", DueTime: "09:00", Priority: "P2", Recurrence: "FREQ=WEEKLY;BYDAY=MO",
+		Notes: "Agenda in the team doc", Links: []string{"https://example.com/standup"}})
+	child, _ := s.Create(ctx, supabase.Task{Title: "Collect updates", DueDate: "2026-02-08", ParentID: &parent.ID, Notes: "Ask in #eng"})
+	s.Create(ctx, supabase.Task{Title: "Ping team", DueDate: "2026-02-08", ParentID: &child.ID})
+
+	now := time.Date(2026, 2, 9, 12, 0, 0, 0, time.UTC)
+	next, err := Advance(ctx, s, *parent, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if next.DueDate != "2026-02-16" || next.Clock() != "09:00" || next.Priority != "P2" || next.Recurrence != parent.Recurrence {
+		t.Errorf("next occurrence = %+v", next)
+	}
+	if next.Notes != parent.Notes || len(next.Links) != 1 || next.Links[0] != parent.Links[0] {
+		t.Errorf("next occurrence notes = %q, links = %v", next.Notes, next.Links)
+	}
+
+	children, _ := s.List(ctx, supabase.Filter{ParentID: &next.ID})
+	if len(children) != 1 || children[0].Title != "Collect updates" || children[0].DueDate != "2026-02-15" || children[0].Status != "Todo" || children[0].Notes != "Ask in #eng" {
+		t.Fatalf("copied children = %+v", children)
+	}
+	grandchildren, _ := s.List(ctx, supabase.Filter{ParentID: &children[0].ID})
+	if len(grandchildren) != 1 || grandchildren[0].Title != "Ping team" {
+		t.Errorf("copied grandchildren = %+v", grandchildren)
+	}
+
+	// Completing the same occurrence again does not create a duplicate.
+	again, err := Advance(ctx, s, *parent, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if again.ID != next.ID {
+		t.Errorf("second Advance created #%d, want existing #%d", again.ID, next.ID)
+	}
+
+	if next, err := Advance(ctx, s, *child, now); next != nil || err != nil {
+		t.Errorf("Advance on a non-recurring task = %v, %v", next, err)
+	}
+}
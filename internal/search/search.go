@@ -0,0 +1,161 @@
+// Package search reads the web-search style queries of `todo search` and
+// /search: words that must all appear, "quoted phrases", -excluded words
+// and "or" between alternatives. The database matches them with
+// websearch_to_tsquery; this package does the same closely enough for the
+// local backend, and highlights matched words in terminal output.
+package search
+
+import (
+	"strings"
+	"unicode"
+)
+
+// stopWords are ignored like the english text search configuration does.
+var stopWords = map[string]bool{
+	"a": true, "an": true, "and": true, "the": true, "of": true, "to": true,
+	"in": true, "on": true, "for": true, "at": true, "is": true, "it": true,
+	"with": true, "by": true,
+}
+
+// Query is a parsed search.
+type Query struct {
+	any [][]string // alternatives, each a list of stems that must all match
+	not []string   // stems that must not match
+}
+
+// Parse reads a query. Words inside quotes are matched as separate words.
+func Parse(text string) Query {
+	var q Query
+	var group []string
+	for _, w := range strings.Fields(strings.ReplaceAll(text, `"`, " ")) {
+		switch {
+		case strings.EqualFold(w, "or"):
+			if len(group) > 0 {
+				q.any = append(q.any, group)
+				group = nil
+			}
+		case strings.HasPrefix(w, "-"):
+			q.not = append(q.not, stems(w[1:])...)
+		default:
+			group = append(group, stems(w)...)
+		}
+	}
+	if len(group) > 0 {
+		q.any = append(q.any, group)
+	}
+	return q
+}
+
+// Empty reports whether q has nothing to look for.
+func (q Query) Empty() bool {
+	return len(q.any) == 0
+}
+
+// Match reports whether text satisfies q.
+func (q Query) Match(text string) bool {
+	words := stems(text)
+	for _, n := range q.not {
+		if count(words, n) > 0 {
+			return false
+		}
+	}
+	for _, group := range q.any {
+		all := true
+		for _, s := range group {
+			all = all && count(words, s) > 0
+		}
+		if all {
+			return true
+		}
+	}
+	return false
+}
+
+// Rank scores how well text matches q: the number of times the query's
+// words occur in it, relative to its length.
+func (q Query) Rank(text string) float64 {
+	words := stems(text)
+	hits := 0
+	for _, group := range q.any {
+		for _, s := range group {
+			hits += count(words, s)
+		}
+	}
+	return float64(hits) / float64(1+len(words))
+}
+
+// Highlight wraps each word of text that matches q in on and off, e.g.
+// terminal escape codes.
+func (q Query) Highlight(text, on, off string) string {
+	var sb strings.Builder
+	for len(text) > 0 {
+		i := strings.IndexFunc(text, isWordRune)
+		if i < 0 {
+			sb.WriteString(text)
+			break
+		}
+		sb.WriteString(text[:i])
+		text = text[i:]
+		j := strings.IndexFunc(text, func(r rune) bool { return !isWordRune(r) })
+		if j < 0 {
+			j = len(text)
+		}
+		word := text[:j]
+		if q.matchesWord(stem(strings.ToLower(word))) {
+			sb.WriteString(on + word + off)
+		} else {
+			sb.WriteString(word)
+		}
+		text = text[j:]
+	}
+	return sb.String()
+}
+
+func (q Query) matchesWord(w string) bool {
+	for _, group := range q.any {
+		for _, s := range group {
+			if strings.HasPrefix(w, s) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func isWordRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r)
+}
+
+// stems splits text into lowercase word stems, leaving out stop words.
+func stems(text string) []string {
+	var out []string
+	for _, w := range strings.FieldsFunc(strings.ToLower(text), func(r rune) bool { return !isWordRune(r) }) {
+		if !stopWords[w] {
+			out = append(out, stem(w))
+		}
+	}
+	return out
+}
+
+// stem strips common English endings so that "meetings" finds "meeting"
+// and "invoice" finds "invoices". Words also match by prefix, which covers
+// most of what the database's stemmer does.
+func stem(w string) string {
+	for _, suffix := range []string{"s", "ing", "ed"} {
+		if len(w)-len(suffix) >= 3 && strings.HasSuffix(w, suffix) {
+			w = strings.TrimSuffix(w, suffix)
+		}
+	}
+	return w
+}
+
+// count returns how many of words start with s.
+func count(words []string, s string) int {
+	n := 0
+	for _, w := range words {
+		if strings.HasPrefix(w, s) {
+			n++
+		}
+	}
+	return n
+}
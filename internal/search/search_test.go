@@ -0,0 +1,45 @@
+package search
+
+import "testing"
+
+func TestMatch(t *testing.T) {
+	tests := []struct {
+		query, text string
+		want        bool
+	}{
+		{"invoice", "Send invoices to ACME", true},
+		{"meetings", "Prepare meeting notes", true},
+		{"dentist", "Book the dentist", true},
+		{"dentist call", "Book the dentist", false},
+		{`"the dentist"`, "Book the dentist", true},
+		{"dentist -book", "Book the dentist", false},
+		{"plumber or dentist", "Book the dentist", true},
+		{"plumber or electrician", "Book the dentist", false},
+		{"box", "Pack the boxes", true},
+	}
+	for _, tt := range tests {
+		if got := Parse(tt.query).Match(tt.text); got != tt.want {
+			t.Errorf("Parse(%q).Match(%q) = %v, want %v", tt.query, tt.text, got, tt.want)
+		}
+	}
+}
+
+func TestRank(t *testing.T) {
+	q := Parse("report")
+	if q.Rank("Quarterly report: draft the report") <= q.Rank("Quarterly report and slides for the board") {
+		t.Error("more occurrences in a shorter title should rank higher")
+	}
+	if q.Rank("Buy milk") != 0 {
+		t.Error("a title without the word should rank 0")
+	}
+}
+
+func TestHighlight(t *testing.T) {
+	got := Parse("invoice acme").Highlight("Send invoices to ACME, then file", "[", "]")
+	if want := "Send [invoices] to [ACME], then file"; got != want {
+		t.Errorf("Highlight = %q, want %q", got, want)
+	}
+	if got := Parse("").Highlight("Buy milk", "[", "]"); got != "Buy milk" {
+		t.Errorf("empty query highlighted %q", got)
+	}
+}
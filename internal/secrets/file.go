@@ -0,0 +1,191 @@
+package secrets
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/pbkdf2"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// fileIterations is the PBKDF2 work factor for new files.
+const fileIterations = 600_000
+
+// File keeps secrets in a JSON map encrypted with AES-256-GCM under a key
+// derived from a passphrase with PBKDF2-SHA256. The passphrase is asked
+// for once per File.
+type File struct {
+	Path       string
+	Passphrase func(create bool) (string, error)
+
+	mu      sync.Mutex
+	key     []byte
+	salt    []byte
+	iter    int
+	secrets map[string]string // nil until loaded
+}
+
+// fileFormat is the file on disk; the secrets are only in Ciphertext.
+type fileFormat struct {
+	Version    int    `json:"version"`
+	KDF        string `json:"kdf"`
+	Iterations int    `json:"iterations"`
+	Salt       []byte `json:"salt"`
+	Nonce      []byte `json:"nonce"`
+	Ciphertext []byte `json:"ciphertext"`
+}
+
+// Get returns the secret called name.
+func (f *File) Get(name string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.load(); err != nil {
+		return "", err
+	}
+	v, ok := f.secrets[name]
+	if !ok {
+		return "", ErrNotFound
+	}
+	return v, nil
+}
+
+// Set stores value as name, creating the file if needed.
+func (f *File) Set(name, value string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.load(); err != nil {
+		return err
+	}
+	if f.key == nil {
+		if err := f.create(); err != nil {
+			return err
+		}
+	}
+	f.secrets[name] = value
+	return f.save()
+}
+
+// Delete removes name.
+func (f *File) Delete(name string) error {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	if err := f.load(); err != nil {
+		return err
+	}
+	if _, ok := f.secrets[name]; !ok {
+		return nil
+	}
+	delete(f.secrets, name)
+	return f.save()
+}
+
+func (f *File) String() string {
+	return "encrypted file " + f.Path
+}
+
+// load decrypts the file. When there is none it starts empty without
+// asking for a passphrase; Set asks for a new one before the first save.
+func (f *File) load() error {
+	if f.secrets != nil {
+		return nil
+	}
+	data, err := os.ReadFile(f.Path)
+	if errors.Is(err, os.ErrNotExist) {
+		f.secrets = map[string]string{}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var ff fileFormat
+	if err := json.Unmarshal(data, &ff); err != nil || ff.Version != 1 || ff.KDF != "pbkdf2-sha256" {
+		return fmt.Errorf("%s is not a todo secrets file", f.Path)
+	}
+	passphrase, err := f.Passphrase(false)
+	if err != nil {
+		return err
+	}
+	key, err := pbkdf2.Key(sha256.New, passphrase, ff.Salt, ff.Iterations, 32)
+	if err != nil {
+		return err
+	}
+	gcm, err := newGCM(key)
+	if err != nil {
+		return err
+	}
+	plain, err := gcm.Open(nil, ff.Nonce, ff.Ciphertext, nil)
+	if err != nil {
+		return errors.New("wrong passphrase")
+	}
+	secrets := map[string]string{}
+	if err := json.Unmarshal(plain, &secrets); err != nil {
+		return fmt.Errorf("%s: %w", f.Path, err)
+	}
+	f.key, f.salt, f.iter, f.secrets = key, ff.Salt, ff.Iterations, secrets
+	return nil
+}
+
+// create asks for a new passphrase and derives the key of a new file.
+func (f *File) create() error {
+	passphrase, err := f.Passphrase(true)
+	if err != nil {
+		return err
+	}
+	salt := make([]byte, 16)
+	rand.Read(salt)
+	key, err := pbkdf2.Key(sha256.New, passphrase, salt, fileIterations, 32)
+	if err != nil {
+		return err
+	}
+	f.key, f.salt, f.iter = key, salt, fileIterations
+	return nil
+}
+
+// save encrypts the secrets under a fresh nonce and replaces the file.
+func (f *File) save() error {
+	plain, err := json.Marshal(f.secrets)
+	if err != nil {
+		return err
+	}
+	gcm, err := newGCM(f.key)
+	if err != nil {
+		return err
+	}
+	nonce := make([]byte, gcm.NonceSize())
+	rand.Read(nonce)
+	data, err := json.MarshalIndent(fileFormat{
+		Version:    1,
+		KDF:        "pbkdf2-sha256",
+		Iterations: f.iter,
+		Salt:       f.salt,
+		Nonce:      nonce,
+		Ciphertext: gcm.Seal(nil, nonce, plain, nil),
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(f.Path), 0700); err != nil {
+		return err
+	}
+	tmp := f.Path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, f.Path)
+}
+
+func newGCM(key []byte) (cipher.AEAD, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,40 @@
+package secrets
+
+import (
+	"errors"
+
+	"github.com/zalando/go-keyring"
+)
+
+// Keyring keeps secrets in the system keyring: the Secret Service (GNOME
+// Keyring, KWallet) over D-Bus on Linux, the Keychain on macOS and the
+// Credential Manager on Windows.
+type Keyring struct {
+	Service string
+}
+
+// Get returns the secret called name.
+func (k Keyring) Get(name string) (string, error) {
+	v, err := keyring.Get(k.Service, name)
+	if errors.Is(err, keyring.ErrNotFound) {
+		return "", ErrNotFound
+	}
+	return v, err
+}
+
+// Set stores value as name.
+func (k Keyring) Set(name, value string) error {
+	return keyring.Set(k.Service, name, value)
+}
+
+// Delete removes name.
+func (k Keyring) Delete(name string) error {
+	if err := keyring.Delete(k.Service, name); err != nil && !errors.Is(err, keyring.ErrNotFound) {
+		return err
+	}
+	return nil
+}
+
+func (k Keyring) String() string {
+	return "keyring"
+}
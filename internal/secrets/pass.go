@@ -0,0 +1,72 @@
+package secrets
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+)
+
+// Pass keeps secrets in pass, the standard unix password manager, as
+// Prefix/<name> entries.
+type Pass struct {
+	Prefix  string
+	Command string // "pass" when empty
+}
+
+// Get returns the first line of the entry for name.
+func (p Pass) Get(name string) (string, error) {
+	out, err := p.run(nil, "show", p.entry(name))
+	if err != nil {
+		return "", err
+	}
+	value, _, _ := strings.Cut(string(out), "\n")
+	return value, nil
+}
+
+// Set writes value to the entry for name, replacing it.
+func (p Pass) Set(name, value string) error {
+	_, err := p.run([]byte(value+"\n"), "insert", "--multiline", "--force", p.entry(name))
+	return err
+}
+
+// Delete removes the entry for name.
+func (p Pass) Delete(name string) error {
+	if _, err := p.run(nil, "rm", "--force", p.entry(name)); err != nil && !errors.Is(err, ErrNotFound) {
+		return err
+	}
+	return nil
+}
+
+func (p Pass) String() string {
+	return "pass"
+}
+
+func (p Pass) entry(name string) string {
+	return p.Prefix + "/" + name
+}
+
+func (p Pass) run(stdin []byte, args ...string) ([]byte, error) {
+	command := p.Command
+	if command == "" {
+		command = "pass"
+	}
+	cmd := exec.Command(command, args...)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if stdin != nil {
+		cmd.Stdin = bytes.NewReader(stdin)
+	}
+	out, err := cmd.Output()
+	var exitErr *exec.ExitError
+	switch {
+	case errors.As(err, &exitErr) && strings.Contains(stderr.String(), "not in the password store"):
+		return nil, ErrNotFound
+	case errors.As(err, &exitErr):
+		return nil, fmt.Errorf("pass %s: %s", args[0], strings.TrimSpace(stderr.String()))
+	case err != nil:
+		return nil, fmt.Errorf("pass: %w", err)
+	}
+	return out, nil
+}
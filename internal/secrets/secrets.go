@@ -0,0 +1,145 @@
+// Package secrets keeps credentials out of plaintext files. A Store is the
+// desktop keyring (the Secret Service over D-Bus on Linux), the pass
+// password manager, or a file encrypted with a passphrase.
+package secrets
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/charmbracelet/x/term"
+)
+
+// ErrNotFound is returned by Get for a secret the store does not hold.
+var ErrNotFound = errors.New("secret not found")
+
+// Store holds named secrets.
+type Store interface {
+	Get(name string) (string, error)
+	Set(name, value string) error
+	// Delete removes a secret; removing a missing one is not an error.
+	Delete(name string) error
+	// String says where the secrets are kept, for messages.
+	String() string
+}
+
+// Kinds lists the stores Open accepts.
+var Kinds = []string{"keyring", "pass", "file"}
+
+// PassphraseEnv holds the passphrase of the encrypted file, so that
+// scripts and services need not be prompted.
+const PassphraseEnv = "TODO_CLI_PASSPHRASE"
+
+// Options configure Open.
+type Options struct {
+	// File is the encrypted file; DefaultFile when empty.
+	File string
+	// Passphrase returns the encrypted file's passphrase; create is set
+	// when the file is about to be created. Prompt when nil.
+	Passphrase func(create bool) (string, error)
+}
+
+// Open returns the store of the given kind, or nil for "", which means
+// secrets stay in the config file.
+func Open(kind string, opt Options) (Store, error) {
+	switch kind {
+	case "":
+		return nil, nil
+	case "keyring":
+		return Keyring{Service: "todo-cli"}, nil
+	case "pass":
+		return Pass{Prefix: "todo-cli"}, nil
+	case "file":
+		path := opt.File
+		if path == "" {
+			var err error
+			if path, err = DefaultFile(); err != nil {
+				return nil, err
+			}
+		}
+		passphrase := opt.Passphrase
+		if passphrase == nil {
+			passphrase = Prompt
+		}
+		return &File{Path: path, Passphrase: passphrase}, nil
+	}
+	return nil, fmt.Errorf("unknown secret store %q (expected %s)", kind, strings.Join(Kinds, ", "))
+}
+
+// DefaultFile returns secrets.enc in the user's config directory.
+func DefaultFile() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "todo", "secrets.enc"), nil
+}
+
+// Prompt takes the passphrase from TODO_CLI_PASSPHRASE, or asks for it on
+// the terminal, twice when create is set.
+func Prompt(create bool) (string, error) {
+	if p := os.Getenv(PassphraseEnv); p != "" {
+		return p, nil
+	}
+	tty, err := os.OpenFile("/dev/tty", os.O_RDWR, 0)
+	if err != nil {
+		return "", fmt.Errorf("no terminal to ask for the passphrase; set %s", PassphraseEnv)
+	}
+	defer tty.Close()
+
+	ask := func(label string) (string, error) {
+		fmt.Fprint(tty, label)
+		p, err := term.ReadPassword(tty.Fd())
+		fmt.Fprintln(tty)
+		return string(p), err
+	}
+	p, err := ask("🔐 Passphrase for the todo secrets file: ")
+	if err != nil {
+		return "", err
+	}
+	if p == "" {
+		return "", errors.New("empty passphrase")
+	}
+	if create {
+		again, err := ask("🔐 Repeat the passphrase: ")
+		if err != nil {
+			return "", err
+		}
+		if again != p {
+			return "", errors.New("passphrases do not match")
+		}
+	}
+	return p, nil
+}
+
+// PermissionError reports a credential file that other users can access.
+type PermissionError struct {
+	Path string
+	Mode fs.FileMode
+}
+
+func (e *PermissionError) Error() string {
+	return fmt.Sprintf("%s holds credentials but other users can read it (mode %04o); run chmod 600 %s", e.Path, e.Mode.Perm(), e.Path)
+}
+
+// CheckPermissions returns a *PermissionError when group or other users
+// have any access to the file at path. A missing file passes, as does
+// every file on Windows, where modes do not describe access.
+func CheckPermissions(path string) error {
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+	fi, err := os.Stat(path)
+	if err != nil {
+		return nil
+	}
+	if fi.Mode().Perm()&0077 != 0 {
+		return &PermissionError{Path: path, Mode: fi.Mode()}
+	}
+	return nil
+}
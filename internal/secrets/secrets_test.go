@@ -0,0 +1,158 @@
+package secrets
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/zalando/go-keyring"
+)
+
+// roundTrip checks the Store contract on s.
+func roundTrip(t *testing.T, s Store) {
+	t.Helper()
+	if _, err := s.Get("work/token"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get missing = %v", err)
+	}
+	if err := s.Set("work/token", "s3cret"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Set("work/token", "s3cret-2"); err != nil {
+		t.Fatal(err)
+	}
+	if v, err := s.Get("work/token"); err != nil || v != "s3cret-2" {
+		t.Fatalf("Get = %q, %v", v, err)
+	}
+	if err := s.Delete("work/token"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := s.Get("work/token"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get after Delete = %v", err)
+	}
+	if err := s.Delete("work/token"); err != nil {
+		t.Errorf("second Delete = %v", err)
+	}
+}
+
+func TestKeyring(t *testing.T) {
+	keyring.MockInit()
+	roundTrip(t, Keyring{Service: "todo-cli-test"})
+}
+
+func TestPass(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake pass is a shell script")
+	}
+	dir := t.TempDir()
+	fake := filepath.Join(dir, "pass")
+	script := `#!/bin/sh
+store="$(dirname "$0")/store"
+case "$1" in
+show) [ -f "$store/$2" ] || { echo "Error: $2 is not in the password store." >&2; exit 1; }; cat "$store/$2" ;;
+insert) mkdir -p "$(dirname "$store/$4")"; cat > "$store/$4" ;;
+rm) [ -f "$store/$3" ] || { echo "Error: $3 is not in the password store." >&2; exit 1; }; rm "$store/$3" ;;
+esac
+`
+	if err := os.WriteFile(fake, []byte(script), 0700); err != nil {
+		t.Fatal(err)
+	}
+	roundTrip(t, Pass{Prefix: "todo-cli", Command: fake})
+}
+
+func TestFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "todo", "secrets.enc")
+	asked := 0
+	open := func(p string) *File {
+		return &File{Path: path, Passphrase: func(bool) (string, error) {
+			asked++
+			return p, nil
+		}}
+	}
+
+	s := open("correct horse")
+	roundTrip(t, s)
+	if err := s.Set("work/token", "s3cret"); err != nil {
+		t.Fatal(err)
+	}
+	if asked != 1 {
+		t.Errorf("passphrase asked %d times", asked)
+	}
+	data, _ := os.ReadFile(path)
+	if strings.Contains(string(data), "s3cret") || strings.Contains(string(data), "work/token") {
+		t.Errorf("file is not encrypted: %s", data)
+	}
+	if err := CheckPermissions(path); err != nil {
+		t.Error(err)
+	}
+
+	if v, err := open("correct horse").Get("work/token"); err != nil || v != "s3cret" {
+		t.Errorf("reopened Get = %q, %v", v, err)
+	}
+	if _, err := open("wrong").Get("work/token"); err == nil || !strings.Contains(err.Error(), "wrong passphrase") {
+		t.Errorf("wrong passphrase err = %v", err)
+	}
+}
+
+func TestFileMissingAsksOnlyToSet(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "secrets.enc")
+	var asked []bool
+	s := &File{Path: path, Passphrase: func(create bool) (string, error) {
+		asked = append(asked, create)
+		return "correct horse", nil
+	}}
+
+	if _, err := s.Get("default/token"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("Get err = %v, want ErrNotFound", err)
+	}
+	if err := s.Delete("default/token"); err != nil {
+		t.Fatal(err)
+	}
+	if len(asked) != 0 {
+		t.Fatalf("passphrase asked %d times before anything was saved", len(asked))
+	}
+	if _, err := os.Stat(path); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("file created without a Set: %v", err)
+	}
+
+	if err := s.Set("default/token", "s3cret"); err != nil {
+		t.Fatal(err)
+	}
+	if len(asked) != 1 || !asked[0] {
+		t.Errorf("asked = %v, want one new passphrase", asked)
+	}
+}
+
+func TestCheckPermissions(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("no unix modes")
+	}
+	path := filepath.Join(t.TempDir(), ".env")
+	os.WriteFile(path, []byte("TODO_CLI_TOKEN=x\n"), 0644)
+	var perm *PermissionError
+	if err := CheckPermissions(path); !errors.As(err, &perm) || perm.Mode.Perm() != 0644 {
+		t.Fatalf("0644 file = %v", err)
+	}
+	os.Chmod(path, 0600)
+	if err := CheckPermissions(path); err != nil {
+		t.Errorf("0600 file = %v", err)
+	}
+	if err := CheckPermissions(path + ".missing"); err != nil {
+		t.Errorf("missing file = %v", err)
+	}
+}
+
+func TestOpen(t *testing.T) {
+	if s, err := Open("", Options{}); s != nil || err != nil {
+		t.Errorf(`Open("") = %v, %v`, s, err)
+	}
+	if _, err := Open("vault", Options{}); err == nil {
+		t.Error("unknown kind accepted")
+	}
+	s, err := Open("file", Options{File: "/tmp/x.enc"})
+	if err != nil || s.String() != "encrypted file /tmp/x.enc" {
+		t.Errorf(`Open("file") = %v, %v`, s, err)
+	}
+}
@@ -0,0 +1,176 @@
+// Package snooze works out where `todo snooze` and /snooze move a task.
+//
+// A snooze is either a duration ("3d", "+3 days", "2w", "in 1 month"),
+// counted from the task's due date or from today if that is later, or any
+// date dateparse understands ("monday", "next week", "2026-03-01"). Without
+// one a task moves to tomorrow.
+package snooze
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"todo-tracker/internal/dateparse"
+	"todo-tracker/internal/supabase"
+)
+
+// ErrInvalid is returned by Parse for text that is neither a duration nor
+// a date.
+var ErrInvalid = errors.New("not a snooze duration or date")
+
+var duration = regexp.MustCompile(`^(?:in\s+|\+)?(\d+)\s*(d|days?|w|wks?|weeks?|m|mos?|months?)$`)
+
+// Spec is a parsed snooze.
+type Spec struct {
+	n    int    // duration length, when unit is set
+	unit string // "d", "w" or "m"; empty for a date
+	date dateparse.Result
+}
+
+// Parse reads a duration or a date relative to now. Empty text means
+// tomorrow.
+func Parse(text string, now time.Time) (Spec, error) {
+	text = strings.ToLower(strings.TrimSpace(text))
+	if text == "" {
+		text = "tomorrow"
+	}
+	if m := duration.FindStringSubmatch(text); m != nil {
+		n, _ := strconv.Atoi(m[1])
+		if n < 1 {
+			return Spec{}, fmt.Errorf("%w: %q", ErrInvalid, text)
+		}
+		return Spec{n: n, unit: m[2][:1]}, nil
+	}
+	d, err := dateparse.Parse(text, now)
+	if err != nil {
+		return Spec{}, fmt.Errorf("%w: %q", ErrInvalid, text)
+	}
+	return Spec{date: d}, nil
+}
+
+// Relative reports whether s is a duration, so the new date depends on
+// each task's due date.
+func (s Spec) Relative() bool {
+	return s.unit != ""
+}
+
+// Time returns the time of day given with a date, or "".
+func (s Spec) Time() string {
+	return s.date.Time
+}
+
+// Due returns the new due date for a task currently due on due (YYYY-MM-DD,
+// possibly empty). With skipWeekends, days of a duration count working days
+// only and a date landing on a weekend moves to the following Monday.
+func (s Spec) Due(due string, now time.Time, skipWeekends bool) time.Time {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	if !s.Relative() {
+		d := s.date.Date
+		if skipWeekends {
+			d = nextWorkday(d)
+		}
+		return d
+	}
+
+	base := today
+	if d, err := time.ParseInLocation(dateparse.Layout, due, now.Location()); err == nil && d.After(today) {
+		base = d
+	}
+	var d time.Time
+	switch s.unit {
+	case "d":
+		if !skipWeekends {
+			return base.AddDate(0, 0, s.n)
+		}
+		d = base
+		for left := s.n; left > 0; {
+			d = d.AddDate(0, 0, 1)
+			if !weekend(d) {
+				left--
+			}
+		}
+		return d
+	case "w":
+		d = base.AddDate(0, 0, 7*s.n)
+	default:
+		d = dateparse.AddMonths(base, s.n)
+	}
+	if skipWeekends {
+		d = nextWorkday(d)
+	}
+	return d
+}
+
+func weekend(d time.Time) bool {
+	return d.Weekday() == time.Saturday || d.Weekday() == time.Sunday
+}
+
+func nextWorkday(d time.Time) time.Time {
+	for weekend(d) {
+		d = d.AddDate(0, 0, 1)
+	}
+	return d
+}
+
+// Store is the subset of task storage Apply needs.
+type Store interface {
+	Update(ctx context.Context, f supabase.Filter, fields map[string]any) ([]supabase.Task, error)
+}
+
+// Apply snoozes tasks by s and bumps their snooze counts. Tasks that end up
+// with the same due date and count share one update, so a date snoozes
+// everything in a single request. It returns the updated tasks in the
+// order given. When some updates are queued offline it carries on and
+// returns the queuing error at the end.
+func Apply(ctx context.Context, st Store, tasks []supabase.Task, s Spec, now time.Time, skipWeekends bool) ([]supabase.Task, error) {
+	type key struct {
+		due   string
+		count int
+	}
+	groups := map[key][]int{}
+	var order []key
+	for _, t := range tasks {
+		k := key{s.Due(t.DueDate, now, skipWeekends).Format(dateparse.Layout), t.SnoozeCount + 1}
+		if _, ok := groups[k]; !ok {
+			order = append(order, k)
+		}
+		groups[k] = append(groups[k], t.ID)
+	}
+
+	updated := map[int]supabase.Task{}
+	var queued error
+	for _, k := range order {
+		fields := map[string]any{"due_date": k.due, "snooze_count": k.count}
+		if s.Time() != "" {
+			fields["due_time"] = s.Time()
+		}
+		rows, err := st.Update(ctx, supabase.Filter{IDs: groups[k]}, fields)
+		if errors.Is(err, supabase.ErrQueued) {
+			queued = err
+			continue
+		}
+		if err != nil {
+			return collect(tasks, updated), err
+		}
+		for _, t := range rows {
+			updated[t.ID] = t
+		}
+	}
+	return collect(tasks, updated), queued
+}
+
+// collect returns the updated versions of tasks, in order.
+func collect(tasks []supabase.Task, updated map[int]supabase.Task) []supabase.Task {
+	var out []supabase.Task
+	for _, t := range tasks {
+		if u, ok := updated[t.ID]; ok {
+			out = append(out, u)
+		}
+	}
+	return out
+}
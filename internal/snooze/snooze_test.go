@@ -0,0 +1,80 @@
+package snooze
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"todo-tracker/internal/dateparse"
+	"todo-tracker/internal/storage"
+	"todo-tracker/internal/supabase"
+)
+
+// Thursday 5 February 2026, 10:00.
+var now = time.Date(2026, time.February, 5, 10, 0, 0, 0, time.UTC)
+
+func TestDue(t *testing.T) {
+	tests := []struct {
+		text, due    string
+		skipWeekends bool
+		want         string
+	}{
+		{"", "2026-02-01", false, "2026-02-06"},
+		{"3d", "2026-02-01", false, "2026-02-08"}, // overdue: from today
+		{"+3 days", "2026-02-10", false, "2026-02-13"},
+		{"in 2 weeks", "", false, "2026-02-19"},
+		{"1 month", "2026-01-31", false, "2026-03-05"},
+		{"1m", "2026-03-31", false, "2026-04-30"},
+		{"3d", "2026-02-01", true, "2026-02-10"}, // Fri, Mon, Tue
+		{"2d", "2026-02-01", true, "2026-02-09"},
+		{"monday", "2026-02-01", false, "2026-02-09"},
+		{"next week", "2026-02-20", false, "2026-02-09"},
+		{"saturday", "", false, "2026-02-07"},
+		{"saturday", "", true, "2026-02-09"},
+		{"1w", "2026-01-31", true, "2026-02-12"},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.text, now)
+		if err != nil {
+			t.Errorf("Parse(%q): %v", tt.text, err)
+			continue
+		}
+		if got := s.Due(tt.due, now, tt.skipWeekends).Format(dateparse.Layout); got != tt.want {
+			t.Errorf("snooze %q from %s (skip weekends %v) = %s, want %s", tt.text, tt.due, tt.skipWeekends, got, tt.want)
+		}
+	}
+}
+
+func TestParse(t *testing.T) {
+	s, err := Parse("friday 9am", now)
+	if err != nil || s.Relative() || s.Time() != "09:00" {
+		t.Errorf("Parse(friday 9am) = %+v, %v", s, err)
+	}
+	if s, _ := Parse("3d", now); !s.Relative() {
+		t.Error("3d is not relative")
+	}
+	for _, bad := range []string{"soon", "0d", "3x"} {
+		if _, err := Parse(bad, now); !errors.Is(err, ErrInvalid) {
+			t.Errorf("Parse(%q) err = %v, want ErrInvalid", bad, err)
+		}
+	}
+}
+
+func TestApply(t *testing.T) {
+	ctx := context.Background()
+	s := storage.NewLocal(filepath.Join(t.TempDir(), "tasks.json"))
+	a, _ := s.Create(ctx, supabase.Task{Title: "A", DueDate: "2026-02-01"})
+	b, _ := s.Create(ctx, supabase.Task{Title: "B", DueDate: "2026-02-10", SnoozeCount: 2})
+
+	spec, _ := Parse("3d", now)
+	got, err := Apply(ctx, s, []supabase.Task{*b, *a}, spec, now, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].ID != b.ID || got[0].DueDate != "2026-02-13" || got[0].SnoozeCount != 3 ||
+		got[1].DueDate != "2026-02-08" || got[1].SnoozeCount != 1 {
+		t.Errorf("Apply = %+v", got)
+	}
+}
@@ -0,0 +1,323 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"sync"
+	"time"
+
+	"todo-tracker/internal/supabase"
+)
+
+// maxActions is how many actions the journal keeps.
+const maxActions = 100
+
+// ErrChanged is matched by a *ChangedError.
+var ErrChanged = errors.New("task changed since")
+
+// ChangedError refuses an undo because a task no longer holds the value the
+// action left it with. Field is empty when the task no longer exists.
+type ChangedError struct {
+	Action Action
+	TaskID int
+	Field  string
+	Want   any // value after the action
+	Got    any // value now
+}
+
+func (e *ChangedError) Error() string {
+	if e.Field == "" {
+		return fmt.Sprintf("%v %q: task %d no longer exists", ErrChanged, e.Action.Command, e.TaskID)
+	}
+	return fmt.Sprintf("%v %q: task %d %s is now %v, not %v", ErrChanged, e.Action.Command, e.TaskID, e.Field, e.Got, e.Want)
+}
+
+func (e *ChangedError) Is(target error) bool { return target == ErrChanged }
+
+// Action is everything one command changed.
+type Action struct {
+	Session string   `json:"session"`
+	Command string   `json:"command"`
+	At      string   `json:"at"`
+	Changes []Change `json:"changes"`
+	Undone  bool     `json:"undone,omitempty"`
+}
+
+// Change is one task's fields before and after an update, or a task the
+// action created.
+type Change struct {
+	TaskID  int            `json:"task_id"`
+	Created bool           `json:"created,omitempty"`
+	Before  map[string]any `json:"before,omitempty"`
+	After   map[string]any `json:"after,omitempty"`
+}
+
+// Journal wraps a Storage and records every change that reaches it, so
+// that the latest actions can be listed and undone. Changes that fail or
+// are only queued offline are not recorded.
+type Journal struct {
+	s       Storage
+	path    string
+	command string
+	session string
+	mu      sync.Mutex
+}
+
+// NewJournal wraps s, recording changes under command in the journal at
+// path. All changes made through one Journal form a single action.
+func NewJournal(s Storage, path, command string) *Journal {
+	return &Journal{
+		s:       s,
+		path:    path,
+		command: command,
+		session: strconv.FormatInt(time.Now().UnixNano(), 36),
+	}
+}
+
+// Begin starts a new action recorded under command, so that one
+// long-running command can make several separately undoable changes.
+func (j *Journal) Begin(command string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	j.command = command
+	j.session = strconv.FormatInt(time.Now().UnixNano(), 36)
+}
+
+// List returns the tasks matching f.
+func (j *Journal) List(ctx context.Context, f supabase.Filter) ([]supabase.Task, error) {
+	return j.s.List(ctx, f)
+}
+
+// Search runs a full-text search.
+func (j *Journal) Search(ctx context.Context, query string, f supabase.Filter) ([]supabase.Task, error) {
+	return j.s.Search(ctx, query, f)
+}
+
+// Get returns a task.
+func (j *Journal) Get(ctx context.Context, id int) (*supabase.Task, error) {
+	return j.s.Get(ctx, id)
+}
+
+// Create inserts t and records it.
+func (j *Journal) Create(ctx context.Context, t supabase.Task) (*supabase.Task, error) {
+	created, err := j.s.Create(ctx, t)
+	if err == nil {
+		j.record([]Change{{TaskID: created.ID, Created: true}})
+	}
+	return created, err
+}
+
+// Update patches the matching tasks and records their values before and
+// after. The rows are read first; if that fails the update still goes
+// ahead, unrecorded.
+func (j *Journal) Update(ctx context.Context, f supabase.Filter, fields map[string]any) ([]supabase.Task, error) {
+	before, listErr := j.s.List(ctx, f)
+	updated, err := j.s.Update(ctx, f, fields)
+	if err != nil || listErr != nil {
+		return updated, err
+	}
+
+	old := make(map[int]supabase.Task, len(before))
+	for _, t := range before {
+		old[t.ID] = t
+	}
+	var changes []Change
+	for _, t := range updated {
+		prev, ok := old[t.ID]
+		if !ok {
+			continue
+		}
+		c := Change{TaskID: t.ID, Before: pick(prev, fields), After: pick(t, fields)}
+		if !reflect.DeepEqual(c.Before, c.After) {
+			changes = append(changes, c)
+		}
+	}
+	j.record(changes)
+	return updated, nil
+}
+
+// UpdateByID patches a single task, or returns supabase.ErrNotFound.
+func (j *Journal) UpdateByID(ctx context.Context, id int, fields map[string]any) (*supabase.Task, error) {
+	tasks, err := j.Update(ctx, supabase.Filter{IDs: []int{id}}, fields)
+	if err != nil {
+		return nil, err
+	}
+	if len(tasks) == 0 {
+		return nil, supabase.ErrNotFound
+	}
+	return &tasks[0], nil
+}
+
+// History returns the recorded actions, oldest first.
+func (j *Journal) History() ([]Action, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+	return j.load()
+}
+
+// Undo reverts the n most recent actions not yet undone, newest first, and
+// returns them with the restored tasks. Unless force is set it first checks
+// that every task still holds the values the actions left, and returns a
+// *ChangedError without changing anything if one does not. Tasks the
+// actions created are closed, since the storage cannot delete them; one
+// that no longer exists is skipped.
+func (j *Journal) Undo(ctx context.Context, n int, force bool) ([]Action, []supabase.Task, error) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	actions, err := j.load()
+	if err != nil {
+		return nil, nil, err
+	}
+	var picked []int
+	for i := len(actions) - 1; i >= 0 && len(picked) < n; i-- {
+		if !actions[i].Undone {
+			picked = append(picked, i)
+		}
+	}
+
+	if !force {
+		// Only the latest action touching a task needs to match the
+		// server; older ones are restored through it.
+		checked := map[int]bool{}
+		for _, i := range picked {
+			for _, c := range actions[i].Changes {
+				if c.Created || checked[c.TaskID] {
+					continue
+				}
+				checked[c.TaskID] = true
+				if err := j.unchanged(ctx, actions[i], c); err != nil {
+					return nil, nil, err
+				}
+			}
+		}
+	}
+
+	var undone []Action
+	var restored []supabase.Task
+	for _, i := range picked {
+		changes := actions[i].Changes
+		for k := len(changes) - 1; k >= 0; k-- {
+			c := changes[k]
+			fields := c.Before
+			if c.Created {
+				fields = map[string]any{"status": "Done"}
+			}
+			t, err := j.s.UpdateByID(ctx, c.TaskID, fields)
+			if c.Created && errors.Is(err, supabase.ErrNotFound) {
+				continue
+			}
+			if err != nil && !errors.Is(err, ErrQueued) {
+				return undone, restored, err
+			}
+			if t != nil {
+				restored = append(restored, *t)
+			}
+		}
+		actions[i].Undone = true
+		undone = append(undone, actions[i])
+		if err := j.save(actions); err != nil {
+			return undone, restored, err
+		}
+	}
+	return undone, restored, nil
+}
+
+// unchanged checks that the task in c still holds c.After.
+func (j *Journal) unchanged(ctx context.Context, a Action, c Change) error {
+	t, err := j.s.Get(ctx, c.TaskID)
+	if errors.Is(err, supabase.ErrNotFound) {
+		return &ChangedError{Action: a, TaskID: c.TaskID}
+	}
+	if err != nil {
+		return err
+	}
+	now := pick(*t, c.After)
+	for field, want := range c.After {
+		if !reflect.DeepEqual(normalize(now[field]), normalize(want)) {
+			return &ChangedError{Action: a, TaskID: c.TaskID, Field: field, Want: want, Got: now[field]}
+		}
+	}
+	return nil
+}
+
+// record adds changes to this session's action. The journal is best
+// effort: a failure to write it never fails the change itself.
+func (j *Journal) record(changes []Change) {
+	if len(changes) == 0 || j.path == "" {
+		return
+	}
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	actions, err := j.load()
+	if err != nil {
+		return
+	}
+	if last := len(actions) - 1; last >= 0 && actions[last].Session == j.session {
+		actions[last].Changes = append(actions[last].Changes, changes...)
+	} else {
+		actions = append(actions, Action{
+			Session: j.session,
+			Command: j.command,
+			At:      time.Now().UTC().Format(time.RFC3339),
+			Changes: changes,
+		})
+	}
+	j.save(actions)
+}
+
+func (j *Journal) load() ([]Action, error) {
+	var actions []Action
+	data, err := os.ReadFile(j.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, &actions); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", j.path, err)
+	}
+	return actions, nil
+}
+
+func (j *Journal) save(actions []Action) error {
+	if len(actions) > maxActions {
+		actions = actions[len(actions)-maxActions:]
+	}
+	data, err := json.MarshalIndent(actions, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(j.path, data)
+}
+
+// notNull holds the values of NOT NULL columns that Task leaves out of its
+// JSON when they are empty.
+var notNull = map[string]any{"tags": []string{}, "snooze_count": 0, "links": []string{}}
+
+// pick returns t's values for the keys of fields. Unset values are nil,
+// except for NOT NULL columns, which get their empty value.
+func pick[V any](t supabase.Task, fields map[string]V) map[string]any {
+	row, err := toRow(t)
+	if err != nil {
+		return nil
+	}
+	out := make(map[string]any, len(fields))
+	for k := range fields {
+		if k == "id" {
+			continue
+		}
+		out[k] = row[k]
+		if zero, ok := notNull[k]; ok && row[k] == nil {
+			out[k] = zero
+		}
+	}
+	return out
+}
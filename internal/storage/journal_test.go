@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"todo-tracker/internal/supabase"
+)
+
+func newJournal(t *testing.T) (*Local, string) {
+	dir := t.TempDir()
+	return NewLocal(filepath.Join(dir, "tasks.json")), filepath.Join(dir, "journal.json")
+}
+
+func TestJournalRecordsAndUndoes(t *testing.T) {
+	ctx := context.Background()
+	local, path := newJournal(t)
+	task, _ := local.Create(ctx, supabase.Task{Title: "Pay rent", DueDate: "2026-03-01"})
+
+	j := NewJournal(local, path, "done 1")
+	if _, err := j.UpdateByID(ctx, task.ID, map[string]any{"status": "Done"}); err != nil {
+		t.Fatal(err)
+	}
+	j.Create(ctx, supabase.Task{Title: "Next rent"})
+	j = NewJournal(local, path, "edit 1 --due friday")
+	j.UpdateByID(ctx, task.ID, map[string]any{"due_date": "2026-03-06", "due_time": nil})
+
+	history, err := j.History()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(history) != 2 || history[0].Command != "done 1" || len(history[0].Changes) != 2 || !history[0].Changes[1].Created {
+		t.Fatalf("history = %+v", history)
+	}
+	if c := history[1].Changes[0]; c.Before["due_date"] != "2026-03-01" || c.After["due_date"] != "2026-03-06" {
+		t.Errorf("edit change = %+v", c)
+	}
+
+	undone, restored, err := j.Undo(ctx, 2, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(undone) != 2 || undone[0].Command != "edit 1 --due friday" || len(restored) != 3 {
+		t.Errorf("undone = %+v, restored = %+v", undone, restored)
+	}
+	got, _ := local.Get(ctx, task.ID)
+	if got.Status != "Todo" || got.DueDate != "2026-03-01" {
+		t.Errorf("after undo = %+v", got)
+	}
+	// The task the action created is closed.
+	if next, _ := local.Get(ctx, history[0].Changes[1].TaskID); next.Status != "Done" {
+		t.Errorf("created task after undo = %+v", next)
+	}
+
+	// Undone actions are not undone twice.
+	if undone, _, _ := j.Undo(ctx, 1, false); len(undone) != 0 {
+		t.Errorf("second undo = %+v", undone)
+	}
+}
+
+func TestJournalRefusesChangedTask(t *testing.T) {
+	ctx := context.Background()
+	local, path := newJournal(t)
+	task, _ := local.Create(ctx, supabase.Task{Title: "Pay rent"})
+
+	j := NewJournal(local, path, "done 1")
+	j.UpdateByID(ctx, task.ID, map[string]any{"status": "Done"})
+	local.UpdateByID(ctx, task.ID, map[string]any{"status": "Todo"}) // changed elsewhere
+
+	_, _, err := j.Undo(ctx, 1, false)
+	var changed *ChangedError
+	if !errors.As(err, &changed) || !errors.Is(err, ErrChanged) || changed.Field != "status" {
+		t.Fatalf("err = %v, want ChangedError on status", err)
+	}
+	if h, _ := j.History(); h[0].Undone {
+		t.Error("refused undo was marked undone")
+	}
+
+	local.UpdateByID(ctx, task.ID, map[string]any{"status": "Done", "title": "Pay the rent"})
+	if _, _, err := j.Undo(ctx, 1, false); err != nil {
+		t.Errorf("undo after unrelated change: %v", err)
+	}
+	if got, _ := local.Get(ctx, task.ID); got.Status != "Todo" || got.Title != "Pay the rent" {
+		t.Errorf("after undo = %+v", got)
+	}
+}
+
+func TestJournalBeginStartsNewAction(t *testing.T) {
+	ctx := context.Background()
+	local, path := newJournal(t)
+	task, _ := local.Create(ctx, supabase.Task{Title: "Pay rent", Priority: "P1"})
+
+	j := NewJournal(local, path, "ui")
+	j.Begin("ui priority 1 P0")
+	j.UpdateByID(ctx, task.ID, map[string]any{"priority": "P0"})
+	j.Begin("ui done 1")
+	j.UpdateByID(ctx, task.ID, map[string]any{"status": "Done"})
+
+	history, _ := j.History()
+	if len(history) != 2 || history[0].Command != "ui priority 1 P0" || history[1].Command != "ui done 1" {
+		t.Fatalf("history = %+v", history)
+	}
+	if _, _, err := j.Undo(ctx, 1, false); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := local.Get(ctx, task.ID); got.Status != "Todo" || got.Priority != "P0" {
+		t.Errorf("after undoing only the last action = %+v", got)
+	}
+}
@@ -0,0 +1,371 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"todo-tracker/internal/search"
+	"todo-tracker/internal/supabase"
+)
+
+// Local keeps tasks in a JSON file on disk. It needs no network and mirrors
+// the tasks table's defaults and filtering semantics.
+type Local struct {
+	path string
+	mu   sync.Mutex
+}
+
+type localFile struct {
+	NextID int             `json:"next_id"`
+	Tasks  []supabase.Task `json:"tasks"`
+}
+
+// NewLocal returns a backend stored at path. The file is created on first write.
+func NewLocal(path string) *Local {
+	return &Local{path: path}
+}
+
+// DefaultDir returns the directory holding the CLI's local state.
+func DefaultDir() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "todo"), nil
+}
+
+// DefaultLocalPath returns the task file location under the user's config dir.
+func DefaultLocalPath() (string, error) {
+	dir, err := DefaultDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "tasks.json"), nil
+}
+
+func (l *Local) load() (*localFile, error) {
+	data, err := os.ReadFile(l.path)
+	if errors.Is(err, os.ErrNotExist) {
+		return &localFile{NextID: 1}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var f localFile
+	if err := json.Unmarshal(data, &f); err != nil {
+		return nil, fmt.Errorf("parse %s: %w", l.path, err)
+	}
+	if f.NextID < 1 {
+		f.NextID = 1
+	}
+	return &f, nil
+}
+
+func (l *Local) save(f *localFile) error {
+	data, err := json.MarshalIndent(f, "", "  ")
+	if err != nil {
+		return err
+	}
+	return writeFileAtomic(l.path, data)
+}
+
+// writeFileAtomic replaces path with data via a temp file and rename so a
+// crash never leaves a half-written file behind.
+func writeFileAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+	tmp := path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, path)
+}
+
+// List returns the tasks matching f.
+func (l *Local) List(_ context.Context, f supabase.Filter) ([]supabase.Task, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	data, err := l.load()
+	if err != nil {
+		return nil, err
+	}
+	return selectTasks(data.Tasks, f), nil
+}
+
+// Search returns the tasks matching f whose titles or notes match query,
+// best match first, approximating the database's full-text ranking.
+func (l *Local) Search(_ context.Context, query string, f supabase.Filter) ([]supabase.Task, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	data, err := l.load()
+	if err != nil {
+		return nil, err
+	}
+	q := search.Parse(query)
+	limit := f.Limit
+	f.Limit = 0
+	var found []supabase.Task
+	rank := map[int]float64{}
+	for _, t := range selectTasks(data.Tasks, f) {
+		if q.Match(t.Title + "\n" + t.Notes) {
+			found = append(found, t)
+			// Title matches count double, like the weights on the index.
+			rank[t.ID] = 2*q.Rank(t.Title) + q.Rank(t.Notes)
+		}
+	}
+	if len(f.Order) == 0 {
+		// Best match first, then newest first like the database function.
+		sort.SliceStable(found, func(i, j int) bool {
+			if rank[found[i].ID] != rank[found[j].ID] {
+				return rank[found[i].ID] > rank[found[j].ID]
+			}
+			return found[i].ID > found[j].ID
+		})
+	}
+	if limit > 0 && len(found) > limit {
+		found = found[:limit]
+	}
+	return found, nil
+}
+
+// Get returns the task with the given ID, or supabase.ErrNotFound.
+func (l *Local) Get(ctx context.Context, id int) (*supabase.Task, error) {
+	tasks, err := l.List(ctx, supabase.Filter{IDs: []int{id}})
+	if err != nil {
+		return nil, err
+	}
+	if len(tasks) == 0 {
+		return nil, supabase.ErrNotFound
+	}
+	return &tasks[0], nil
+}
+
+// Create stores t, filling in the same defaults as the tasks table.
+func (l *Local) Create(_ context.Context, t supabase.Task) (*supabase.Task, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	data, err := l.load()
+	if err != nil {
+		return nil, err
+	}
+	t.ID = data.NextID
+	if t.DueDate == "" {
+		t.DueDate = time.Now().AddDate(0, 0, 1).Format("2006-01-02")
+	}
+	if t.Priority == "" {
+		t.Priority = "P1"
+	}
+	if t.Status == "" {
+		t.Status = "Todo"
+	}
+	t.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+
+	data.NextID++
+	data.Tasks = append(data.Tasks, t)
+	if err := l.save(data); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Update patches every task matching f with fields and returns the updated rows.
+func (l *Local) Update(_ context.Context, f supabase.Filter, fields map[string]any) ([]supabase.Task, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	data, err := l.load()
+	if err != nil {
+		return nil, err
+	}
+	var updated []supabase.Task
+	for i, t := range data.Tasks {
+		if !matches(t, f) {
+			continue
+		}
+		patched, err := applyFields(t, fields)
+		if err != nil {
+			return nil, err
+		}
+		data.Tasks[i] = patched
+		updated = append(updated, patched)
+	}
+	if len(updated) == 0 {
+		return nil, nil
+	}
+	if err := l.save(data); err != nil {
+		return nil, err
+	}
+	return updated, nil
+}
+
+// UpdateByID patches a single task and returns it, or supabase.ErrNotFound.
+func (l *Local) UpdateByID(ctx context.Context, id int, fields map[string]any) (*supabase.Task, error) {
+	tasks, err := l.Update(ctx, supabase.Filter{IDs: []int{id}}, fields)
+	if err != nil {
+		return nil, err
+	}
+	if len(tasks) == 0 {
+		return nil, supabase.ErrNotFound
+	}
+	return &tasks[0], nil
+}
+
+// applyFields overlays a PostgREST-style patch onto t by round-tripping
+// through the task's JSON column names.
+func applyFields(t supabase.Task, fields map[string]any) (supabase.Task, error) {
+	row, err := toRow(t)
+	if err != nil {
+		return t, err
+	}
+	for k, v := range fields {
+		if k == "id" {
+			continue
+		}
+		row[k] = v
+	}
+	data, err := json.Marshal(row)
+	if err != nil {
+		return t, err
+	}
+	var out supabase.Task
+	if err := json.Unmarshal(data, &out); err != nil {
+		return t, fmt.Errorf("invalid update: %w", err)
+	}
+	out.ID = t.ID
+	return out, nil
+}
+
+func toRow(t supabase.Task) (map[string]any, error) {
+	data, err := json.Marshal(t)
+	if err != nil {
+		return nil, err
+	}
+	row := map[string]any{}
+	return row, json.Unmarshal(data, &row)
+}
+
+func selectTasks(all []supabase.Task, f supabase.Filter) []supabase.Task {
+	var out []supabase.Task
+	for _, t := range all {
+		if matches(t, f) {
+			out = append(out, t)
+		}
+	}
+	sortTasks(out, f.Order)
+	if f.Limit > 0 && len(out) > f.Limit {
+		out = out[:f.Limit]
+	}
+	return out
+}
+
+func matches(t supabase.Task, f supabase.Filter) bool {
+	if len(f.IDs) > 0 {
+		found := false
+		for _, id := range f.IDs {
+			if t.ID == id {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	if f.UserID != "" && t.UserID != f.UserID {
+		return false
+	}
+	if f.Status != "" && t.Status != f.Status {
+		return false
+	}
+	if f.ParentID != nil && (t.ParentID == nil || *t.ParentID != *f.ParentID) {
+		return false
+	}
+	if f.ParentID == nil && len(f.ParentIDs) > 0 && (t.ParentID == nil || !slices.Contains(f.ParentIDs, *t.ParentID)) {
+		return false
+	}
+	if f.DueFrom != "" && (t.DueDate == "" || t.DueDate < f.DueFrom) {
+		return false
+	}
+	if f.DueTo != "" && (t.DueDate == "" || t.DueDate > f.DueTo) {
+		return false
+	}
+	for _, tag := range f.Tags {
+		if !slices.Contains(t.Tags, tag) {
+			return false
+		}
+	}
+	if f.Project != "" && t.Project != f.Project {
+		return false
+	}
+	if len(f.Priorities) > 0 && !slices.Contains(f.Priorities, t.Priority) {
+		return false
+	}
+	if f.Search != "" && !strings.Contains(strings.ToLower(t.Title), strings.ToLower(f.Search)) {
+		return false
+	}
+	return true
+}
+
+// sortTasks orders tasks by PostgREST order terms ("column[.asc|.desc]").
+// Empty values sort last, as PostgREST does for ascending order.
+func sortTasks(tasks []supabase.Task, order []string) {
+	if len(order) == 0 {
+		sort.SliceStable(tasks, func(i, j int) bool { return tasks[i].ID < tasks[j].ID })
+		return
+	}
+	sort.SliceStable(tasks, func(i, j int) bool {
+		for _, term := range order {
+			col, dir, _ := strings.Cut(term, ".")
+			a, b := sortKey(tasks[i], col), sortKey(tasks[j], col)
+			if a == b {
+				continue
+			}
+			if a == "" || b == "" {
+				return b == ""
+			}
+			if dir == "desc" {
+				return a > b
+			}
+			return a < b
+		}
+		return false
+	})
+}
+
+func sortKey(t supabase.Task, col string) string {
+	switch col {
+	case "id":
+		return fmt.Sprintf("%010d", t.ID)
+	case "title":
+		return strings.ToLower(t.Title)
+	case "due_date":
+		return t.DueDate
+	case "priority":
+		return t.Priority
+	case "status":
+		return t.Status
+	case "parent_id":
+		if t.ParentID == nil {
+			return ""
+		}
+		return fmt.Sprintf("%010d", *t.ParentID)
+	case "created_at":
+		return t.CreatedAt
+	case "project":
+		return t.Project
+	}
+	return ""
+}
@@ -0,0 +1,132 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"todo-tracker/internal/supabase"
+)
+
+func TestLocalCreateListUpdate(t *testing.T) {
+	ctx := context.Background()
+	l := NewLocal(filepath.Join(t.TempDir(), "tasks.json"))
+
+	a, err := l.Create(ctx, supabase.Task{Title: "a", DueDate: "2026-02-03", Priority: "P2", UserID: "u"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := l.Create(ctx, supabase.Task{Title: "b", DueDate: "2026-02-01", UserID: "u"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if a.ID != 1 || b.ID != 2 || b.Priority != "P1" || b.Status != "Todo" || b.CreatedAt == "" {
+		t.Fatalf("defaults not applied: %+v %+v", a, b)
+	}
+
+	got, err := l.List(ctx, supabase.Filter{UserID: "u", Status: "Todo", Order: []string{"priority", "due_date"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].ID != b.ID {
+		t.Fatalf("order = %+v, want b first", got)
+	}
+
+	done, err := l.UpdateByID(ctx, a.ID, map[string]any{"status": "Done"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if done.Status != "Done" || done.Title != "a" {
+		t.Errorf("update = %+v", done)
+	}
+
+	got, _ = l.List(ctx, supabase.Filter{Status: "Todo"})
+	if len(got) != 1 || got[0].ID != b.ID {
+		t.Errorf("pending after done = %+v", got)
+	}
+}
+
+func TestLocalUpdateClearsParent(t *testing.T) {
+	ctx := context.Background()
+	l := NewLocal(filepath.Join(t.TempDir(), "tasks.json"))
+
+	parent, _ := l.Create(ctx, supabase.Task{Title: "p"})
+	child, _ := l.Create(ctx, supabase.Task{Title: "c", ParentID: &parent.ID})
+
+	kids, _ := l.List(ctx, supabase.Filter{ParentID: &parent.ID})
+	if len(kids) != 1 {
+		t.Fatalf("children = %+v", kids)
+	}
+	updated, err := l.UpdateByID(ctx, child.ID, map[string]any{"parent_id": nil})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.ParentID != nil {
+		t.Errorf("parent_id = %v, want nil", *updated.ParentID)
+	}
+}
+
+func TestLocalNotFound(t *testing.T) {
+	l := NewLocal(filepath.Join(t.TempDir(), "tasks.json"))
+	if _, err := l.Get(context.Background(), 1); !errors.Is(err, supabase.ErrNotFound) {
+		t.Errorf("Get err = %v", err)
+	}
+	if _, err := l.UpdateByID(context.Background(), 1, map[string]any{"status": "Done"}); !errors.Is(err, supabase.ErrNotFound) {
+		t.Errorf("UpdateByID err = %v", err)
+	}
+}
+
+func TestLocalFiltersByTagAndProject(t *testing.T) {
+	ctx := context.Background()
+	l := NewLocal(filepath.Join(t.TempDir(), "tasks.json"))
+
+	l.Create(ctx, supabase.Task{Title: "a", Tags: []string{"work", "urgent"}, Project: "infra"})
+	l.Create(ctx, supabase.Task{Title: "b", Tags: []string{"work"}})
+	l.Create(ctx, supabase.Task{Title: "c", Project: "infra"})
+
+	tests := []struct {
+		f    supabase.Filter
+		want []string
+	}{
+		{supabase.Filter{Tags: []string{"work"}}, []string{"a", "b"}},
+		{supabase.Filter{Tags: []string{"work", "urgent"}}, []string{"a"}},
+		{supabase.Filter{Project: "infra"}, []string{"a", "c"}},
+		{supabase.Filter{Tags: []string{"work"}, Project: "infra"}, []string{"a"}},
+	}
+	for _, tt := range tests {
+		got, err := l.List(ctx, tt.f)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var titles []string
+		for _, task := range got {
+			titles = append(titles, task.Title)
+		}
+		if strings.Join(titles, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("List(%+v) = %v, want %v", tt.f, titles, tt.want)
+		}
+	}
+}
+
+func TestLocalSearch(t *testing.T) {
+	ctx := context.Background()
+	l := NewLocal(filepath.Join(t.TempDir(), "tasks.json"))
+
+	l.Create(ctx, supabase.Task{Title: "Quarterly report and slides for the board"})
+	l.Create(ctx, supabase.Task{Title: "Report: draft the report", Status: "Done"})
+	l.Create(ctx, supabase.Task{Title: "Buy milk"})
+
+	got, err := l.Search(ctx, "reports", supabase.Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(got) != 2 || got[0].ID != 2 || got[1].ID != 1 {
+		t.Errorf("Search = %+v, want the closer match first", got)
+	}
+	got, _ = l.Search(ctx, "report", supabase.Filter{Status: "Todo", Limit: 1})
+	if len(got) != 1 || got[0].ID != 1 {
+		t.Errorf("Search(Status: Todo) = %+v", got)
+	}
+}
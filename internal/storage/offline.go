@@ -0,0 +1,484 @@
+package storage
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"todo-tracker/internal/supabase"
+)
+
+// ErrQueued reports that a change could not reach the server and was saved
+// to the offline queue for a later Flush. It is supabase.ErrQueued.
+var ErrQueued = supabase.ErrQueued
+
+// Op is a journaled mutation waiting to be replayed.
+type Op struct {
+	Kind     string           `json:"kind"` // "create" or "update"
+	Task     *supabase.Task   `json:"task,omitempty"`
+	Filter   *supabase.Filter `json:"filter,omitempty"`
+	Fields   map[string]any   `json:"fields,omitempty"`
+	Before   map[string]any   `json:"before,omitempty"` // task ID -> last seen values of Fields
+	QueuedAt string           `json:"queued_at"`
+}
+
+func (o Op) String() string {
+	if o.Kind == "create" && o.Task != nil {
+		return fmt.Sprintf("add %q", o.Task.Title)
+	}
+	target := "tasks"
+	if o.Filter != nil && len(o.Filter.IDs) > 0 {
+		ids := make([]string, len(o.Filter.IDs))
+		for i, id := range o.Filter.IDs {
+			ids[i] = "#" + strconv.Itoa(id)
+		}
+		target = strings.Join(ids, ",")
+	}
+	keys := make([]string, 0, len(o.Fields))
+	for k := range o.Fields {
+		keys = append(keys, fmt.Sprintf("%s=%v", k, o.Fields[k]))
+	}
+	sort.Strings(keys)
+	return fmt.Sprintf("update %s %s", target, strings.Join(keys, " "))
+}
+
+// Conflict is a queued update whose target row changed on the server after
+// the CLI last saw it. Field is empty when the task no longer exists.
+type Conflict struct {
+	Op     Op
+	TaskID int
+	Field  string
+	Seen   any // value when the change was queued
+	Server any // value on the server at replay time
+}
+
+// Failure is a queued op the server rejected during replay.
+type Failure struct {
+	Op  Op
+	Err error
+}
+
+// SyncReport summarises a Flush.
+type SyncReport struct {
+	Applied   int
+	Skipped   int // conflicting ops discarded with SkipConflicts
+	Conflicts []Conflict
+	Failed    []Failure
+	Pending   int   // ops left in the queue, offline or blocked by a conflict
+	Stopped   error // why replay stopped early when it was not a conflict
+}
+
+// Resolution says what Flush does with an op that conflicts with the server.
+type Resolution int
+
+const (
+	// StopOnConflict leaves the conflicting op and everything after it queued.
+	StopOnConflict Resolution = iota
+	// ForceConflicts applies conflicting ops anyway, overwriting the server.
+	ForceConflicts
+	// SkipConflicts discards conflicting ops and carries on.
+	SkipConflicts
+)
+
+// Offline wraps a remote Storage, journaling mutations that fail with a
+// network, server or rate-limit error to an append-only queue and replaying them in order once
+// the server is reachable again.
+type Offline struct {
+	remote    Storage
+	queuePath string
+	cachePath string
+	mu        sync.Mutex
+
+	// OnFlush, if set, is called after an opportunistic flush replays
+	// queued ops ahead of a regular call.
+	OnFlush func(SyncReport)
+}
+
+// NewOffline wraps remote, keeping its queue and row cache in dir.
+func NewOffline(remote Storage, dir string) *Offline {
+	return &Offline{
+		remote:    remote,
+		queuePath: filepath.Join(dir, "queue.jsonl"),
+		cachePath: filepath.Join(dir, "cache.json"),
+	}
+}
+
+// Pending returns the queued ops in replay order.
+func (o *Offline) Pending() ([]Op, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	return o.readQueue()
+}
+
+// List returns the tasks matching f from the remote.
+func (o *Offline) List(ctx context.Context, f supabase.Filter) ([]supabase.Task, error) {
+	o.autoFlush(ctx)
+	tasks, err := o.remote.List(ctx, f)
+	if err == nil {
+		o.remember(tasks...)
+	}
+	return tasks, err
+}
+
+// Search runs a full-text search on the remote.
+func (o *Offline) Search(ctx context.Context, query string, f supabase.Filter) ([]supabase.Task, error) {
+	o.autoFlush(ctx)
+	tasks, err := o.remote.Search(ctx, query, f)
+	if err == nil {
+		o.remember(tasks...)
+	}
+	return tasks, err
+}
+
+// Get returns a task from the remote.
+func (o *Offline) Get(ctx context.Context, id int) (*supabase.Task, error) {
+	o.autoFlush(ctx)
+	t, err := o.remote.Get(ctx, id)
+	if err == nil {
+		o.remember(*t)
+	}
+	return t, err
+}
+
+// Create inserts t, or queues it and returns ErrQueued when the server
+// cannot take it right now.
+func (o *Offline) Create(ctx context.Context, t supabase.Task) (*supabase.Task, error) {
+	op := Op{Kind: "create", Task: &t}
+	if o.autoFlush(ctx) > 0 {
+		return nil, o.enqueue(op)
+	}
+	created, err := o.remote.Create(ctx, t)
+	if transient(err) {
+		return nil, o.enqueue(op)
+	}
+	if err == nil {
+		o.remember(*created)
+	}
+	return created, err
+}
+
+// Update patches the matching tasks, or queues the patch and returns
+// ErrQueued when the server cannot take it right now.
+func (o *Offline) Update(ctx context.Context, f supabase.Filter, fields map[string]any) ([]supabase.Task, error) {
+	op := Op{Kind: "update", Filter: &f, Fields: fields, Before: o.seen(f.IDs, fields)}
+	if o.autoFlush(ctx) > 0 {
+		return nil, o.enqueue(op)
+	}
+	tasks, err := o.remote.Update(ctx, f, fields)
+	if transient(err) {
+		return nil, o.enqueue(op)
+	}
+	if err == nil {
+		o.remember(tasks...)
+	}
+	return tasks, err
+}
+
+// UpdateByID patches a single task, or queues the patch and returns
+// ErrQueued when offline.
+func (o *Offline) UpdateByID(ctx context.Context, id int, fields map[string]any) (*supabase.Task, error) {
+	tasks, err := o.Update(ctx, supabase.Filter{IDs: []int{id}}, fields)
+	if err != nil {
+		return nil, err
+	}
+	if len(tasks) == 0 {
+		return nil, supabase.ErrNotFound
+	}
+	return &tasks[0], nil
+}
+
+// autoFlush replays any queued ops before a regular call and returns how
+// many remain queued.
+func (o *Offline) autoFlush(ctx context.Context) int {
+	o.mu.Lock()
+	ops, _ := o.readQueue()
+	o.mu.Unlock()
+	if len(ops) == 0 {
+		return 0
+	}
+	report, err := o.Flush(ctx, StopOnConflict)
+	if err != nil {
+		return len(ops)
+	}
+	if o.OnFlush != nil && (report.Applied > 0 || len(report.Conflicts) > 0 || len(report.Failed) > 0) {
+		o.OnFlush(report)
+	}
+	return report.Pending
+}
+
+// Flush replays queued ops in order. An update whose target row changed on
+// the server since it was queued is handled according to resolve. Replay
+// stops at the first error that may pass, leaving the rest of the queue in
+// place: no response, a server error, rate limiting, an auth failure or a
+// cancelled ctx. Only ops the server rejects outright, as not found, a
+// constraint violation or denied by row-level security, are dropped and
+// reported as failures.
+func (o *Offline) Flush(ctx context.Context, resolve Resolution) (SyncReport, error) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+
+	var report SyncReport
+	ops, err := o.readQueue()
+	if err != nil {
+		return report, err
+	}
+
+	for i, op := range ops {
+		conflicts, err := o.replay(ctx, op, resolve == ForceConflicts)
+		if err != nil && !rejected(err) {
+			if ctxErr := ctx.Err(); ctxErr != nil {
+				err = ctxErr
+			}
+			report.Pending = len(ops) - i
+			report.Stopped = err
+			return report, o.writeQueue(ops[i:])
+		}
+		if err != nil {
+			report.Failed = append(report.Failed, Failure{Op: op, Err: err})
+			continue
+		}
+		if len(conflicts) > 0 {
+			report.Conflicts = append(report.Conflicts, conflicts...)
+			if resolve == SkipConflicts {
+				report.Skipped++
+				continue
+			}
+			report.Pending = len(ops) - i
+			return report, o.writeQueue(ops[i:])
+		}
+		report.Applied++
+	}
+	return report, o.writeQueue(nil)
+}
+
+// replay applies op to the remote unless it conflicts with the server's
+// current state and force is unset.
+func (o *Offline) replay(ctx context.Context, op Op, force bool) ([]Conflict, error) {
+	switch op.Kind {
+	case "create":
+		if op.Task == nil {
+			return nil, fmt.Errorf("%w: create has no task", errBadOp)
+		}
+		created, err := o.remote.Create(ctx, *op.Task)
+		if err != nil {
+			return nil, err
+		}
+		o.rememberLocked(*created)
+	case "update":
+		if op.Filter == nil {
+			return nil, fmt.Errorf("%w: update has no filter", errBadOp)
+		}
+		if !force {
+			conflicts, err := o.conflicts(ctx, op)
+			if err != nil || len(conflicts) > 0 {
+				return conflicts, err
+			}
+		}
+		tasks, err := o.remote.Update(ctx, *op.Filter, op.Fields)
+		if err != nil {
+			return nil, err
+		}
+		o.rememberLocked(tasks...)
+	default:
+		return nil, fmt.Errorf("%w: unknown kind %q", errBadOp, op.Kind)
+	}
+	return nil, nil
+}
+
+// errBadOp marks a queued op that can never be replayed.
+var errBadOp = errors.New("malformed queued op")
+
+// transient reports whether err may pass if the call is tried again later:
+// the request got no response, or the server was failing or rate limiting.
+func transient(err error) bool {
+	return supabase.IsNetworkError(err) || errors.Is(err, supabase.ErrServer) || errors.Is(err, supabase.ErrRateLimit)
+}
+
+// rejected reports whether err is a definitive answer for a queued op, so
+// that replaying it again can only fail the same way.
+func rejected(err error) bool {
+	return errors.Is(err, supabase.ErrNotFound) || errors.Is(err, supabase.ErrConstraint) ||
+		supabase.IsRowSecurityError(err) || errors.Is(err, errBadOp)
+}
+
+// conflicts compares the server's current values against what the CLI had
+// seen when the op was queued. A field already holding the queued value is
+// not a conflict.
+func (o *Offline) conflicts(ctx context.Context, op Op) ([]Conflict, error) {
+	var out []Conflict
+	for key, raw := range op.Before {
+		id, err := strconv.Atoi(key)
+		if err != nil {
+			continue
+		}
+		seen, _ := raw.(map[string]any)
+		server, err := o.remote.Get(ctx, id)
+		if errors.Is(err, supabase.ErrNotFound) {
+			out = append(out, Conflict{Op: op, TaskID: id})
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		row, err := toRow(*server)
+		if err != nil {
+			return nil, err
+		}
+		for field, seenVal := range seen {
+			now := normalize(row[field])
+			if reflect.DeepEqual(now, normalize(seenVal)) || reflect.DeepEqual(now, normalize(op.Fields[field])) {
+				continue
+			}
+			out = append(out, Conflict{Op: op, TaskID: id, Field: field, Seen: seenVal, Server: row[field]})
+		}
+	}
+	return out, nil
+}
+
+func (o *Offline) enqueue(op Op) error {
+	op.QueuedAt = time.Now().UTC().Format(time.RFC3339)
+	line, err := json.Marshal(op)
+	if err != nil {
+		return err
+	}
+
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	if err := os.MkdirAll(filepath.Dir(o.queuePath), 0700); err != nil {
+		return err
+	}
+	f, err := os.OpenFile(o.queuePath, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	if _, err := f.Write(append(line, '\n')); err != nil {
+		return err
+	}
+	return ErrQueued
+}
+
+func (o *Offline) readQueue() ([]Op, error) {
+	data, err := os.ReadFile(o.queuePath)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var ops []Op
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	for sc.Scan() {
+		if len(bytes.TrimSpace(sc.Bytes())) == 0 {
+			continue
+		}
+		var op Op
+		if err := json.Unmarshal(sc.Bytes(), &op); err != nil {
+			return nil, fmt.Errorf("parse %s: %w", o.queuePath, err)
+		}
+		ops = append(ops, op)
+	}
+	return ops, sc.Err()
+}
+
+func (o *Offline) writeQueue(ops []Op) error {
+	if len(ops) == 0 {
+		err := os.Remove(o.queuePath)
+		if errors.Is(err, os.ErrNotExist) {
+			return nil
+		}
+		return err
+	}
+	var buf bytes.Buffer
+	for _, op := range ops {
+		line, err := json.Marshal(op)
+		if err != nil {
+			return err
+		}
+		buf.Write(line)
+		buf.WriteByte('\n')
+	}
+	return writeFileAtomic(o.queuePath, buf.Bytes())
+}
+
+// seen returns the last values the CLI saw for fields on each task in ids,
+// keyed by task ID, for conflict detection at replay time.
+func (o *Offline) seen(ids []int, fields map[string]any) map[string]any {
+	o.mu.Lock()
+	cache := o.readCache()
+	o.mu.Unlock()
+
+	out := map[string]any{}
+	for _, id := range ids {
+		t, ok := cache[strconv.Itoa(id)]
+		if !ok {
+			continue
+		}
+		row, err := toRow(t)
+		if err != nil {
+			continue
+		}
+		vals := map[string]any{}
+		for k := range fields {
+			vals[k] = row[k]
+		}
+		out[strconv.Itoa(id)] = vals
+	}
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+func (o *Offline) remember(tasks ...supabase.Task) {
+	o.mu.Lock()
+	defer o.mu.Unlock()
+	o.rememberLocked(tasks...)
+}
+
+// rememberLocked records the latest server state of tasks. The cache is
+// best effort; failures only weaken conflict detection.
+func (o *Offline) rememberLocked(tasks ...supabase.Task) {
+	if len(tasks) == 0 {
+		return
+	}
+	cache := o.readCache()
+	for _, t := range tasks {
+		cache[strconv.Itoa(t.ID)] = t
+	}
+	if data, err := json.Marshal(cache); err == nil {
+		writeFileAtomic(o.cachePath, data)
+	}
+}
+
+func (o *Offline) readCache() map[string]supabase.Task {
+	cache := map[string]supabase.Task{}
+	if data, err := os.ReadFile(o.cachePath); err == nil {
+		json.Unmarshal(data, &cache)
+	}
+	return cache
+}
+
+// normalize maps a value onto its JSON form so values read back from the
+// queue compare equal to freshly decoded server rows.
+func normalize(v any) any {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return v
+	}
+	var out any
+	json.Unmarshal(data, &out)
+	return out
+}
@@ -0,0 +1,242 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"net"
+	"path/filepath"
+	"testing"
+
+	"todo-tracker/internal/supabase"
+)
+
+// flaky wraps a Storage and fails every call with a network error while
+// down, with err when it is set, and with ctx's error once ctx is done.
+type flaky struct {
+	Storage
+	down bool
+	err  error
+}
+
+var errOffline = &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("network is unreachable")}
+
+func (f *flaky) fail(ctx context.Context) error {
+	switch {
+	case ctx.Err() != nil:
+		return ctx.Err()
+	case f.err != nil:
+		return f.err
+	case f.down:
+		return errOffline
+	}
+	return nil
+}
+
+func (f *flaky) List(ctx context.Context, q supabase.Filter) ([]supabase.Task, error) {
+	if err := f.fail(ctx); err != nil {
+		return nil, err
+	}
+	return f.Storage.List(ctx, q)
+}
+
+func (f *flaky) Get(ctx context.Context, id int) (*supabase.Task, error) {
+	if err := f.fail(ctx); err != nil {
+		return nil, err
+	}
+	return f.Storage.Get(ctx, id)
+}
+
+func (f *flaky) Create(ctx context.Context, t supabase.Task) (*supabase.Task, error) {
+	if err := f.fail(ctx); err != nil {
+		return nil, err
+	}
+	return f.Storage.Create(ctx, t)
+}
+
+func (f *flaky) Update(ctx context.Context, q supabase.Filter, fields map[string]any) ([]supabase.Task, error) {
+	if err := f.fail(ctx); err != nil {
+		return nil, err
+	}
+	return f.Storage.Update(ctx, q, fields)
+}
+
+func newOfflineTest(t *testing.T) (*Offline, *flaky, *Local) {
+	t.Helper()
+	dir := t.TempDir()
+	server := NewLocal(filepath.Join(dir, "server.json"))
+	remote := &flaky{Storage: server}
+	return NewOffline(remote, dir), remote, server
+}
+
+func TestOfflineQueuesAndReplaysInOrder(t *testing.T) {
+	ctx := context.Background()
+	o, remote, server := newOfflineTest(t)
+
+	task, err := o.Create(ctx, supabase.Task{Title: "a"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	remote.down = true
+	if _, err := o.Create(ctx, supabase.Task{Title: "b"}); !errors.Is(err, ErrQueued) {
+		t.Fatalf("Create err = %v, want ErrQueued", err)
+	}
+	if _, err := o.UpdateByID(ctx, task.ID, map[string]any{"status": "Done"}); !errors.Is(err, ErrQueued) {
+		t.Fatalf("UpdateByID err = %v, want ErrQueued", err)
+	}
+	if ops, _ := o.Pending(); len(ops) != 2 {
+		t.Fatalf("pending = %d, want 2", len(ops))
+	}
+
+	remote.down = false
+	var flushed SyncReport
+	o.OnFlush = func(r SyncReport) { flushed = r }
+	if _, err := o.List(ctx, supabase.Filter{}); err != nil {
+		t.Fatal(err)
+	}
+	if flushed.Applied != 2 {
+		t.Fatalf("opportunistic flush applied %d, want 2", flushed.Applied)
+	}
+	if ops, _ := o.Pending(); len(ops) != 0 {
+		t.Errorf("queue not drained: %v", ops)
+	}
+	got, _ := server.Get(ctx, task.ID)
+	if got.Status != "Done" {
+		t.Errorf("status = %q, want Done", got.Status)
+	}
+	if all, _ := server.List(ctx, supabase.Filter{}); len(all) != 2 {
+		t.Errorf("server has %d tasks, want 2", len(all))
+	}
+}
+
+func TestOfflineDetectsConflicts(t *testing.T) {
+	ctx := context.Background()
+	o, remote, server := newOfflineTest(t)
+
+	task, _ := o.Create(ctx, supabase.Task{Title: "a", DueDate: "2026-02-01"})
+
+	remote.down = true
+	o.UpdateByID(ctx, task.ID, map[string]any{"due_date": "2026-02-05"})
+	o.Create(ctx, supabase.Task{Title: "later"})
+
+	// Someone else moves the task while we are offline.
+	server.UpdateByID(ctx, task.ID, map[string]any{"due_date": "2026-02-03"})
+	remote.down = false
+
+	report, err := o.Flush(ctx, StopOnConflict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Conflicts) != 1 || report.Conflicts[0].Field != "due_date" || report.Pending != 2 {
+		t.Fatalf("report = %+v, want one due_date conflict blocking 2 ops", report)
+	}
+
+	report, err = o.Flush(ctx, SkipConflicts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Skipped != 1 || report.Applied != 1 || report.Pending != 0 {
+		t.Fatalf("report = %+v, want 1 skipped, 1 applied", report)
+	}
+	got, _ := server.Get(ctx, task.ID)
+	if got.DueDate != "2026-02-03" {
+		t.Errorf("due_date = %q, server change should win", got.DueDate)
+	}
+}
+
+func TestOfflineFlushKeepsQueueUntilServerAnswers(t *testing.T) {
+	for _, tc := range []struct {
+		name   string
+		err    error
+		queues bool // whether a live change is queued rather than failing
+	}{
+		{"unavailable", &supabase.APIError{StatusCode: 503, Message: "Service Unavailable"}, true},
+		{"rate limited", &supabase.APIError{StatusCode: 429, Message: "Too Many Requests"}, true},
+		{"jwt expired", &supabase.APIError{StatusCode: 401, Code: "PGRST303", Message: "JWT expired"}, false},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			ctx := context.Background()
+			o, remote, server := newOfflineTest(t)
+			task, _ := o.Create(ctx, supabase.Task{Title: "a"})
+
+			remote.err = tc.err
+			if _, err := o.Create(ctx, supabase.Task{Title: "b"}); errors.Is(err, ErrQueued) != tc.queues {
+				t.Fatalf("Create err = %v, queued = %v", err, tc.queues)
+			}
+			remote.err = nil
+			remote.down = true
+			o.UpdateByID(ctx, task.ID, map[string]any{"status": "Done"})
+			o.Create(ctx, supabase.Task{Title: "c"})
+			queued, _ := o.Pending()
+
+			remote.down = false
+			remote.err = tc.err
+			report, err := o.Flush(ctx, StopOnConflict)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.Applied != 0 || len(report.Failed) != 0 || report.Pending != len(queued) || !errors.Is(report.Stopped, tc.err) {
+				t.Fatalf("report = %+v, want all %d ops kept", report, len(queued))
+			}
+			if ops, _ := o.Pending(); len(ops) != len(queued) {
+				t.Fatalf("pending = %d, want %d", len(ops), len(queued))
+			}
+
+			remote.err = nil
+			if report, err := o.Flush(ctx, StopOnConflict); err != nil || report.Applied != len(queued) {
+				t.Fatalf("report = %+v, err = %v, want %d applied", report, err, len(queued))
+			}
+			if got, _ := server.Get(ctx, task.ID); got.Status != "Done" {
+				t.Errorf("status = %q, want Done", got.Status)
+			}
+		})
+	}
+}
+
+func TestOfflineFlushKeepsQueueWhenCancelled(t *testing.T) {
+	o, remote, _ := newOfflineTest(t)
+
+	remote.down = true
+	o.Create(context.Background(), supabase.Task{Title: "a"})
+	o.Create(context.Background(), supabase.Task{Title: "b"})
+	remote.down = false
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	report, err := o.Flush(ctx, StopOnConflict)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if report.Pending != 2 || len(report.Failed) != 0 || !errors.Is(report.Stopped, context.Canceled) {
+		t.Fatalf("report = %+v, want both ops kept", report)
+	}
+	if ops, _ := o.Pending(); len(ops) != 2 {
+		t.Fatalf("pending = %d, want 2", len(ops))
+	}
+}
+
+func TestOfflineFlushDropsRejectedOps(t *testing.T) {
+	for _, rejection := range []error{
+		&supabase.APIError{StatusCode: 409, Code: "23505", Message: "duplicate key"},
+		&supabase.APIError{StatusCode: 403, Code: "42501", Message: "new row violates row-level security policy"},
+	} {
+		ctx := context.Background()
+		o, remote, _ := newOfflineTest(t)
+
+		remote.down = true
+		o.Create(ctx, supabase.Task{Title: "a"})
+		remote.down = false
+
+		remote.err = rejection
+		report, err := o.Flush(ctx, StopOnConflict)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(report.Failed) != 1 || report.Pending != 0 {
+			t.Fatalf("%v: report = %+v, want the op rejected", rejection, report)
+		}
+		if ops, _ := o.Pending(); len(ops) != 0 {
+			t.Errorf("%v: pending = %d, want 0", rejection, len(ops))
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package storage
+
+import (
+	"context"
+	"sync"
+
+	"todo-tracker/internal/supabase"
+)
+
+// Reauth wraps a remote Storage whose credentials may have gone stale.
+// When a call is rejected because its token expired or was not accepted it
+// calls Refresh, which re-verifies the credentials, and retries the call
+// once if that worked. Row-level security rejections are returned as they
+// are, since fresh credentials would not change them.
+type Reauth struct {
+	s       Storage
+	refresh func(ctx context.Context) error
+	mu      sync.Mutex
+}
+
+// NewReauth wraps s, calling refresh after a rejected token.
+// refresh returns an error when the credentials are no longer valid.
+func NewReauth(s Storage, refresh func(ctx context.Context) error) *Reauth {
+	return &Reauth{s: s, refresh: refresh}
+}
+
+// retry runs op, and again after a successful refresh if it was rejected.
+func retry[T any](ctx context.Context, r *Reauth, op func() (T, error)) (T, error) {
+	v, err := op()
+	if !supabase.IsExpiredToken(err) {
+		return v, err
+	}
+	r.mu.Lock()
+	rerr := r.refresh(ctx)
+	r.mu.Unlock()
+	if rerr != nil {
+		var zero T
+		return zero, rerr
+	}
+	return op()
+}
+
+// List returns the tasks matching f.
+func (r *Reauth) List(ctx context.Context, f supabase.Filter) ([]supabase.Task, error) {
+	return retry(ctx, r, func() ([]supabase.Task, error) { return r.s.List(ctx, f) })
+}
+
+// Search runs a full-text search.
+func (r *Reauth) Search(ctx context.Context, query string, f supabase.Filter) ([]supabase.Task, error) {
+	return retry(ctx, r, func() ([]supabase.Task, error) { return r.s.Search(ctx, query, f) })
+}
+
+// Get returns a task.
+func (r *Reauth) Get(ctx context.Context, id int) (*supabase.Task, error) {
+	return retry(ctx, r, func() (*supabase.Task, error) { return r.s.Get(ctx, id) })
+}
+
+// Create inserts t.
+func (r *Reauth) Create(ctx context.Context, t supabase.Task) (*supabase.Task, error) {
+	return retry(ctx, r, func() (*supabase.Task, error) { return r.s.Create(ctx, t) })
+}
+
+// Update patches the matching tasks.
+func (r *Reauth) Update(ctx context.Context, f supabase.Filter, fields map[string]any) ([]supabase.Task, error) {
+	return retry(ctx, r, func() ([]supabase.Task, error) { return r.s.Update(ctx, f, fields) })
+}
+
+// UpdateByID patches one task.
+func (r *Reauth) UpdateByID(ctx context.Context, id int, fields map[string]any) (*supabase.Task, error) {
+	return retry(ctx, r, func() (*supabase.Task, error) { return r.s.UpdateByID(ctx, id, fields) })
+}
@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"testing"
+
+	"todo-tracker/internal/supabase"
+)
+
+// expiring rejects calls until its credentials are renewed.
+type expiring struct {
+	Storage
+	stale bool
+}
+
+func (e *expiring) List(ctx context.Context, f supabase.Filter) ([]supabase.Task, error) {
+	if e.stale {
+		return nil, &supabase.APIError{StatusCode: 401, Message: "JWT expired"}
+	}
+	return e.Storage.List(ctx, f)
+}
+
+func TestReauthRetriesAfterRefresh(t *testing.T) {
+	ctx := context.Background()
+	local := NewLocal(filepath.Join(t.TempDir(), "tasks.json"))
+	local.Create(ctx, supabase.Task{Title: "Pay rent"})
+	remote := &expiring{Storage: local, stale: true}
+
+	refreshed := 0
+	r := NewReauth(remote, func(context.Context) error {
+		refreshed++
+		remote.stale = false
+		return nil
+	})
+	found, err := r.List(ctx, supabase.Filter{})
+	if err != nil || len(found) != 1 || refreshed != 1 {
+		t.Fatalf("List = %v, %v after %d refreshes", found, err, refreshed)
+	}
+	if _, err := r.List(ctx, supabase.Filter{}); err != nil || refreshed != 1 {
+		t.Errorf("second List = %v after %d refreshes", err, refreshed)
+	}
+}
+
+func TestReauthReportsRevokedCredentials(t *testing.T) {
+	local := NewLocal(filepath.Join(t.TempDir(), "tasks.json"))
+	remote := &expiring{Storage: local, stale: true}
+	revoked := &supabase.APIError{StatusCode: 401, Message: "Token expired"}
+
+	r := NewReauth(remote, func(context.Context) error { return revoked })
+	if _, err := r.List(context.Background(), supabase.Filter{}); !errors.Is(err, revoked) {
+		t.Errorf("List err = %v", err)
+	}
+}
+
+// denied rejects every List as row-level security would.
+type denied struct{ Storage }
+
+func (denied) List(context.Context, supabase.Filter) ([]supabase.Task, error) {
+	return nil, &supabase.APIError{StatusCode: 403, Code: "42501", Message: "permission denied for table tasks"}
+}
+
+func TestReauthPassesRowSecurityErrorsThrough(t *testing.T) {
+	local := NewLocal(filepath.Join(t.TempDir(), "tasks.json"))
+	refreshed := 0
+	r := NewReauth(denied{local}, func(context.Context) error {
+		refreshed++
+		return nil
+	})
+	if _, err := r.List(context.Background(), supabase.Filter{}); !supabase.IsRowSecurityError(err) || refreshed != 0 {
+		t.Errorf("List err = %v after %d refreshes, want the 42501 without a refresh", err, refreshed)
+	}
+}
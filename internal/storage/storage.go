@@ -0,0 +1,21 @@
+// Package storage abstracts where tasks live so the CLI can run against
+// Supabase or a local file with the same commands.
+package storage
+
+import (
+	"context"
+
+	"todo-tracker/internal/supabase"
+)
+
+// Storage is the set of task operations the CLI needs from a backend.
+type Storage interface {
+	List(ctx context.Context, f supabase.Filter) ([]supabase.Task, error)
+	Get(ctx context.Context, id int) (*supabase.Task, error)
+	Create(ctx context.Context, t supabase.Task) (*supabase.Task, error)
+	Update(ctx context.Context, f supabase.Filter, fields map[string]any) ([]supabase.Task, error)
+	UpdateByID(ctx context.Context, id int, fields map[string]any) (*supabase.Task, error)
+	Search(ctx context.Context, query string, f supabase.Filter) ([]supabase.Task, error)
+}
+
+var _ Storage = (*supabase.TaskStore)(nil)
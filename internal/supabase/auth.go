@@ -0,0 +1,104 @@
+package supabase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// TokenInfo describes a CLI API token as reported by the auth-verify function.
+type TokenInfo struct {
+	Valid     bool   `json:"valid"`
+	UserID    string `json:"user_id"`
+	TokenName string `json:"token_name"`
+	TokenID   int    `json:"token_id"`
+	ExpiresAt string `json:"expires_at"`
+
+	// AccessToken is a short-lived JWT for UserID, to pass to
+	// SetAccessToken, valid until AccessExpiresAt (RFC 3339).
+	AccessToken     string `json:"access_token"`
+	AccessExpiresAt string `json:"access_expires_at"`
+}
+
+// VerifyToken exchanges a CLI API token for the user it belongs to and an
+// access token that acts as them.
+func (c *Client) VerifyToken(ctx context.Context, token string) (*TokenInfo, error) {
+	var info TokenInfo
+	if err := c.function(ctx, "GET", "auth-verify", token, nil, &info); err != nil {
+		return nil, err
+	}
+	if !info.Valid || info.UserID == "" {
+		return nil, &APIError{StatusCode: 401, Message: "token not accepted"}
+	}
+	if info.AccessToken == "" {
+		return nil, errors.New("supabase: auth-verify issued no access token; deploy the current auth-verify function")
+	}
+	return &info, nil
+}
+
+// APIToken is one of a user's CLI tokens, as the bot's /revoke lists them.
+type APIToken struct {
+	ID        int    `json:"id"`
+	Name      string `json:"name"`
+	CreatedAt string `json:"created_at"`
+	ExpiresAt string `json:"expires_at"`
+	Current   bool   `json:"current"` // the token the request was made with
+}
+
+// ListTokens returns the tokens of the user token belongs to, newest first.
+func (c *Client) ListTokens(ctx context.Context, token string) ([]APIToken, error) {
+	var tokens []APIToken
+	if err := c.function(ctx, "GET", "auth-verify/tokens", token, nil, &tokens); err != nil {
+		return nil, err
+	}
+	return tokens, nil
+}
+
+// RevokeToken deletes the user's token id, authenticating with token, and
+// returns what it was.
+func (c *Client) RevokeToken(ctx context.Context, token string, id int) (*APIToken, error) {
+	var revoked APIToken
+	if err := c.function(ctx, "DELETE", fmt.Sprintf("auth-verify/tokens/%d", id), token, nil, &revoked); err != nil {
+		return nil, err
+	}
+	return &revoked, nil
+}
+
+// Pairing is a pending `todo login`: the user sends Code to the bot with
+// /login while the CLI polls with DeviceCode.
+type Pairing struct {
+	Code       string `json:"code"`
+	DeviceCode string `json:"device_code"`
+	ExpiresAt  string `json:"expires_at"`
+	Interval   int    `json:"interval"` // seconds between polls
+}
+
+// PairingResult is the answer to a poll: Pending until the code is
+// approved, then the new token.
+type PairingResult struct {
+	Pending   bool   `json:"pending"`
+	Token     string `json:"token"`
+	UserID    string `json:"user_id"`
+	TokenName string `json:"token_name"`
+}
+
+// StartPairing asks for a pairing code for a new token called name.
+func (c *Client) StartPairing(ctx context.Context, name string) (*Pairing, error) {
+	var p Pairing
+	body := map[string]string{"name": name}
+	if err := c.function(ctx, "POST", "auth-verify/pair", c.APIKey, body, &p); err != nil {
+		return nil, err
+	}
+	return &p, nil
+}
+
+// PollPairing checks once whether the pairing was approved. An expired
+// pairing is an *APIError with status 410.
+func (c *Client) PollPairing(ctx context.Context, deviceCode string) (*PairingResult, error) {
+	var r PairingResult
+	body := map[string]string{"device_code": deviceCode}
+	if err := c.function(ctx, "POST", "auth-verify/pair/poll", c.APIKey, body, &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
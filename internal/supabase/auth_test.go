@@ -0,0 +1,113 @@
+package supabase
+
+import (
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestClient(t *testing.T, status int, body string) (*Client, *fakePostgREST) {
+	t.Helper()
+	fake := &fakePostgREST{status: status, body: body}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	c := NewClient(srv.URL, "anon")
+	c.Backoff = time.Millisecond
+	return c, fake
+}
+
+func TestVerifyToken(t *testing.T) {
+	c, fake := newTestClient(t, 200, `{"valid":true,"user_id":"42","token_name":"laptop","token_id":7,"expires_at":"2027-10-17T00:00:00Z","access_token":"jwt","access_expires_at":"2026-10-17T10:00:00Z"}`)
+	info, err := c.VerifyToken(context.Background(), "tok")
+	if err != nil || info.UserID != "42" || info.TokenID != 7 || info.ExpiresAt == "" || info.AccessToken != "jwt" {
+		t.Fatalf("VerifyToken = %+v, %v", info, err)
+	}
+	if got := fake.last.Header.Get("Authorization"); got != "Bearer tok" {
+		t.Errorf("Authorization = %q", got)
+	}
+
+	// An auth-verify from before access tokens would leave requests
+	// anonymous, which row-level security answers with nothing.
+	fake.body = `{"valid":true,"user_id":"42","token_name":"laptop","token_id":7}`
+	if _, err := c.VerifyToken(context.Background(), "tok"); err == nil {
+		t.Error("verification without an access token accepted")
+	}
+}
+
+func TestAccessToken(t *testing.T) {
+	c, fake := newTestClient(t, 200, `[]`)
+	store := NewTaskStore(c).ForUser("42")
+	store.List(context.Background(), Filter{})
+	if got := fake.last.Header.Get("Authorization"); got != "Bearer anon" {
+		t.Errorf("Authorization before SetAccessToken = %q", got)
+	}
+	c.SetAccessToken("jwt")
+	store.UpdateByID(context.Background(), 3, map[string]any{"status": "Done"})
+	if got := fake.last.Header.Get("Authorization"); got != "Bearer jwt" {
+		t.Errorf("Authorization = %q", got)
+	}
+	if got := fake.last.Header.Get("apikey"); got != "anon" {
+		t.Errorf("apikey = %q", got)
+	}
+	if q := fake.last.URL.Query(); q.Get("id") != "eq.3" || q.Get("user_id") != "eq.42" {
+		t.Errorf("update not scoped to the user: %s", fake.last.URL.RawQuery)
+	}
+}
+
+func TestListAndRevokeTokens(t *testing.T) {
+	c, fake := newTestClient(t, 200, `[{"id":7,"name":"laptop","created_at":"2026-10-01T00:00:00Z","expires_at":"2027-10-01T00:00:00Z","current":true},{"id":3,"name":"old"}]`)
+	tokens, err := c.ListTokens(context.Background(), "tok")
+	if err != nil || len(tokens) != 2 || !tokens[0].Current || tokens[1].Name != "old" {
+		t.Fatalf("ListTokens = %+v, %v", tokens, err)
+	}
+	if fake.last.Method != "GET" || fake.last.URL.Path != "/functions/v1/auth-verify/tokens" {
+		t.Errorf("request = %s %s", fake.last.Method, fake.last.URL.Path)
+	}
+
+	fake.body = `{"id":3,"name":"old"}`
+	revoked, err := c.RevokeToken(context.Background(), "tok", 3)
+	if err != nil || revoked.Name != "old" {
+		t.Fatalf("RevokeToken = %+v, %v", revoked, err)
+	}
+	if fake.last.Method != "DELETE" || fake.last.URL.Path != "/functions/v1/auth-verify/tokens/3" {
+		t.Errorf("request = %s %s", fake.last.Method, fake.last.URL.Path)
+	}
+
+	fake.status, fake.body = 404, `{"error":"Token not found"}`
+	if _, err := c.RevokeToken(context.Background(), "tok", 99); !errors.Is(err, ErrNotFound) {
+		t.Errorf("missing token err = %v", err)
+	}
+}
+
+func TestPairing(t *testing.T) {
+	c, fake := newTestClient(t, 200, `{"code":"K7Q-4MZ","device_code":"secret","expires_at":"2026-10-17T10:10:00Z","interval":3}`)
+	p, err := c.StartPairing(context.Background(), "laptop")
+	if err != nil || p.Code != "K7Q-4MZ" || p.DeviceCode != "secret" || p.Interval != 3 {
+		t.Fatalf("StartPairing = %+v, %v", p, err)
+	}
+	if fake.last.URL.Path != "/functions/v1/auth-verify/pair" || !strings.Contains(string(fake.sent), `"name":"laptop"`) {
+		t.Errorf("request = %s %s", fake.last.URL.Path, fake.sent)
+	}
+
+	fake.status, fake.body = 202, `{"pending":true}`
+	if r, err := c.PollPairing(context.Background(), "secret"); err != nil || !r.Pending {
+		t.Fatalf("pending poll = %+v, %v", r, err)
+	}
+	if !strings.Contains(string(fake.sent), `"device_code":"secret"`) {
+		t.Errorf("poll body = %s", fake.sent)
+	}
+
+	fake.status, fake.body = 200, `{"token":"new","user_id":"42","token_name":"laptop"}`
+	if r, err := c.PollPairing(context.Background(), "secret"); err != nil || r.Pending || r.Token != "new" {
+		t.Fatalf("approved poll = %+v, %v", r, err)
+	}
+
+	fake.status, fake.body = 410, `{"error":"Pairing code expired"}`
+	var apiErr *APIError
+	if _, err := c.PollPairing(context.Background(), "secret"); !errors.As(err, &apiErr) || apiErr.StatusCode != 410 {
+		t.Errorf("expired poll err = %v", err)
+	}
+}
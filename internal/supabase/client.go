@@ -0,0 +1,174 @@
+// Package supabase is a small PostgREST client for the tasks table shared by
+// the todo CLI, the Telegram webhook and the Obsidian sync.
+package supabase
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Default request policy used by NewClient.
+const (
+	DefaultTimeout    = 10 * time.Second
+	DefaultMaxRetries = 3
+)
+
+// Client holds the connection settings for a Supabase project.
+type Client struct {
+	BaseURL    string
+	APIKey     string
+	HTTPClient *http.Client
+
+	// Timeout bounds each attempt; the caller's context bounds the whole
+	// call including retries. Zero means no per-attempt limit.
+	Timeout time.Duration
+	// MaxRetries is how many times a retryable failure is retried.
+	MaxRetries int
+	// Backoff is the base delay for jittered exponential backoff.
+	Backoff time.Duration
+
+	mu          sync.Mutex
+	accessToken string
+}
+
+// NewClient returns a client for the project at baseURL authenticated with apiKey.
+func NewClient(baseURL, apiKey string) *Client {
+	return &Client{
+		BaseURL:    strings.TrimRight(baseURL, "/"),
+		APIKey:     apiKey,
+		HTTPClient: &http.Client{},
+		Timeout:    DefaultTimeout,
+		MaxRetries: DefaultMaxRetries,
+		Backoff:    250 * time.Millisecond,
+	}
+}
+
+// SetAccessToken makes the client read and write tables as the user an
+// access token from VerifyToken was issued for, so that the database's
+// row-level security applies. Until it is set requests carry APIKey, which
+// only the webhook, holding the service role key, gets anything with.
+func (c *Client) SetAccessToken(jwt string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.accessToken = jwt
+}
+
+func (c *Client) bearer() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.accessToken != "" {
+		return c.accessToken
+	}
+	return c.APIKey
+}
+
+// rest performs a request against /rest/v1/<path>. A non-nil body is sent as
+// JSON; a non-nil out receives the decoded JSON response.
+func (c *Client) rest(ctx context.Context, method, path string, query url.Values, body any, prefer string, out any) error {
+	u := fmt.Sprintf("%s/rest/v1/%s", c.BaseURL, path)
+	if len(query) > 0 {
+		u += "?" + query.Encode()
+	}
+	headers := http.Header{}
+	headers.Set("apikey", c.APIKey)
+	headers.Set("Authorization", "Bearer "+c.bearer())
+	if prefer != "" {
+		headers.Set("Prefer", prefer)
+	}
+	return c.do(ctx, method, u, headers, body, out)
+}
+
+// function calls the edge function name, authenticating with bearer.
+func (c *Client) function(ctx context.Context, method, name, bearer string, body, out any) error {
+	headers := http.Header{}
+	headers.Set("Authorization", "Bearer "+bearer)
+	return c.do(ctx, method, fmt.Sprintf("%s/functions/v1/%s", c.BaseURL, name), headers, body, out)
+}
+
+func (c *Client) do(ctx context.Context, method, u string, headers http.Header, body, out any) error {
+	var payload []byte
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return fmt.Errorf("supabase: encode request: %w", err)
+		}
+		payload = data
+	}
+
+	for attempt := 0; ; attempt++ {
+		resp, err := c.attempt(ctx, method, u, headers, payload)
+		if err == nil && resp.StatusCode >= 200 && resp.StatusCode < 300 {
+			defer resp.Body.Close()
+			if out == nil {
+				return nil
+			}
+			if err := json.NewDecoder(resp.Body).Decode(out); err != nil {
+				return fmt.Errorf("supabase: decode response: %w", err)
+			}
+			return nil
+		}
+		if err == nil {
+			err = decodeAPIError(resp)
+			resp.Body.Close()
+		}
+
+		if attempt >= c.MaxRetries || !retryable(method, err) {
+			return err
+		}
+		if werr := wait(ctx, c.delay(attempt, resp)); werr != nil {
+			return err
+		}
+	}
+}
+
+// attempt sends one request. The per-attempt timeout stays attached to the
+// response body until the caller closes it.
+func (c *Client) attempt(ctx context.Context, method, u string, headers http.Header, payload []byte) (*http.Response, error) {
+	cancel := context.CancelFunc(func() {})
+	if c.Timeout > 0 {
+		ctx, cancel = context.WithTimeout(ctx, c.Timeout)
+	}
+
+	var reader io.Reader
+	if payload != nil {
+		reader = bytes.NewReader(payload)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, u, reader)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	for k, v := range headers {
+		req.Header[k] = v
+	}
+	if payload != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+
+	resp, err := c.HTTPClient.Do(req)
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = cancelOnClose{resp.Body, cancel}
+	return resp, nil
+}
+
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
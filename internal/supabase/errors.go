@@ -0,0 +1,134 @@
+package supabase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+)
+
+// Error classes. An *APIError matches the class of its status and PostgREST
+// code under errors.Is, so callers can branch without inspecting codes.
+var (
+	ErrAuth       = errors.New("not authorized")
+	ErrNotFound   = errors.New("task not found")
+	ErrConstraint = errors.New("constraint violation")
+	ErrRateLimit  = errors.New("rate limited")
+	ErrServer     = errors.New("server error")
+)
+
+// ErrQueued reports that a change could not reach the server and was saved
+// to an offline queue for later. The storage package's offline wrapper
+// returns it; it lives here so that packages acting on any task store can
+// recognise it without depending on that wrapper.
+var ErrQueued = errors.New("offline: change queued for sync")
+
+// APIError is a non-2xx response from PostgREST or an edge function, decoded
+// from PostgREST's {code,message,details,hint} body or a function's {error}
+// body when present.
+type APIError struct {
+	StatusCode int    `json:"-"`
+	Code       string `json:"code"`
+	Message    string `json:"message"`
+	Details    string `json:"details"`
+	Hint       string `json:"hint"`
+}
+
+func (e *APIError) Error() string {
+	msg := e.Message
+	if msg == "" {
+		msg = http.StatusText(e.StatusCode)
+	}
+	if e.Code != "" {
+		msg = fmt.Sprintf("%s (%s)", msg, e.Code)
+	}
+	if e.Details != "" {
+		msg += ": " + e.Details
+	}
+	if e.Hint != "" {
+		msg += " — hint: " + e.Hint
+	}
+	return fmt.Sprintf("supabase: %d %s", e.StatusCode, msg)
+}
+
+// Is reports whether e belongs to one of the error classes above.
+func (e *APIError) Is(target error) bool {
+	return target != nil && e.class() == target
+}
+
+func (e *APIError) class() error {
+	switch {
+	case e.StatusCode == http.StatusUnauthorized, e.StatusCode == http.StatusForbidden,
+		e.Code == "42501", strings.HasPrefix(e.Code, "PGRST3"):
+		// 42501 is an RLS/privilege rejection; PGRST3xx are JWT errors.
+		return ErrAuth
+	case e.StatusCode == http.StatusTooManyRequests:
+		return ErrRateLimit
+	case e.StatusCode == http.StatusNotFound, e.Code == "PGRST116":
+		return ErrNotFound
+	case e.StatusCode == http.StatusConflict, strings.HasPrefix(e.Code, "23"):
+		// Class 23 is integrity constraint violation (unique, check, FK, not null).
+		return ErrConstraint
+	case e.StatusCode >= 500:
+		return ErrServer
+	}
+	return nil
+}
+
+func decodeAPIError(resp *http.Response) error {
+	body, _ := io.ReadAll(resp.Body)
+	var decoded struct {
+		APIError
+		Error string `json:"error"`
+	}
+	apiErr := &APIError{StatusCode: resp.StatusCode}
+	if err := json.Unmarshal(body, &decoded); err == nil {
+		*apiErr = decoded.APIError
+		apiErr.StatusCode = resp.StatusCode
+		if apiErr.Message == "" {
+			apiErr.Message = decoded.Error
+		}
+	}
+	if apiErr.Message == "" {
+		apiErr.Message = strings.TrimSpace(string(body))
+	}
+	return apiErr
+}
+
+// IsExpiredToken reports whether err rejects the request's JWT itself: a
+// 401, or PostgREST's PGRST301 and PGRST303 for a JWT it cannot decode or
+// that has expired. Unlike a row-level security rejection (42501), such a
+// request may succeed with a fresh token.
+func IsExpiredToken(err error) bool {
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Code == "42501" {
+		return false
+	}
+	return apiErr.StatusCode == http.StatusUnauthorized || apiErr.Code == "PGRST301" || apiErr.Code == "PGRST303"
+}
+
+// IsRowSecurityError reports whether err is PostgreSQL's insufficient
+// privilege error (42501), which row-level security policies raise for rows
+// the user may not read or write.
+func IsRowSecurityError(err error) bool {
+	var apiErr *APIError
+	return errors.As(err, &apiErr) && apiErr.Code == "42501"
+}
+
+// IsNetworkError reports whether err means the request never got an HTTP
+// response, as opposed to the server rejecting it.
+func IsNetworkError(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) {
+		return false
+	}
+	var apiErr *APIError
+	if errors.As(err, &apiErr) {
+		return false
+	}
+	var netErr net.Error
+	return errors.As(err, &netErr)
+}
@@ -0,0 +1,80 @@
+package supabase
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"testing"
+)
+
+func TestAPIErrorClasses(t *testing.T) {
+	tests := []struct {
+		status int
+		body   string
+		want   error
+	}{
+		{401, `{"code":"PGRST301","message":"JWT expired"}`, ErrAuth},
+		{403, `{"code":"42501","message":"new row violates row-level security policy"}`, ErrAuth},
+		{406, `{"code":"PGRST116","message":"JSON object requested, multiple (or no) rows returned"}`, ErrNotFound},
+		{409, `{"code":"23505","message":"duplicate key value violates unique constraint"}`, ErrConstraint},
+		{400, `{"code":"23514","message":"new row violates check constraint"}`, ErrConstraint},
+		{429, `rate limit exceeded`, ErrRateLimit},
+		{503, `{"message":"upstream connect error"}`, ErrServer},
+	}
+	for _, tt := range tests {
+		store, _ := newTestStore(t, tt.status, tt.body)
+		_, err := store.List(context.Background(), Filter{})
+		if !errors.Is(err, tt.want) {
+			t.Errorf("%d %s: err = %v, want %v", tt.status, tt.body, err, tt.want)
+		}
+		if IsNetworkError(err) {
+			t.Errorf("%d: API error classified as network error", tt.status)
+		}
+	}
+}
+
+func TestIsExpiredToken(t *testing.T) {
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{&APIError{StatusCode: 401, Code: "PGRST303", Message: "JWT expired"}, true},
+		{&APIError{StatusCode: 401, Message: "Token expired"}, true},
+		{&APIError{StatusCode: 403, Code: "42501", Message: "new row violates row-level security policy"}, false},
+		{&APIError{StatusCode: 401, Code: "42501", Message: "permission denied for table tasks"}, false},
+		{ErrAuth, false},
+	}
+	for _, tt := range tests {
+		if got := IsExpiredToken(tt.err); got != tt.want {
+			t.Errorf("IsExpiredToken(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+	if !IsRowSecurityError(&APIError{StatusCode: 403, Code: "42501"}) {
+		t.Error("42501 not recognised as a row-level security error")
+	}
+}
+
+func TestFunctionErrorBody(t *testing.T) {
+	store, _ := newTestStore(t, http.StatusUnauthorized, `{"error":"Token expired"}`)
+	_, err := store.client.VerifyToken(context.Background(), "tok")
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) || apiErr.Message != "Token expired" {
+		t.Fatalf("err = %v, want decoded function error", err)
+	}
+	if !errors.Is(err, ErrAuth) {
+		t.Errorf("err = %v, want ErrAuth", err)
+	}
+}
+
+func TestIsNetworkError(t *testing.T) {
+	c := NewClient("http://127.0.0.1:1", "k")
+	c.MaxRetries = 0
+	store := NewTaskStore(c)
+	_, err := store.List(context.Background(), Filter{})
+	if !IsNetworkError(err) {
+		t.Errorf("err = %v, want network error", err)
+	}
+	if IsNetworkError(context.Canceled) {
+		t.Error("context.Canceled classified as network error")
+	}
+}
@@ -0,0 +1,60 @@
+package supabase
+
+import (
+	"context"
+	"errors"
+	"math/rand/v2"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+const maxBackoff = 5 * time.Second
+
+// retryable reports whether a failed request may be sent again. Reads and
+// PATCHes (which set absolute values) are idempotent and retry on rate
+// limits, server errors and network failures. Inserts are only retried when
+// the server certainly did not process them: a 429, or a connection that
+// was never established.
+func retryable(method string, err error) bool {
+	if errors.Is(err, context.Canceled) {
+		return false
+	}
+	if errors.Is(err, ErrRateLimit) {
+		return true
+	}
+	if method == http.MethodPost {
+		var opErr *net.OpError
+		return errors.As(err, &opErr) && opErr.Op == "dial"
+	}
+	return errors.Is(err, ErrServer) || IsNetworkError(err)
+}
+
+// delay returns how long to sleep before retry number attempt+1, honouring
+// a Retry-After header when the server sent one.
+func (c *Client) delay(attempt int, resp *http.Response) time.Duration {
+	if resp != nil {
+		if secs, err := strconv.Atoi(resp.Header.Get("Retry-After")); err == nil && secs >= 0 {
+			return min(time.Duration(secs)*time.Second, maxBackoff)
+		}
+	}
+	ceiling := min(c.Backoff<<attempt, maxBackoff)
+	if ceiling <= 0 {
+		return 0
+	}
+	// Full jitter: spread retries from many clients across the window.
+	return time.Duration(rand.Int64N(int64(ceiling) + 1))
+}
+
+// wait sleeps for d or until ctx is done.
+func wait(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-t.C:
+		return nil
+	}
+}
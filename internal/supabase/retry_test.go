@@ -0,0 +1,101 @@
+package supabase
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// flakyServer fails the first n requests with status, then returns body.
+func flakyServer(t *testing.T, n int32, status int, body string) (*Client, *atomic.Int32) {
+	t.Helper()
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) <= n {
+			w.WriteHeader(status)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(srv.Close)
+	c := NewClient(srv.URL, "k")
+	c.Backoff = time.Millisecond
+	return c, &calls
+}
+
+func TestRetriesReadsOnServerError(t *testing.T) {
+	c, calls := flakyServer(t, 2, http.StatusServiceUnavailable, `[{"id":1,"title":"a"}]`)
+
+	tasks, err := NewTaskStore(c).List(context.Background(), Filter{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 1 || calls.Load() != 3 {
+		t.Errorf("tasks = %v after %d calls, want 1 task after 3", tasks, calls.Load())
+	}
+}
+
+func TestGivesUpAfterMaxRetries(t *testing.T) {
+	c, calls := flakyServer(t, 100, http.StatusBadGateway, `[]`)
+	c.MaxRetries = 2
+
+	_, err := NewTaskStore(c).List(context.Background(), Filter{})
+	if !errors.Is(err, ErrServer) {
+		t.Fatalf("err = %v, want ErrServer", err)
+	}
+	if calls.Load() != 3 {
+		t.Errorf("calls = %d, want 3", calls.Load())
+	}
+}
+
+func TestInsertNotRetriedOnServerError(t *testing.T) {
+	c, calls := flakyServer(t, 1, http.StatusInternalServerError, `[{"id":1,"title":"a"}]`)
+
+	_, err := NewTaskStore(c).Create(context.Background(), Task{Title: "a"})
+	if !errors.Is(err, ErrServer) || calls.Load() != 1 {
+		t.Errorf("err = %v after %d calls, want ErrServer after 1", err, calls.Load())
+	}
+}
+
+func TestInsertRetriedOnRateLimit(t *testing.T) {
+	c, calls := flakyServer(t, 1, http.StatusTooManyRequests, `[{"id":1,"title":"a"}]`)
+
+	if _, err := NewTaskStore(c).Create(context.Background(), Task{Title: "a"}); err != nil {
+		t.Fatal(err)
+	}
+	if calls.Load() != 2 {
+		t.Errorf("calls = %d, want 2", calls.Load())
+	}
+}
+
+func TestCancelStopsRetries(t *testing.T) {
+	c, _ := flakyServer(t, 100, http.StatusServiceUnavailable, `[]`)
+	c.Backoff = time.Hour
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := NewTaskStore(c).List(ctx, Filter{})
+	if err == nil || time.Since(start) > 5*time.Second {
+		t.Fatalf("err = %v after %v, want prompt failure", err, time.Since(start))
+	}
+}
+
+func TestPerAttemptTimeout(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	t.Cleanup(srv.Close)
+	c := NewClient(srv.URL, "k")
+	c.Timeout = 20 * time.Millisecond
+	c.MaxRetries = 0
+
+	_, err := NewTaskStore(c).List(context.Background(), Filter{})
+	if !errors.Is(err, context.DeadlineExceeded) || !IsNetworkError(err) {
+		t.Errorf("err = %v, want deadline exceeded network error", err)
+	}
+}
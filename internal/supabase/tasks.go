@@ -0,0 +1,219 @@
+package supabase
+
+import (
+	"context"
+	"errors"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// Task is a row of the tasks table.
+type Task struct {
+	ID        int    `json:"id,omitempty"`
+	Title     string `json:"title"`
+	DueDate   string `json:"due_date,omitempty"`
+	DueTime   string `json:"due_time,omitempty"` // HH:MM[:SS], empty when the task has no time
+	Priority  string `json:"priority,omitempty"`
+	Status    string `json:"status,omitempty"`
+	ParentID  *int   `json:"parent_id,omitempty"`
+	UserID    string `json:"user_id"`
+	CreatedAt string `json:"created_at,omitempty"`
+
+	// Recurrence is an RRULE such as "FREQ=WEEKLY;BYDAY=MO"; completing a
+	// recurring task creates its next occurrence.
+	Recurrence string `json:"recurrence,omitempty"`
+
+	Tags    []string `json:"tags,omitempty"` // lowercase, without the leading #
+	Project string   `json:"project,omitempty"`
+
+	SnoozeCount int `json:"snooze_count,omitempty"` // times the due date was postponed
+
+	Notes string   `json:"notes,omitempty"` // markdown
+	Links []string `json:"links,omitempty"`
+}
+
+// Clock returns the due time as HH:MM, or "" when none is set.
+func (t Task) Clock() string {
+	if len(t.DueTime) > 5 {
+		return t.DueTime[:5] // Postgres returns HH:MM:SS
+	}
+	return t.DueTime
+}
+
+// Due returns the due date followed by the time of day when one is set.
+func (t Task) Due() string {
+	if t.DueTime == "" {
+		return t.DueDate
+	}
+	return t.DueDate + " " + t.Clock()
+}
+
+// Filter selects tasks. Zero-valued fields are not applied.
+type Filter struct {
+	IDs      []int
+	UserID   string
+	Status   string
+	ParentID *int
+	DueFrom  string   // due_date >= DueFrom (YYYY-MM-DD)
+	DueTo    string   // due_date <= DueTo (YYYY-MM-DD)
+	Tags     []string // tasks carrying all of these tags
+	Project  string
+
+	Priorities []string // priority is one of these
+	Search     string   // case-insensitive substring of the title
+	ParentIDs  []int    // subtasks of any of these tasks
+
+	Order []string // PostgREST order terms, e.g. "priority.asc"
+	Limit int
+}
+
+// Query compiles the filter into PostgREST query parameters.
+func (f Filter) Query() url.Values {
+	q := url.Values{}
+	switch len(f.IDs) {
+	case 0:
+	case 1:
+		q.Set("id", "eq."+strconv.Itoa(f.IDs[0]))
+	default:
+		q.Set("id", "in.("+joinInts(f.IDs)+")")
+	}
+	if f.UserID != "" {
+		q.Set("user_id", "eq."+f.UserID)
+	}
+	if f.Status != "" {
+		q.Set("status", "eq."+f.Status)
+	}
+	if f.ParentID != nil {
+		q.Set("parent_id", "eq."+strconv.Itoa(*f.ParentID))
+	} else if len(f.ParentIDs) > 0 {
+		q.Set("parent_id", "in.("+joinInts(f.ParentIDs)+")")
+	}
+	if f.DueFrom != "" {
+		q.Add("due_date", "gte."+f.DueFrom)
+	}
+	if f.DueTo != "" {
+		q.Add("due_date", "lte."+f.DueTo)
+	}
+	if len(f.Tags) > 0 {
+		q.Set("tags", "cs.{"+strings.Join(f.Tags, ",")+"}")
+	}
+	if f.Project != "" {
+		q.Set("project", "eq."+f.Project)
+	}
+	if len(f.Priorities) > 0 {
+		q.Set("priority", "in.("+strings.Join(f.Priorities, ",")+")")
+	}
+	if f.Search != "" {
+		q.Set("title", "ilike.*"+f.Search+"*")
+	}
+	if len(f.Order) > 0 {
+		q.Set("order", strings.Join(f.Order, ","))
+	}
+	if f.Limit > 0 {
+		q.Set("limit", strconv.Itoa(f.Limit))
+	}
+	return q
+}
+
+func joinInts(ids []int) string {
+	s := make([]string, len(ids))
+	for i, id := range ids {
+		s[i] = strconv.Itoa(id)
+	}
+	return strings.Join(s, ",")
+}
+
+// TaskStore reads and writes the tasks table.
+type TaskStore struct {
+	client *Client
+	userID string
+}
+
+// NewTaskStore returns a store for the tasks table reachable through c.
+func NewTaskStore(c *Client) *TaskStore {
+	return &TaskStore{client: c}
+}
+
+// ForUser returns a copy of the store that scopes every read and write to
+// rows owned by userID.
+func (s *TaskStore) ForUser(userID string) *TaskStore {
+	return &TaskStore{client: s.client, userID: userID}
+}
+
+func (s *TaskStore) scope(f Filter) Filter {
+	if s.userID != "" {
+		f.UserID = s.userID
+	}
+	return f
+}
+
+// List returns the tasks matching f.
+func (s *TaskStore) List(ctx context.Context, f Filter) ([]Task, error) {
+	var tasks []Task
+	if err := s.client.rest(ctx, "GET", "tasks", s.scope(f).Query(), nil, "", &tasks); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// Search returns the tasks matching a web-search style query ("quoted
+// phrase", -word, or), best match first, narrowed by f. It calls the
+// search_tasks database function; f.Order, if set, replaces the ranking.
+func (s *TaskStore) Search(ctx context.Context, query string, f Filter) ([]Task, error) {
+	q := s.scope(f).Query()
+	q.Set("search_query", query)
+	var tasks []Task
+	if err := s.client.rest(ctx, "GET", "rpc/search_tasks", q, nil, "", &tasks); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// Get returns the task with the given ID, or ErrNotFound.
+func (s *TaskStore) Get(ctx context.Context, id int) (*Task, error) {
+	tasks, err := s.List(ctx, Filter{IDs: []int{id}})
+	if err != nil {
+		return nil, err
+	}
+	if len(tasks) == 0 {
+		return nil, ErrNotFound
+	}
+	return &tasks[0], nil
+}
+
+// Create inserts t and returns the stored row.
+func (s *TaskStore) Create(ctx context.Context, t Task) (*Task, error) {
+	if s.userID != "" {
+		t.UserID = s.userID
+	}
+	var tasks []Task
+	if err := s.client.rest(ctx, "POST", "tasks", nil, t, "return=representation", &tasks); err != nil {
+		return nil, err
+	}
+	if len(tasks) == 0 {
+		return nil, errors.New("supabase: no task returned")
+	}
+	return &tasks[0], nil
+}
+
+// Update patches every task matching f with fields and returns the updated rows.
+func (s *TaskStore) Update(ctx context.Context, f Filter, fields map[string]any) ([]Task, error) {
+	var tasks []Task
+	if err := s.client.rest(ctx, "PATCH", "tasks", s.scope(f).Query(), fields, "return=representation", &tasks); err != nil {
+		return nil, err
+	}
+	return tasks, nil
+}
+
+// UpdateByID patches a single task and returns it, or ErrNotFound.
+func (s *TaskStore) UpdateByID(ctx context.Context, id int, fields map[string]any) (*Task, error) {
+	tasks, err := s.Update(ctx, Filter{IDs: []int{id}}, fields)
+	if err != nil {
+		return nil, err
+	}
+	if len(tasks) == 0 {
+		return nil, ErrNotFound
+	}
+	return &tasks[0], nil
+}
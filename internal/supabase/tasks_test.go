@@ -0,0 +1,200 @@
+package supabase
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+// fakePostgREST serves canned responses and records the last request.
+type fakePostgREST struct {
+	status int
+	body   string
+	last   *http.Request
+	sent   []byte
+}
+
+func (f *fakePostgREST) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.last = r
+	f.sent, _ = io.ReadAll(r.Body)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(f.status)
+	io.WriteString(w, f.body)
+}
+
+func newTestStore(t *testing.T, status int, body string) (*TaskStore, *fakePostgREST) {
+	t.Helper()
+	fake := &fakePostgREST{status: status, body: body}
+	srv := httptest.NewServer(fake)
+	t.Cleanup(srv.Close)
+	c := NewClient(srv.URL, "secret")
+	c.Backoff = time.Millisecond
+	return NewTaskStore(c), fake
+}
+
+func TestFilterQuery(t *testing.T) {
+	parent := 7
+	f := Filter{
+		IDs:      []int{1, 2},
+		UserID:   "42",
+		Status:   "Todo",
+		ParentID: &parent,
+		DueFrom:  "2026-02-01",
+		DueTo:    "2026-02-10",
+		Tags:     []string{"work", "urgent"},
+		Project:  "infra",
+		Order:    []string{"priority.asc", "due_date.asc"},
+		Limit:    5,
+
+		Priorities: []string{"P0", "P1"},
+		Search:     "report",
+	}
+	q := f.Query()
+	want := map[string][]string{
+		"id":        {"in.(1,2)"},
+		"user_id":   {"eq.42"},
+		"status":    {"eq.Todo"},
+		"parent_id": {"eq.7"},
+		"due_date":  {"gte.2026-02-01", "lte.2026-02-10"},
+		"tags":      {"cs.{work,urgent}"},
+		"project":   {"eq.infra"},
+		"priority":  {"in.(P0,P1)"},
+		"title":     {"ilike.*report*"},
+		"order":     {"priority.asc,due_date.asc"},
+		"limit":     {"5"},
+	}
+	for k, v := range want {
+		got := q[k]
+		if len(got) != len(v) {
+			t.Fatalf("%s = %v, want %v", k, got, v)
+		}
+		for i := range v {
+			if got[i] != v[i] {
+				t.Errorf("%s[%d] = %q, want %q", k, i, got[i], v[i])
+			}
+		}
+	}
+	if len(q) != len(want) {
+		t.Errorf("unexpected params: %v", q)
+	}
+}
+
+func TestFilterQueryParentIDs(t *testing.T) {
+	q := Filter{ParentIDs: []int{3, 9}}.Query()
+	if got := q.Get("parent_id"); got != "in.(3,9)" {
+		t.Errorf("parent_id = %q, want in.(3,9)", got)
+	}
+}
+
+func TestListSendsFilterAndAuth(t *testing.T) {
+	store, fake := newTestStore(t, http.StatusOK, `[{"id":1,"title":"a","due_date":"2026-02-01","priority":"P1","status":"Todo","parent_id":null,"user_id":"42"}]`)
+
+	tasks, err := store.ForUser("42").List(context.Background(), Filter{Status: "Todo"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 1 || tasks[0].Title != "a" || tasks[0].ParentID != nil {
+		t.Fatalf("unexpected tasks: %+v", tasks)
+	}
+	if fake.last.Method != http.MethodGet || fake.last.URL.Path != "/rest/v1/tasks" {
+		t.Errorf("request = %s %s", fake.last.Method, fake.last.URL.Path)
+	}
+	if got := fake.last.URL.Query().Get("user_id"); got != "eq.42" {
+		t.Errorf("user_id = %q, want scoped to 42", got)
+	}
+	if got := fake.last.Header.Get("Authorization"); got != "Bearer secret" {
+		t.Errorf("Authorization = %q", got)
+	}
+	if got := fake.last.Header.Get("apikey"); got != "secret" {
+		t.Errorf("apikey = %q", got)
+	}
+}
+
+func TestCreateOmitsServerDefaults(t *testing.T) {
+	store, fake := newTestStore(t, http.StatusCreated, `[{"id":9,"title":"x","user_id":"42"}]`)
+
+	task, err := store.Create(context.Background(), Task{Title: "x", UserID: "42"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if task.ID != 9 {
+		t.Errorf("ID = %d, want 9", task.ID)
+	}
+	if fake.last.Header.Get("Prefer") != "return=representation" {
+		t.Errorf("Prefer = %q", fake.last.Header.Get("Prefer"))
+	}
+	var sent map[string]any
+	if err := json.Unmarshal(fake.sent, &sent); err != nil {
+		t.Fatal(err)
+	}
+	for _, k := range []string{"id", "created_at", "parent_id", "due_date"} {
+		if _, ok := sent[k]; ok {
+			t.Errorf("body unexpectedly contains %q: %s", k, fake.sent)
+		}
+	}
+}
+
+func TestUpdateByIDNotFound(t *testing.T) {
+	store, fake := newTestStore(t, http.StatusOK, `[]`)
+
+	_, err := store.UpdateByID(context.Background(), 3, map[string]any{"status": "Done"})
+	if !errors.Is(err, ErrNotFound) {
+		t.Fatalf("err = %v, want ErrNotFound", err)
+	}
+	if fake.last.Method != http.MethodPatch || fake.last.URL.Query().Get("id") != "eq.3" {
+		t.Errorf("request = %s %s", fake.last.Method, fake.last.URL)
+	}
+}
+
+func TestAPIErrorDecoded(t *testing.T) {
+	store, _ := newTestStore(t, http.StatusBadRequest,
+		`{"code":"23514","message":"new row violates check constraint","details":"Failing row contains (P9)","hint":null}`)
+
+	_, err := store.List(context.Background(), Filter{})
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("err = %v, want *APIError", err)
+	}
+	if apiErr.StatusCode != http.StatusBadRequest || apiErr.Code != "23514" || apiErr.Details == "" {
+		t.Errorf("unexpected error: %+v", apiErr)
+	}
+}
+
+func TestAPIErrorNonJSONBody(t *testing.T) {
+	store, _ := newTestStore(t, http.StatusBadGateway, `upstream unavailable`)
+
+	_, err := store.Get(context.Background(), 1)
+	var apiErr *APIError
+	if !errors.As(err, &apiErr) {
+		t.Fatalf("err = %v, want *APIError", err)
+	}
+	if apiErr.Message != "upstream unavailable" {
+		t.Errorf("Message = %q", apiErr.Message)
+	}
+}
+
+func TestSearchCallsRPC(t *testing.T) {
+	store, fake := newTestStore(t, http.StatusOK, `[{"id":3,"title":"Send invoices","user_id":"42"}]`)
+
+	tasks, err := store.ForUser("42").Search(context.Background(), "invoice -draft", Filter{Status: "Done", Limit: 5})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 1 || tasks[0].ID != 3 {
+		t.Fatalf("unexpected tasks: %+v", tasks)
+	}
+	if fake.last.Method != http.MethodGet || fake.last.URL.Path != "/rest/v1/rpc/search_tasks" {
+		t.Errorf("request = %s %s", fake.last.Method, fake.last.URL.Path)
+	}
+	q := fake.last.URL.Query()
+	for k, want := range map[string]string{"search_query": "invoice -draft", "user_id": "eq.42", "status": "eq.Done", "limit": "5"} {
+		if got := q.Get(k); got != want {
+			t.Errorf("%s = %q, want %q", k, got, want)
+		}
+	}
+}
@@ -0,0 +1,100 @@
+package supabase
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+// accessMargin is how long before its access token expires a cached
+// verification stops being fresh, so that a command does not start with
+// an access token about to run out.
+const accessMargin = time.Minute
+
+// TokenCache remembers what a CLI token last verified as, so that commands
+// can skip the auth-verify roundtrip until TTL has passed or the token or
+// its access token expires. Only a hash of the token is stored, next to
+// the short-lived access token.
+type TokenCache struct {
+	Path string
+	TTL  time.Duration
+}
+
+type cachedToken struct {
+	TokenHash  string    `json:"token_hash"`
+	UserID     string    `json:"user_id"`
+	TokenName  string    `json:"token_name"`
+	TokenID    int       `json:"token_id"`
+	ExpiresAt  string    `json:"expires_at"`
+	VerifiedAt time.Time `json:"verified_at"`
+
+	AccessToken     string `json:"access_token"`
+	AccessExpiresAt string `json:"access_expires_at"`
+}
+
+// Lookup returns what token verified as when it was last cached, or nil
+// when it was not. fresh is false once TTL has passed since then, the
+// token has expired or its access token is about to; a stale entry still
+// tells who the token belonged to, for queuing changes offline.
+func (c TokenCache) Lookup(token string, now time.Time) (info *TokenInfo, fresh bool) {
+	data, err := os.ReadFile(c.Path)
+	if err != nil {
+		return nil, false
+	}
+	var e cachedToken
+	if json.Unmarshal(data, &e) != nil || e.TokenHash != hashToken(token) || e.UserID == "" {
+		return nil, false
+	}
+	info = &TokenInfo{Valid: true, UserID: e.UserID, TokenName: e.TokenName, TokenID: e.TokenID, ExpiresAt: e.ExpiresAt,
+		AccessToken: e.AccessToken, AccessExpiresAt: e.AccessExpiresAt}
+	fresh = now.Before(e.VerifiedAt.Add(c.TTL))
+	if exp, err := time.Parse(time.RFC3339, e.ExpiresAt); err == nil && !now.Before(exp) {
+		fresh = false
+	}
+	if exp, err := time.Parse(time.RFC3339, e.AccessExpiresAt); err != nil || !now.Before(exp.Add(-accessMargin)) {
+		fresh = false
+	}
+	return info, fresh
+}
+
+// Store records that token verified as info at now.
+func (c TokenCache) Store(token string, info *TokenInfo, now time.Time) error {
+	data, err := json.MarshalIndent(cachedToken{
+		TokenHash:  hashToken(token),
+		UserID:     info.UserID,
+		TokenName:  info.TokenName,
+		TokenID:    info.TokenID,
+		ExpiresAt:  info.ExpiresAt,
+		VerifiedAt: now.UTC(),
+
+		AccessToken:     info.AccessToken,
+		AccessExpiresAt: info.AccessExpiresAt,
+	}, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.Path), 0700); err != nil {
+		return err
+	}
+	tmp := c.Path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return err
+	}
+	return os.Rename(tmp, c.Path)
+}
+
+// Clear forgets the cached verification.
+func (c TokenCache) Clear() error {
+	if err := os.Remove(c.Path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
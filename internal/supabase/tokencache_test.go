@@ -0,0 +1,66 @@
+package supabase
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTokenCache(t *testing.T) {
+	c := TokenCache{Path: filepath.Join(t.TempDir(), "state", "token.json"), TTL: time.Hour}
+	now := time.Date(2026, 10, 17, 9, 0, 0, 0, time.UTC)
+	if info, _ := c.Lookup("tok", now); info != nil {
+		t.Fatalf("empty cache = %+v", info)
+	}
+
+	if err := c.Store("tok", &TokenInfo{UserID: "42", TokenName: "laptop", TokenID: 7, ExpiresAt: "2026-10-17T12:00:00+00:00",
+		AccessToken: "jwt", AccessExpiresAt: "2026-10-17T13:00:00Z"}, now); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := os.ReadFile(c.Path)
+	if strings.Contains(string(data), `"tok"`) {
+		t.Errorf("cache holds the token: %s", data)
+	}
+
+	info, fresh := c.Lookup("tok", now.Add(30*time.Minute))
+	if info == nil || !fresh || info.UserID != "42" || info.TokenID != 7 || info.AccessToken != "jwt" {
+		t.Fatalf("within TTL = %+v, %v", info, fresh)
+	}
+	if info, fresh := c.Lookup("tok", now.Add(2*time.Hour)); info == nil || fresh {
+		t.Errorf("after TTL = %+v, %v", info, fresh)
+	}
+	c.TTL = 24 * time.Hour
+	if _, fresh := c.Lookup("tok", now.Add(3*time.Hour)); fresh {
+		t.Error("expired token still fresh")
+	}
+	if info, _ := c.Lookup("other", now); info != nil {
+		t.Errorf("other token = %+v", info)
+	}
+
+	c.Store("tok", &TokenInfo{UserID: "42", AccessToken: "jwt", AccessExpiresAt: "2026-10-17T09:20:00Z"}, now)
+	if _, fresh := c.Lookup("tok", now.Add(10*time.Minute)); !fresh {
+		t.Error("unexpired access token stale")
+	}
+	if info, fresh := c.Lookup("tok", now.Add(19*time.Minute+30*time.Second)); info == nil || fresh {
+		t.Errorf("access token about to expire = %+v, %v", info, fresh)
+	}
+	c.Store("tok", &TokenInfo{UserID: "42"}, now)
+	if _, fresh := c.Lookup("tok", now); fresh {
+		t.Error("verification without an access token fresh")
+	}
+	if info, _ := c.Lookup("other", now); info != nil {
+		t.Errorf("other token = %+v", info)
+	}
+
+	if err := c.Clear(); err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := c.Lookup("tok", now); info != nil {
+		t.Errorf("after Clear = %+v", info)
+	}
+	if err := c.Clear(); err != nil {
+		t.Errorf("second Clear: %v", err)
+	}
+}
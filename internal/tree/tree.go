@@ -0,0 +1,73 @@
+// Package tree arranges tasks into parent/child trees for display.
+package tree
+
+import "todo-tracker/internal/supabase"
+
+// Node is a task and the subtasks listed under it.
+type Node struct {
+	Task     supabase.Task
+	Children []*Node
+}
+
+// Build arranges tasks into trees, keeping their order among siblings. A
+// task whose parent is not in tasks becomes a root.
+func Build(tasks []supabase.Task) []*Node {
+	nodes := make(map[int]*Node, len(tasks))
+	for _, t := range tasks {
+		nodes[t.ID] = &Node{Task: t}
+	}
+	var roots []*Node
+	for _, t := range tasks {
+		n := nodes[t.ID]
+		if t.ParentID != nil {
+			if p, ok := nodes[*t.ParentID]; ok && p != n {
+				p.Children = append(p.Children, n)
+				continue
+			}
+		}
+		roots = append(roots, n)
+	}
+	return roots
+}
+
+// Walk calls fn for every node depth-first. prefix is the tree drawing
+// that goes before the node's line, such as "│   └── ".
+func Walk(roots []*Node, fn func(n *Node, prefix string)) {
+	for _, r := range roots {
+		fn(r, "")
+		walk(r.Children, "", fn)
+	}
+}
+
+func walk(nodes []*Node, indent string, fn func(n *Node, prefix string)) {
+	for i, n := range nodes {
+		branch, next := "├── ", "│   "
+		if i == len(nodes)-1 {
+			branch, next = "└── ", "    "
+		}
+		fn(n, indent+branch)
+		walk(n.Children, indent+next, fn)
+	}
+}
+
+// Progress counts a task's direct subtasks.
+type Progress struct {
+	Done, Total int
+}
+
+// Count returns the progress of every parent in subtasks, keyed by parent ID.
+func Count(subtasks []supabase.Task) map[int]Progress {
+	out := map[int]Progress{}
+	for _, t := range subtasks {
+		if t.ParentID == nil {
+			continue
+		}
+		p := out[*t.ParentID]
+		p.Total++
+		if t.Status == "Done" {
+			p.Done++
+		}
+		out[*t.ParentID] = p
+	}
+	return out
+}
@@ -0,0 +1,38 @@
+package tree
+
+import (
+	"strings"
+	"testing"
+
+	"todo-tracker/internal/supabase"
+)
+
+func task(id, parent int, status string) supabase.Task {
+	t := supabase.Task{ID: id, Title: "t", Status: status}
+	if parent != 0 {
+		t.ParentID = &parent
+	}
+	return t
+}
+
+func TestBuildAndWalk(t *testing.T) {
+	tasks := []supabase.Task{
+		task(1, 0, "Todo"), task(2, 1, "Todo"), task(3, 2, "Todo"),
+		task(4, 1, "Todo"), task(5, 9, "Todo"),
+	}
+	var lines []string
+	Walk(Build(tasks), func(n *Node, prefix string) {
+		lines = append(lines, prefix+string(rune('0'+n.Task.ID)))
+	})
+	want := "1\n├── 2\n│   └── 3\n└── 4\n5"
+	if got := strings.Join(lines, "\n"); got != want {
+		t.Errorf("tree =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestCount(t *testing.T) {
+	got := Count([]supabase.Task{task(2, 1, "Done"), task(3, 1, "Todo"), task(4, 1, "Done"), task(5, 0, "Done")})
+	if got[1] != (Progress{Done: 2, Total: 3}) || len(got) != 1 {
+		t.Errorf("Count = %v", got)
+	}
+}
@@ -38,6 +38,9 @@ let SUPABASE_URL = process.env.TODO_CLI_SUPABASE_URL || process.env.SUPABASE_URL
 let SUPABASE_KEY = '';
 let USER_ID = '';
 let API_TOKEN = '';
+// Short-lived JWT from auth-verify; REST requests made with it only reach
+// the user's own tasks
+let ACCESS_TOKEN = '';
 
 // Try to load API token
 function loadApiToken(): string {
@@ -56,7 +59,7 @@ function loadApiToken(): string {
   return '';
 }
 
-async function verifyToken(token: string): Promise<string> {
+async function verifyToken(token: string): Promise<{ user_id: string; access_token: string }> {
   return new Promise((resolve, reject) => {
     const url = new URL(`${SUPABASE_URL}/functions/v1/auth-verify`);
     
@@ -75,7 +78,11 @@ async function verifyToken(token: string): Promise<string> {
       res.on('end', () => {
         if (res.statusCode === 200) {
           const result = JSON.parse(data);
-          resolve(result.user_id);
+          if (!result.access_token) {
+            reject(new Error('auth-verify issued no access token; deploy the current auth-verify function'));
+            return;
+          }
+          resolve(result);
         } else {
           const err = JSON.parse(data);
           reject(new Error(err.error || 'Invalid token'));
@@ -93,7 +100,9 @@ async function initAuth(): Promise<void> {
   
   if (API_TOKEN) {
     try {
-      USER_ID = await verifyToken(API_TOKEN);
+      const verified = await verifyToken(API_TOKEN);
+      USER_ID = verified.user_id;
+      ACCESS_TOKEN = verified.access_token;
       SUPABASE_KEY = process.env.TODO_CLI_SUPABASE_ANON_KEY || process.env.SUPABASE_ANON_KEY || DEFAULT_ANON_KEY;
     } catch (err: any) {
       console.error(`❌ Invalid API token: ${err.message}`);
@@ -139,7 +148,7 @@ function request(method: string, endpoint: string, body?: object): Promise<any>
       method,
       headers: {
         'apikey': SUPABASE_KEY,
-        'Authorization': `Bearer ${SUPABASE_KEY}`,
+        'Authorization': `Bearer ${ACCESS_TOKEN || SUPABASE_KEY}`,
         'Content-Type': 'application/json',
         'Prefer': 'return=representation',
       },
@@ -8,14 +8,92 @@ function toHex(buffer: ArrayBuffer): string {
     .join('')
 }
 
+async function sha256(text: string): Promise<string> {
+  const hashBuffer = await crypto.subtle.digest("SHA-256", new TextEncoder().encode(text))
+  return toHex(hashBuffer)
+}
+
 const supabaseUrl = Deno.env.get("TODO_CLI_SUPABASE_URL") || Deno.env.get("SUPABASE_URL")!
 const supabaseKey = Deno.env.get("TODO_CLI_SUPABASE_SERVICE_ROLE_KEY") || Deno.env.get("SUPABASE_SERVICE_ROLE_KEY")!
+// The project's JWT secret, which signs the access tokens the CLI queries
+// the tasks table with
+const jwtSecret = Deno.env.get("TODO_CLI_JWT_SECRET") || Deno.env.get("SUPABASE_JWT_SECRET")!
 
 const supabase = createClient(supabaseUrl, supabaseKey)
 
 const corsHeaders = {
   "Access-Control-Allow-Origin": "*",
   "Access-Control-Allow-Headers": "authorization, x-client-info, apikey, content-type",
+  "Access-Control-Allow-Methods": "GET, POST, DELETE, OPTIONS",
+}
+
+// Pairing codes avoid letters and digits that are easy to confuse
+const codeAlphabet = "ABCDEFGHJKMNPQRSTUVWXYZ23456789"
+const pollInterval = 3 // seconds
+const accessTokenLifetime = 60 * 60 // seconds
+
+function json(body: unknown, status = 200): Response {
+  return new Response(JSON.stringify(body), {
+    status,
+    headers: { ...corsHeaders, "Content-Type": "application/json" },
+  })
+}
+
+type TokenRecord = { id: number; user_id: string; name: string; expires_at: string }
+
+function base64url(data: Uint8Array): string {
+  return btoa(String.fromCharCode(...data)).replace(/\+/g, "-").replace(/\//g, "_").replace(/=+$/, "")
+}
+
+// accessToken signs a short-lived JWT that PostgREST accepts as the token's
+// user: role authenticated, with the user ID as sub for the row-level
+// security policies on tasks. It never outlives the CLI token.
+async function accessToken(auth: TokenRecord): Promise<{ access_token: string; access_expires_at: string }> {
+  const now = Math.floor(Date.now() / 1000)
+  const exp = Math.min(now + accessTokenLifetime, Math.floor(new Date(auth.expires_at).getTime() / 1000))
+  const encode = (part: unknown) => base64url(new TextEncoder().encode(JSON.stringify(part)))
+  const unsigned = encode({ alg: "HS256", typ: "JWT" }) + "." + encode({
+    aud: "authenticated",
+    role: "authenticated",
+    sub: auth.user_id,
+    token_id: auth.id,
+    iat: now,
+    exp,
+  })
+  const key = await crypto.subtle.importKey(
+    "raw", new TextEncoder().encode(jwtSecret), { name: "HMAC", hash: "SHA-256" }, false, ["sign"],
+  )
+  const signature = await crypto.subtle.sign("HMAC", key, new TextEncoder().encode(unsigned))
+  return {
+    access_token: unsigned + "." + base64url(new Uint8Array(signature)),
+    access_expires_at: new Date(exp * 1000).toISOString(),
+  }
+}
+
+// authenticate returns the token record for the request's bearer token, or
+// the 401 response to send
+async function authenticate(req: Request): Promise<TokenRecord | Response> {
+  const authHeader = req.headers.get("Authorization")
+  if (!authHeader || !authHeader.startsWith("Bearer ")) {
+    return json({ error: "Missing or invalid Authorization header" }, 401)
+  }
+
+  // Hash the token to compare with stored hash
+  const tokenHash = await sha256(authHeader.replace("Bearer ", ""))
+
+  const { data: tokenRecord, error } = await supabase
+    .from("api_tokens")
+    .select("id, user_id, name, expires_at")
+    .eq("token_hash", tokenHash)
+    .single()
+
+  if (error || !tokenRecord) {
+    return json({ error: "Invalid token" }, 401)
+  }
+  if (new Date(tokenRecord.expires_at) < new Date()) {
+    return json({ error: "Token expired" }, 401)
+  }
+  return tokenRecord
 }
 
 serve(async (req) => {
@@ -25,59 +103,118 @@ serve(async (req) => {
   }
 
   try {
-    // Get token from Authorization header
-    const authHeader = req.headers.get("Authorization")
-    if (!authHeader || !authHeader.startsWith("Bearer ")) {
-      return new Response(
-        JSON.stringify({ error: "Missing or invalid Authorization header" }),
-        { status: 401, headers: { ...corsHeaders, "Content-Type": "application/json" } }
-      )
-    }
+    // Routes below /auth-verify: "" (verify, and exchange for an access
+    // token), /tokens, /tokens/<id>, /pair and /pair/poll
+    const path = new URL(req.url).pathname.replace(/^.*\/auth-verify/, "").replace(/\/$/, "")
 
-    const token = authHeader.replace("Bearer ", "")
-    
-    // Hash the token to compare with stored hash
-    const encoder = new TextEncoder()
-    const data = encoder.encode(token)
-    const hashBuffer = await crypto.subtle.digest("SHA-256", data)
-    const tokenHash = toHex(hashBuffer)
-
-    // Look up token in database
-    const { data: tokenRecord, error } = await supabase
-      .from("api_tokens")
-      .select("id, user_id, name, expires_at")
-      .eq("token_hash", tokenHash)
-      .single()
-
-    if (error || !tokenRecord) {
-      return new Response(
-        JSON.stringify({ error: "Invalid token" }),
-        { status: 401, headers: { ...corsHeaders, "Content-Type": "application/json" } }
-      )
+    if (path === "/pair" && req.method === "POST") {
+      return await startPairing(req)
     }
-
-    // Check expiration
-    if (new Date(tokenRecord.expires_at) < new Date()) {
-      return new Response(
-        JSON.stringify({ error: "Token expired" }),
-        { status: 401, headers: { ...corsHeaders, "Content-Type": "application/json" } }
-      )
+    if (path === "/pair/poll" && req.method === "POST") {
+      return await pollPairing(req)
     }
 
-    // Return user info
-    return new Response(
-      JSON.stringify({
-        valid: true,
-        user_id: tokenRecord.user_id,
-        token_name: tokenRecord.name,
-      }),
-      { status: 200, headers: { ...corsHeaders, "Content-Type": "application/json" } }
-    )
+    const auth = await authenticate(req)
+    if (auth instanceof Response) return auth
 
+    if (path === "" && req.method === "GET") {
+      return json({
+        valid: true,
+        user_id: auth.user_id,
+        token_name: auth.name,
+        token_id: auth.id,
+        expires_at: auth.expires_at,
+        ...await accessToken(auth),
+      })
+    }
+    if (path === "/tokens" && req.method === "GET") {
+      return await listTokens(auth)
+    }
+    const revoke = path.match(/^\/tokens\/(\d+)$/)
+    if (revoke && req.method === "DELETE") {
+      return await revokeToken(auth, parseInt(revoke[1]))
+    }
+    return json({ error: "Not found" }, 404)
   } catch (err) {
-    return new Response(
-      JSON.stringify({ error: "Internal server error" }),
-      { status: 500, headers: { ...corsHeaders, "Content-Type": "application/json" } }
-    )
+    return json({ error: "Internal server error" }, 500)
   }
 })
+
+// listTokens mirrors the bot's /revoke listing, marking the caller's token
+async function listTokens(auth: TokenRecord): Promise<Response> {
+  const { data: tokens, error } = await supabase
+    .from("api_tokens")
+    .select("id, name, created_at, expires_at")
+    .eq("user_id", auth.user_id)
+    .order("created_at", { ascending: false })
+
+  if (error) return json({ error: "Failed to fetch tokens: " + error.message }, 500)
+  return json((tokens || []).map(t => ({ ...t, current: t.id === auth.id })))
+}
+
+async function revokeToken(auth: TokenRecord, id: number): Promise<Response> {
+  const { data: token } = await supabase
+    .from("api_tokens")
+    .select("id, name")
+    .eq("id", id)
+    .eq("user_id", auth.user_id)
+    .single()
+
+  if (!token) return json({ error: "Token not found" }, 404)
+
+  const { error } = await supabase
+    .from("api_tokens")
+    .delete()
+    .eq("id", id)
+    .eq("user_id", auth.user_id)
+
+  if (error) return json({ error: "Failed to revoke token" }, 500)
+  return json({ id: token.id, name: token.name })
+}
+
+// startPairing creates a code for the user to send to the bot with /login
+async function startPairing(req: Request): Promise<Response> {
+  const body = await req.json().catch(() => ({}))
+  const name = String(body.name || "").trim().slice(0, 60) || "CLI Token"
+
+  // Clear out pairings nobody finished
+  await supabase.from("cli_pairings").delete().lt("expires_at", new Date().toISOString())
+
+  const deviceCode = crypto.randomUUID() + "-" + crypto.randomUUID()
+  const random = crypto.getRandomValues(new Uint8Array(6))
+  const chars = Array.from(random, b => codeAlphabet[b % codeAlphabet.length]).join("")
+  const code = chars.slice(0, 3) + "-" + chars.slice(3)
+
+  const { data: pairing, error } = await supabase
+    .from("cli_pairings")
+    .insert({ code, device_hash: await sha256(deviceCode), name })
+    .select("code, expires_at")
+    .single()
+
+  if (error || !pairing) return json({ error: "Failed to start pairing" }, 500)
+  return json({ code: pairing.code, device_code: deviceCode, expires_at: pairing.expires_at, interval: pollInterval })
+}
+
+// pollPairing hands over the token once /login approved the code. The
+// pairing is deleted when the token is collected.
+async function pollPairing(req: Request): Promise<Response> {
+  const body = await req.json().catch(() => ({}))
+  if (!body.device_code) return json({ error: "Missing device_code" }, 400)
+
+  const { data: pairing } = await supabase
+    .from("cli_pairings")
+    .select("id, user_id, token, name, expires_at")
+    .eq("device_hash", await sha256(String(body.device_code)))
+    .single()
+
+  if (!pairing) return json({ error: "Unknown pairing" }, 404)
+  if (!pairing.token) {
+    if (new Date(pairing.expires_at) < new Date()) {
+      return json({ error: "Pairing code expired" }, 410)
+    }
+    return json({ pending: true }, 202)
+  }
+
+  await supabase.from("cli_pairings").delete().eq("id", pairing.id)
+  return json({ token: pairing.token, user_id: pairing.user_id, token_name: pairing.name })
+}
@@ -30,7 +30,7 @@ serve(async (req) => {
 
   // Normalize: support commands without leading slash
   const firstWord = text.split(" ")[0].toLowerCase()
-  const commandWords = ["add", "list", "ls", "done", "rm", "snooze", "subtask", "token", "revoke", "start"]
+  const commandWords = ["add", "list", "ls", "done", "rm", "snooze", "subtask", "token", "revoke", "login", "start"]
   if (!firstWord.startsWith("/") && commandWords.includes(firstWord)) {
     text = "/" + text
   }
@@ -51,10 +51,12 @@ serve(async (req) => {
     response = await handleToken(chatId, text)
   } else if (text.startsWith("/revoke")) {
     response = await handleRevoke(chatId, text)
+  } else if (text.startsWith("/login")) {
+    response = await handleLogin(chatId, text)
   } else if (text.startsWith("/start")) {
-    response = "👋 Welcome to TODO Tracker!\n\nCommands:\nadd <task> - Add task\nlist, ls - Show tasks\ndone, rm <id> - Complete task\nsnooze <id> - Postpone to tomorrow\nsubtask <id> <task> - Add subtask\ntoken [name] - Generate API token for CLI\nrevoke [id] - List or revoke API tokens\n\n(Slash prefix is optional)"
+    response = "👋 Welcome to TODO Tracker!\n\nCommands:\nadd <task> - Add task\nlist, ls - Show tasks\ndone, rm <id> - Complete task\nsnooze <id> - Postpone to tomorrow\nsubtask <id> <task> - Add subtask\ntoken [name] - Generate API token for CLI\nlogin <code> - Pair the CLI (shown by todo login)\nrevoke [id] - List or revoke API tokens\n\n(Slash prefix is optional)"
   } else {
-    response = "❌ Unknown command. Use add, list (ls), done (rm), snooze, subtask, token, or revoke"
+    response = "❌ Unknown command. Use add, list (ls), done (rm), snooze, subtask, token, login, or revoke"
   }
 
   await sendTelegram(chatId, response)
@@ -217,6 +219,18 @@ async function handleSubtask(chatId: number, text: string): Promise<string> {
 async function handleToken(chatId: number, text: string): Promise<string> {
   const name = text.replace("/token", "").trim() || "CLI Token"
   
+  const { token, error } = await createToken(chatId, name)
+  if (error) return "❌ Failed to create token: " + error
+  
+  return `🔑 API Token created: ${name}\n\n` +
+    `Token: \`${token}\`\n\n` +
+    `⚠️ Save this token now! It won't be shown again.\n\n` +
+    `Use in CLI: run todo login --paste and paste it, or next time just run todo login.`
+}
+
+// createToken stores a new API token for the chat and returns it; only its
+// hash is kept
+async function createToken(chatId: number, name: string): Promise<{ token?: string; error?: string }> {
   // Generate random token
   const token = crypto.randomUUID() + "-" + crypto.randomUUID()
   
@@ -235,12 +249,35 @@ async function handleToken(chatId: number, text: string): Promise<string> {
       name: name,
     })
   
-  if (error) return "❌ Failed to create token: " + error.message
-  
-  return `🔑 API Token created: ${name}\n\n` +
-    `Token: \`${token}\`\n\n` +
-    `⚠️ Save this token now! It won't be shown again.\n\n` +
-    `Use in CLI: Add to ~/.todo-cli-token or set TODO_CLI_TOKEN env var.`
+  if (error) return { error: error.message }
+  return { token }
+}
+
+// handleLogin approves a pairing code shown by `todo login`: it creates a
+// token for this chat, which the waiting CLI then collects
+async function handleLogin(chatId: number, text: string): Promise<string> {
+  const code = text.replace("/login", "").trim().toUpperCase().replace(/[^A-Z0-9]/g, "")
+  if (code.length !== 6) return "❌ Usage: /login <code>, with the code shown by todo login"
+
+  const { data: pairing } = await supabase
+    .from("cli_pairings")
+    .select("id, name, user_id, expires_at")
+    .eq("code", code.slice(0, 3) + "-" + code.slice(3))
+    .single()
+
+  if (!pairing || pairing.user_id) return "❌ Unknown code. Run todo login again for a new one."
+  if (new Date(pairing.expires_at) < new Date()) return "❌ That code has expired. Run todo login again."
+
+  const { token, error } = await createToken(chatId, pairing.name)
+  if (error) return "❌ Failed to create token: " + error
+
+  const { error: updateError } = await supabase
+    .from("cli_pairings")
+    .update({ user_id: String(chatId), token })
+    .eq("id", pairing.id)
+  if (updateError) return "❌ Failed to complete login"
+
+  return `✅ CLI logged in with a new token: ${pairing.name}\n\nRevoke it any time with /revoke.`
 }
 
 async function handleRevoke(chatId: number, text: string): Promise<string> {
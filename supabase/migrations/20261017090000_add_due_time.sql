@@ -0,0 +1,2 @@
+-- Optional time of day for tasks added as e.g. "tomorrow 14:00"
+ALTER TABLE tasks ADD COLUMN due_time TIME;
@@ -0,0 +1,3 @@
+-- Repeat rule for recurring tasks, stored as an RRULE (e.g. FREQ=WEEKLY;BYDAY=MO).
+-- Completing a recurring task creates its next occurrence.
+ALTER TABLE tasks ADD COLUMN recurrence TEXT;
@@ -0,0 +1,6 @@
+-- Tags (#work) and a project (+infra) per task, filterable from the CLI and bot
+ALTER TABLE tasks ADD COLUMN tags TEXT[] NOT NULL DEFAULT '{}';
+ALTER TABLE tasks ADD COLUMN project TEXT;
+
+CREATE INDEX idx_tasks_tags ON tasks USING GIN (tags);
+CREATE INDEX idx_tasks_project ON tasks(project);
@@ -0,0 +1,2 @@
+-- How many times a task has been snoozed, shown by `todo snooze` and /snooze
+ALTER TABLE tasks ADD COLUMN snooze_count INTEGER NOT NULL DEFAULT 0;
@@ -0,0 +1,16 @@
+-- Full-text search over task titles for `todo search` and /search
+ALTER TABLE tasks ADD COLUMN fts TSVECTOR
+  GENERATED ALWAYS AS (to_tsvector('english', coalesce(title, ''))) STORED;
+
+CREATE INDEX idx_tasks_fts ON tasks USING GIN (fts);
+
+-- Tasks matching a web-search style query ("quoted phrase", -word, or),
+-- best match first. Called through PostgREST as /rpc/search_tasks, where
+-- the usual filters (user_id=eq.…, status=eq.…, limit) apply on top.
+CREATE FUNCTION search_tasks(search_query TEXT) RETURNS SETOF tasks
+LANGUAGE sql STABLE AS $$
+  SELECT *
+  FROM tasks
+  WHERE fts @@ websearch_to_tsquery('english', search_query)
+  ORDER BY ts_rank(fts, websearch_to_tsquery('english', search_query)) DESC, created_at DESC
+$$;
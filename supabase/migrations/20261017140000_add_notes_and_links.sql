@@ -0,0 +1,14 @@
+-- Markdown notes and a list of links per task, edited with `todo note` and
+-- /note and synced to Obsidian as an indented block under the task
+ALTER TABLE tasks ADD COLUMN notes TEXT;
+ALTER TABLE tasks ADD COLUMN links TEXT[] NOT NULL DEFAULT '{}';
+
+-- Search notes as well as titles, ranking title matches higher
+ALTER TABLE tasks DROP COLUMN fts;
+ALTER TABLE tasks ADD COLUMN fts TSVECTOR
+  GENERATED ALWAYS AS (
+    setweight(to_tsvector('english', coalesce(title, '')), 'A') ||
+    setweight(to_tsvector('english', coalesce(notes, '')), 'B')
+  ) STORED;
+
+CREATE INDEX idx_tasks_fts ON tasks USING GIN (fts);
@@ -0,0 +1,16 @@
+-- Pairing requests for `todo login`: the CLI asks auth-verify for a short
+-- code, the user sends /login <code> to the bot, and the CLI collects the
+-- token the bot created by polling with its device secret
+CREATE TABLE cli_pairings (
+  id SERIAL PRIMARY KEY,
+  code TEXT NOT NULL UNIQUE,
+  device_hash TEXT NOT NULL UNIQUE,
+  name TEXT NOT NULL DEFAULT 'CLI Token',
+  user_id TEXT,   -- set by /login
+  token TEXT,     -- held until the CLI collects it, then the row is deleted
+  created_at TIMESTAMPTZ DEFAULT NOW(),
+  expires_at TIMESTAMPTZ DEFAULT NOW() + INTERVAL '10 minutes'
+);
+
+-- Only the edge functions (service role) touch pairings or tokens
+ALTER TABLE cli_pairings ENABLE ROW LEVEL SECURITY;
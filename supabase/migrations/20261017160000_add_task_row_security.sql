@@ -0,0 +1,34 @@
+-- Row-level security on tasks: the CLI and obsidian-sync query PostgREST
+-- with an access token from auth-verify (role authenticated, sub = the
+-- Telegram chat ID) and only ever see and change that user's rows. The
+-- webhook and the edge functions use the service role, which bypasses
+-- these policies. user_id is a chat ID rather than a UUID, so the policies
+-- compare it with the sub claim instead of auth.uid().
+ALTER TABLE tasks ENABLE ROW LEVEL SECURITY;
+
+CREATE POLICY "Users read their own tasks" ON tasks
+  FOR SELECT TO authenticated
+  USING (user_id = (SELECT auth.jwt() ->> 'sub'));
+
+-- A subtask may only hang under one of the user's own tasks
+CREATE POLICY "Users add their own tasks" ON tasks
+  FOR INSERT TO authenticated
+  WITH CHECK (
+    user_id = (SELECT auth.jwt() ->> 'sub')
+    AND (parent_id IS NULL OR EXISTS (SELECT 1 FROM tasks p WHERE p.id = tasks.parent_id))
+  );
+
+CREATE POLICY "Users change their own tasks" ON tasks
+  FOR UPDATE TO authenticated
+  USING (user_id = (SELECT auth.jwt() ->> 'sub'))
+  WITH CHECK (
+    user_id = (SELECT auth.jwt() ->> 'sub')
+    AND (parent_id IS NULL OR EXISTS (SELECT 1 FROM tasks p WHERE p.id = tasks.parent_id))
+  );
+
+CREATE POLICY "Users delete their own tasks" ON tasks
+  FOR DELETE TO authenticated
+  USING (user_id = (SELECT auth.jwt() ->> 'sub'));
+
+-- Only the edge functions (service role) read or write tokens
+ALTER TABLE api_tokens ENABLE ROW LEVEL SECURITY;